@@ -6,8 +6,7 @@ rm -rf db
 mkdir -p db
 
 echo "🔧 Recreating schema and views..."
-sqlite3 db/chefops.db <schema.sql
-sqlite3 db/chefops.db <views.sql
+./chefops db migrate
 
 echo "🍏 Importing ingredients (with UAE estimated prices)..."
 
@@ -0,0 +1,94 @@
+// Package chefops is the ChefOps kitchen costing engine as a library.
+//
+// A Store supplies recipes, their lines, ingredients, unit conversions and
+// price history; Open returns one over a chefops SQLite database (the same
+// file the chefops CLI uses). A Kitchen costs, scales and forecasts recipes
+// and builds market lists from any Store:
+//
+//	store, err := chefops.Open("db/chefops.db")
+//	if err != nil {
+//		return err
+//	}
+//	defer store.Close()
+//
+//	k := chefops.New(store)
+//	r, err := store.RecipeByName("DISH Lobster Roll")
+//	if err != nil {
+//		return err
+//	}
+//	rc, err := k.Cost(r.ID)
+//
+// Subrecipes are costed to any depth against their own yield, and quantities
+// convert through standard units and each ingredient's conversions. Nothing
+// in this package prints or exits; every failure is returned as an error.
+package chefops
+
+import (
+	"database/sql"
+
+	"github.com/ChefChristoph/chefops/internal"
+)
+
+// Domain types.
+type (
+	Ingredient = internal.Ingredient
+	Recipe     = internal.Recipe
+	Line       = internal.Line
+	Conversion = internal.Conversion
+)
+
+// Store is the data a Kitchen works from. Implement it to cost recipes kept
+// somewhere other than the chefops database.
+type Store = internal.Store
+
+// SQLiteStore is the Store over a chefops SQLite database.
+type SQLiteStore = internal.SQLStore
+
+// Results.
+type (
+	RecipeCost      = internal.RecipeCost
+	CostLine        = internal.CostLine
+	ScaledRecipe    = internal.ScaledRecipe
+	Dish            = internal.Dish
+	Forecast        = internal.Forecast
+	IngredientUsage = internal.IngredientUsage
+	SubrecipeUsage  = internal.SubrecipeUsage
+)
+
+var (
+	// ErrNotFound is returned (wrapped) for unknown recipe and ingredient
+	// IDs and names.
+	ErrNotFound = internal.ErrNotFound
+	// ErrNoConversion is returned (wrapped) when a quantity cannot be
+	// expressed in the unit a recipe or ingredient is measured in.
+	ErrNoConversion = internal.ErrNoConversion
+)
+
+// ErrNoDatabase is returned (wrapped) by Open when there is no database at
+// the path.
+var ErrNoDatabase = internal.ErrNoDatabase
+
+// Open opens the existing chefops database at path and applies any pending
+// schema migrations.
+func Open(path string) (*SQLiteStore, error) {
+	db, err := internal.OpenDBAt(path)
+	if err != nil {
+		return nil, err
+	}
+	return internal.NewSQLStore(db), nil
+}
+
+// Create is Open, creating a new database at path when there is none.
+func Create(path string) (*SQLiteStore, error) {
+	db, err := internal.CreateDBAt(path)
+	if err != nil {
+		return nil, err
+	}
+	return internal.NewSQLStore(db), nil
+}
+
+// NewSQLiteStore returns a Store over an already open chefops database. The
+// schema is used as it is.
+func NewSQLiteStore(db *sql.DB) *SQLiteStore {
+	return internal.NewSQLStore(db)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ChefChristoph/chefops/internal"
+)
+
+func configCommand(args []string) {
+	if len(args) < 1 {
+		fmt.Println("usage: chefops config <show|path>")
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "show":
+		configShow()
+	case "path":
+		fmt.Println(internal.ConfigPath())
+	default:
+		fmt.Println("unknown config subcommand:", args[0])
+		os.Exit(1)
+	}
+}
+
+func configShow() {
+	cfg := internal.CurrentConfig()
+
+	file := cfg.File
+	if file == "" {
+		file = internal.ConfigPath() + " (not found)"
+	}
+
+	fmt.Printf("Config file: %s\n", file)
+	fmt.Printf("Database:    %s (from %s)\n", cfg.DBPath, cfg.Source)
+	fmt.Printf("Currency:    %s\n", orDash(cfg.Currency))
+	fmt.Printf("Export dir:  %s\n", orDash(cfg.ExportDir))
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
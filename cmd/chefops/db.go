@@ -0,0 +1,105 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/ChefChristoph/chefops/internal"
+)
+
+func dbCommand(args []string) {
+	if len(args) < 1 {
+		fmt.Println("usage: chefops db <migrate|status>")
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "migrate":
+		dbMigrate(args[1:])
+	case "status":
+		dbStatus(args[1:])
+	default:
+		fmt.Println("unknown db subcommand:", args[0])
+		os.Exit(1)
+	}
+}
+
+func dbMigrate(args []string) {
+	fs := flag.NewFlagSet("db migrate", flag.ExitOnError)
+	fs.Parse(args)
+
+	// The one command that may create a database.
+	db, err := internal.CreateRawDB()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error opening DB: %v\n", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	applied, err := internal.Migrate(db)
+	for _, m := range applied {
+		fmt.Printf("Applied %04d_%s\n", m.Version, m.Name)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migration failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	version, err := internal.SchemaVersion(db)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error reading schema version: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(applied) == 0 {
+		fmt.Printf("%s is up to date (schema version %d)\n", internal.CurrentConfig().DBPath, version)
+		return
+	}
+	fmt.Printf("%s migrated to schema version %d\n", internal.CurrentConfig().DBPath, version)
+}
+
+func dbStatus(args []string) {
+	fs := flag.NewFlagSet("db status", flag.ExitOnError)
+	fs.Parse(args)
+
+	db, err := internal.OpenRawDB()
+	if err != nil {
+		exitOpenError(err)
+	}
+	defer db.Close()
+
+	applied, err := internal.AppliedMigrations(db)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error reading migrations: %v\n", err)
+		os.Exit(1)
+	}
+	pending, err := internal.PendingMigrations(db)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error reading migrations: %v\n", err)
+		os.Exit(1)
+	}
+
+	version := 0
+	if len(applied) > 0 {
+		version = applied[len(applied)-1].Version
+	}
+
+	fmt.Printf("Database:       %s\n", internal.CurrentConfig().DBPath)
+	fmt.Printf("Schema version: %d\n\n", version)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS")
+	for _, m := range applied {
+		fmt.Fprintf(w, "%04d\t%s\tapplied %s\n", m.Version, m.Name, m.AppliedAt)
+	}
+	for _, m := range pending {
+		fmt.Fprintf(w, "%04d\t%s\tpending\n", m.Version, m.Name)
+	}
+	w.Flush()
+
+	if len(pending) > 0 {
+		fmt.Printf("\n%d pending migration(s); run `chefops db migrate`.\n", len(pending))
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ChefChristoph/chefops/internal"
+)
+
+// doctorReport is the JSON form of `chefops doctor --format json`.
+type doctorReport struct {
+	Database string              `json:"database"`
+	Fixed    []*internal.Finding `json:"fixed"`
+	Findings []*internal.Finding `json:"findings"`
+	Errors   int                 `json:"errors"`
+	Warnings int                 `json:"warnings"`
+}
+
+// ------------------------------------------------------------
+// doctor
+//
+// Example:
+//
+//	chefops doctor
+//	chefops doctor --fix
+//	chefops doctor --format json   # exits 1 when errors are found
+//
+// ------------------------------------------------------------
+func doctorCommand(args []string) {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	fix := fs.Bool("fix", false, "apply the safe repairs")
+	format := fs.String("format", "", "output format: text or json (default: --output)")
+	fs.Parse(args)
+
+	if *format == "" {
+		*format = "text"
+		if outputFormat == outputJSON {
+			*format = "json"
+		}
+	}
+	if *format != "text" && *format != "json" {
+		fmt.Fprintln(os.Stderr, "--format must be text or json")
+		os.Exit(1)
+	}
+
+	db, err := internal.OpenRawDB()
+	if err != nil {
+		exitOpenError(err)
+	}
+	defer db.Close()
+
+	report := doctorReport{Database: internal.CurrentConfig().DBPath, Fixed: []*internal.Finding{}}
+
+	findings, err := internal.Diagnose(db)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error checking database: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Fixing can uncover more (e.g. checks skipped until migrations are
+	// applied), so repeat a few times while there is something to fix.
+	for pass := 0; *fix && pass < 3; pass++ {
+		fixed, err := internal.Repair(db, findings)
+		report.Fixed = append(report.Fixed, fixed...)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error repairing database: %v\n", err)
+			os.Exit(1)
+		}
+		if len(fixed) == 0 {
+			break
+		}
+		if findings, err = internal.Diagnose(db); err != nil {
+			fmt.Fprintf(os.Stderr, "error checking database: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	report.Findings = findings
+	if report.Findings == nil {
+		report.Findings = []*internal.Finding{}
+	}
+	for _, f := range findings {
+		if f.Severity == internal.SeverityError {
+			report.Errors++
+		} else {
+			report.Warnings++
+		}
+	}
+
+	if *format == "json" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Println(string(data))
+	} else {
+		t := newTable(
+			column{"severity", "SEVERITY"},
+			column{"check", "CHECK"},
+			column{"subject", "SUBJECT"},
+			column{"message", "PROBLEM"},
+			column{"fix", "FIX"},
+		)
+		for _, f := range report.Findings {
+			t.add(f.Severity, f.Check, f.Subject, f.Message, f.Fix)
+		}
+		printResult(result{Table: t, Doc: report, Text: func() { printDoctorReport(report) }})
+	}
+
+	if report.Errors > 0 {
+		os.Exit(1)
+	}
+}
+
+func printDoctorReport(r doctorReport) {
+	fmt.Printf("Checking %s\n", r.Database)
+
+	if len(r.Fixed) > 0 {
+		fmt.Printf("\nFixed %d problem(s):\n", len(r.Fixed))
+		for _, f := range r.Fixed {
+			fmt.Printf("  %s: %s (%s)\n", f.Subject, f.Message, f.Fix)
+		}
+	}
+
+	if len(r.Findings) == 0 {
+		fmt.Println("\nNo problems found.")
+		return
+	}
+
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SEVERITY\tCHECK\tSUBJECT\tPROBLEM")
+	fixable := 0
+	for _, f := range r.Findings {
+		msg := f.Message
+		if f.Fixable() {
+			msg += " [--fix: " + f.Fix + "]"
+			fixable++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", strings.ToUpper(f.Severity), f.Check, f.Subject, msg)
+	}
+	w.Flush()
+
+	fmt.Printf("\n%d error(s), %d warning(s)", r.Errors, r.Warnings)
+	if fixable > 0 {
+		fmt.Printf("; %d can be fixed with --fix", fixable)
+	}
+	fmt.Println()
+}
@@ -0,0 +1,437 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ChefChristoph/chefops/internal"
+	"github.com/ChefChristoph/chefops/internal/tui"
+)
+
+func eventCommand(args []string) {
+	if len(args) < 1 {
+		fmt.Println("usage: chefops event <new|list|set-covers|add-dish|remove-dish|forecast|report> ...")
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "new":
+		eventNew(args[1:])
+	case "list":
+		eventList(args[1:])
+	case "set-covers":
+		eventSetCovers(args[1:])
+	case "add-dish":
+		eventAddDish(args[1:])
+	case "remove-dish":
+		eventRemoveDish(args[1:])
+	case "forecast":
+		eventForecast(args[1:])
+	case "report":
+		eventReport(args[1:])
+	default:
+		fmt.Println("unknown event subcommand:", args[0])
+		os.Exit(1)
+	}
+}
+
+func eventNew(args []string) {
+	fs := flag.NewFlagSet("event new", flag.ExitOnError)
+	name := fs.String("name", "", "event name")
+	start := fs.String("start", "", "first day, YYYY-MM-DD")
+	end := fs.String("end", "", "last day, YYYY-MM-DD (default: start)")
+	covers := fs.Float64("covers", 0, "expected covers per day")
+	notes := fs.String("notes", "", "free-form notes")
+	fs.Parse(args)
+
+	if *name == "" || *start == "" {
+		fmt.Fprintln(os.Stderr, "usage: chefops event new --name NAME --start YYYY-MM-DD [--end YYYY-MM-DD] [--covers N] [--notes TEXT]")
+		os.Exit(1)
+	}
+	if *end == "" {
+		*end = *start
+	}
+
+	startDate, err := internal.ParseDate(*start)
+	if err == nil {
+		*end, err = internal.ParseDate(*end)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	db := openDBOrExit()
+	defer db.Close()
+
+	if _, err := internal.CreateEvent(db, *name, startDate, *end, *covers, *notes); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Event saved: %s (%s → %s, %.0f covers/day)\n", *name, startDate, *end, *covers)
+}
+
+func eventList(args []string) {
+	db := openDBOrExit()
+	defer db.Close()
+
+	rows, err := db.Query(`
+		SELECT e.name, e.start_date, e.end_date,
+		       COALESCE((SELECT SUM(covers) FROM event_days WHERE event_id = e.id), 0),
+		       (SELECT COUNT(*) FROM event_dishes WHERE event_id = e.id)
+		FROM events e
+		ORDER BY e.start_date, e.name
+	`)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error loading events: %v\n", err)
+		os.Exit(1)
+	}
+	defer rows.Close()
+
+	t := newTable(
+		column{"event", "EVENT"},
+		column{"start_date", "START"},
+		column{"end_date", "END"},
+		column{"covers", "COVERS"},
+		column{"dishes", "DISHES"},
+	)
+	for rows.Next() {
+		var name, start, end string
+		var covers float64
+		var dishes int
+		if err := rows.Scan(&name, &start, &end, &covers, &dishes); err != nil {
+			fmt.Fprintf(os.Stderr, "error scanning row: %v\n", err)
+			os.Exit(1)
+		}
+		t.add(name, start, end, fixed{covers, 0}, dishes)
+	}
+	printResult(result{Table: t})
+}
+
+func eventSetCovers(args []string) {
+	fs := flag.NewFlagSet("event set-covers", flag.ExitOnError)
+	name := fs.String("event", "", "event name")
+	day := fs.String("day", "", "day, YYYY-MM-DD (default: every day)")
+	covers := fs.Float64("covers", -1, "expected covers")
+	fs.Parse(args)
+
+	if *name == "" || *covers < 0 {
+		fmt.Fprintln(os.Stderr, "usage: chefops event set-covers --event NAME --covers N [--day YYYY-MM-DD]")
+		os.Exit(1)
+	}
+
+	db := openDBOrExit()
+	defer db.Close()
+
+	ev := loadEventOrExit(db, *name)
+
+	if *day == "" {
+		if _, err := db.Exec(`UPDATE event_days SET covers = ? WHERE event_id = ?`, *covers, ev.ID); err != nil {
+			fmt.Fprintf(os.Stderr, "error saving covers: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Covers set: %s %.0f on every day\n", ev.Name, *covers)
+		return
+	}
+
+	d, err := internal.ParseDate(*day)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if d < ev.StartDate || d > ev.EndDate {
+		fmt.Fprintf(os.Stderr, "%s is outside %s (%s → %s)\n", d, ev.Name, ev.StartDate, ev.EndDate)
+		os.Exit(1)
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO event_days (event_id, day, covers)
+		VALUES (?, ?, ?)
+		ON CONFLICT(event_id, day) DO UPDATE SET covers = excluded.covers
+	`, ev.ID, d, *covers)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error saving covers: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Covers set: %s %.0f on %s\n", ev.Name, *covers, d)
+}
+
+func eventAddDish(args []string) {
+	fs := flag.NewFlagSet("event add-dish", flag.ExitOnError)
+	name := fs.String("event", "", "event name")
+	dish := fs.String("dish", "", "dish recipe name")
+	mix := fs.Float64("mix", 0, "share of covers expected to order the dish, in percent")
+	replace := fs.Bool("replace", false, "change the mix of a dish already on the menu")
+	fs.Parse(args)
+
+	if *name == "" || *dish == "" || *mix <= 0 {
+		fmt.Fprintln(os.Stderr, "usage: chefops event add-dish --event NAME --dish \"DISH NAME\" --mix PERCENT [--replace]")
+		os.Exit(1)
+	}
+
+	db := openDBOrExit()
+	defer db.Close()
+
+	ev := loadEventOrExit(db, *name)
+
+	recipeID, recipeName, err := findRecipeByName(db, *dish)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			fmt.Fprintln(os.Stderr, "recipe not found:", *dish)
+			os.Exit(1)
+		}
+		fmt.Fprintln(os.Stderr, "error finding recipe:", err)
+		os.Exit(1)
+	}
+
+	total := *mix
+	onMenu := false
+	for _, d := range ev.Dishes {
+		if d.RecipeID != recipeID {
+			total += d.MixPct
+			continue
+		}
+		onMenu = true
+		if !*replace {
+			fmt.Fprintf(os.Stderr, "%s is already on the %s menu at %.1f%%; use --replace to change it\n", recipeName, ev.Name, d.MixPct)
+			os.Exit(1)
+		}
+	}
+	if total > 100+internal.MixEpsilon {
+		fmt.Fprintf(os.Stderr, "menu mix would be %.1f%% of covers; the dishes on %s can add up to at most 100%%\n", total, ev.Name)
+		os.Exit(1)
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO event_dishes (event_id, recipe_id, mix_pct)
+		VALUES (?, ?, ?)
+		ON CONFLICT(event_id, recipe_id) DO UPDATE SET mix_pct = excluded.mix_pct
+	`, ev.ID, recipeID, *mix)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error saving dish: %v\n", err)
+		os.Exit(1)
+	}
+	if onMenu {
+		fmt.Printf("Changed %s on %s to %.1f%% of covers\n", recipeName, ev.Name, *mix)
+		return
+	}
+	fmt.Printf("Added %s to %s at %.1f%% of covers\n", recipeName, ev.Name, *mix)
+}
+
+func eventRemoveDish(args []string) {
+	fs := flag.NewFlagSet("event remove-dish", flag.ExitOnError)
+	name := fs.String("event", "", "event name")
+	dish := fs.String("dish", "", "dish recipe name")
+	fs.Parse(args)
+
+	if *name == "" || *dish == "" {
+		fmt.Fprintln(os.Stderr, "usage: chefops event remove-dish --event NAME --dish \"DISH NAME\"")
+		os.Exit(1)
+	}
+
+	db := openDBOrExit()
+	defer db.Close()
+
+	ev := loadEventOrExit(db, *name)
+
+	recipeID, recipeName, err := findRecipeByName(db, *dish)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			fmt.Fprintln(os.Stderr, "recipe not found:", *dish)
+			os.Exit(1)
+		}
+		fmt.Fprintln(os.Stderr, "error finding recipe:", err)
+		os.Exit(1)
+	}
+
+	if err := internal.RemoveEventDish(db, ev.ID, recipeID); err != nil {
+		if errors.Is(err, internal.ErrNotFound) {
+			fmt.Fprintf(os.Stderr, "%s is not on the %s menu\n", recipeName, ev.Name)
+			os.Exit(1)
+		}
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Removed %s from %s\n", recipeName, ev.Name)
+}
+
+func eventForecast(args []string) {
+	fs := flag.NewFlagSet("event forecast", flag.ExitOnError)
+	name := fs.String("event", "", "event name")
+	outFile := fs.String("out", "", "output CSV file (default: <event>_forecast.csv)")
+	net := fs.Bool("net", false, "subtract stock on hand from the ingredient totals")
+	fs.Parse(args)
+
+	if *name == "" {
+		fmt.Fprintln(os.Stderr, "usage: chefops event forecast --event NAME [--out FILE.csv] [--net]")
+		os.Exit(1)
+	}
+
+	db := openDBOrExit()
+	defer db.Close()
+
+	ev := loadEventOrExit(db, *name)
+	plan := ev.Plan()
+	if len(plan.Entries) == 0 {
+		fmt.Fprintf(os.Stderr, "%s has no portions to forecast; set covers and add dishes first\n", ev.Name)
+		os.Exit(1)
+	}
+
+	if *outFile == "" {
+		*outFile = tui.Slugify(ev.Name) + "_forecast.csv"
+	}
+
+	writeForecastCSV(db, planDishes(db, plan), plan.Name, *outFile, *net)
+}
+
+func eventReport(args []string) {
+	if len(args) < 1 {
+		fmt.Println("usage: chefops event report \"EVENT NAME\"")
+		os.Exit(1)
+	}
+
+	db := openDBOrExit()
+	defer db.Close()
+
+	ev := loadEventOrExit(db, strings.Join(args, " "))
+	money := internal.CurrentConfig().Money
+
+	coster := internal.NewCoster(db)
+	dayCost := make([]float64, len(ev.Days))
+	var totalCost float64
+
+	cols := []column{{"dish", "DISH"}, {"mix_pct", "MIX %"}}
+	for _, d := range ev.Days {
+		cols = append(cols, column{d.Day, d.Day})
+	}
+	cols = append(cols,
+		column{"portions", "PORTIONS"},
+		column{"cost_per_portion", "COST/PORTION"},
+		column{"total_cost", "TOTAL COST"},
+	)
+	t := newTable(cols...)
+
+	for _, dish := range ev.Dishes {
+		rc, err := coster.Cost(dish.RecipeID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error costing %s: %v\n", dish.Name, err)
+			os.Exit(1)
+		}
+		perPortion := rc.CostPerYieldUnit()
+
+		row := []any{dish.Name, fixed{dish.MixPct, 1}}
+		var portions float64
+		for i, d := range ev.Days {
+			p := d.Covers * dish.MixPct / 100
+			portions += p
+			dayCost[i] += p * perPortion
+			row = append(row, fixed{p, 0})
+		}
+		totalCost += portions * perPortion
+		row = append(row, fixed{portions, 0}, fixed{perPortion, 2}, fixed{portions * perPortion, 2})
+		t.add(row...)
+	}
+
+	doc := eventReportDoc{
+		Event:       ev.Name,
+		StartDate:   ev.StartDate,
+		EndDate:     ev.EndDate,
+		Notes:       ev.Notes,
+		TotalCovers: internal.CleanFloat(ev.TotalCovers()),
+		MenuMix:     internal.CleanFloat(ev.TotalMix()),
+		Dishes:      t.objects(),
+		FoodCost:    internal.RoundMoney(totalCost),
+	}
+	for i, d := range ev.Days {
+		doc.Days = append(doc.Days, eventDayDoc{Day: d.Day, Covers: internal.CleanFloat(d.Covers), FoodCost: internal.RoundMoney(dayCost[i])})
+	}
+	if ev.TotalCovers() > 0 {
+		perCover := internal.RoundMoney(totalCost / ev.TotalCovers())
+		doc.CostPerCover = &perCover
+	}
+
+	printResult(result{Table: t, Doc: doc, Text: func() {
+		fmt.Printf("\nEvent:        %s\n", ev.Name)
+		fmt.Printf("Dates:        %s → %s (%d day(s))\n", ev.StartDate, ev.EndDate, len(ev.Days))
+		fmt.Printf("Total covers: %.0f\n", ev.TotalCovers())
+		fmt.Printf("Menu mix:     %.1f%%\n", ev.TotalMix())
+		if ev.Notes != "" {
+			fmt.Printf("Notes:        %s\n", ev.Notes)
+		}
+		fmt.Println()
+
+		if len(ev.Dishes) == 0 {
+			fmt.Println("No dishes on the menu yet (chefops event add-dish).")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		var header []string
+		covers := []string{"Covers", ""}
+		for _, c := range t.Columns {
+			header = append(header, c.Header)
+		}
+		for _, d := range ev.Days {
+			covers = append(covers, fmt.Sprintf("%.0f", d.Covers))
+		}
+		covers = append(covers, fmt.Sprintf("%.0f", ev.TotalCovers()), "", "")
+		fmt.Fprintln(w, strings.Join(header, "\t"))
+		fmt.Fprintln(w, strings.Join(covers, "\t"))
+
+		for _, row := range t.Rows {
+			cells := make([]string, len(row))
+			for i, cell := range row {
+				cells[i] = cellText(cell, true)
+			}
+			fmt.Fprintln(w, strings.Join(cells, "\t"))
+		}
+
+		costRow := []string{"Food cost", ""}
+		for _, c := range dayCost {
+			costRow = append(costRow, fmt.Sprintf("%.2f", c))
+		}
+		costRow = append(costRow, "", "", fmt.Sprintf("%.2f", totalCost))
+		fmt.Fprintln(w, strings.Join(costRow, "\t"))
+		w.Flush()
+
+		fmt.Printf("\nFood cost:      %s\n", money(totalCost))
+		if ev.TotalCovers() > 0 {
+			fmt.Printf("Cost per cover: %s\n", money(totalCost/ev.TotalCovers()))
+		}
+	}})
+}
+
+// eventReportDoc is the JSON form of `event report`.
+type eventReportDoc struct {
+	Event        string        `json:"event"`
+	StartDate    string        `json:"start_date"`
+	EndDate      string        `json:"end_date"`
+	Notes        string        `json:"notes,omitempty"`
+	TotalCovers  float64       `json:"total_covers"`
+	MenuMix      float64       `json:"menu_mix_pct"`
+	Days         []eventDayDoc `json:"days"`
+	Dishes       []orderedRow  `json:"dishes"`
+	FoodCost     float64       `json:"food_cost"`
+	CostPerCover *float64      `json:"cost_per_cover"`
+}
+
+type eventDayDoc struct {
+	Day      string  `json:"day"`
+	Covers   float64 `json:"covers"`
+	FoodCost float64 `json:"food_cost"`
+}
+
+func loadEventOrExit(db *sql.DB, name string) *internal.Event {
+	ev, err := internal.LoadEvent(db, name)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	return ev
+}
@@ -1,10 +1,10 @@
 package main
 
 import (
-	"database/sql"
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/ChefChristoph/chefops/internal"
@@ -38,8 +38,10 @@ func exportCommand(args []string) {
 ///////////////////////////////////////////////////////////////////////////////
 
 type exportOptions struct {
-	outfile string
-	json    bool
+	outfile    string
+	json       bool
+	bySupplier bool
+	net        bool
 }
 
 func parseExportFlags(args []string) (exportOptions, []string) {
@@ -63,6 +65,18 @@ func parseExportFlags(args []string) (exportOptions, []string) {
 			continue
 		}
 
+		// --by-supplier
+		if a == "--by-supplier" {
+			opts.bySupplier = true
+			continue
+		}
+
+		// --net
+		if a == "--net" {
+			opts.net = true
+			continue
+		}
+
 		// positional argument
 		positional = append(positional, a)
 	}
@@ -84,46 +98,26 @@ func exportRecipe(args []string) {
 
 	recipeName := positional[0]
 
-	db, _ := internal.OpenDB()
+	db := openDBOrExit()
 	defer db.Close()
 
-	var yield, secYield float64
-	var unit, secUnit string
-
-	err := db.QueryRow(`
-		SELECT yield_qty, yield_unit, secondary_yield_qty, secondary_yield_unit
-		FROM recipes
-		WHERE name = ?
-	`, recipeName).Scan(&yield, &unit, &secYield, &secUnit)
+	var recipeID int
+	err := db.QueryRow(`SELECT id FROM recipes WHERE name = ?`, recipeName).Scan(&recipeID)
 	if err != nil {
 		fmt.Println("recipe not found:", recipeName)
 		os.Exit(1)
 	}
 
-	var totalCost, costPerYield float64
-	var costPerSecondary sql.NullFloat64
-
-	err = db.QueryRow(`
-		SELECT total_cost, cost_per_yield_unit, cost_per_secondary_unit
-		FROM recipe_totals
-		WHERE recipe_name = ?
-	`, recipeName).Scan(&totalCost, &costPerYield, &costPerSecondary)
+	rc, err := kitchen(db).Cost(recipeID)
 	if err != nil {
-		fmt.Println("error loading totals:", err)
+		fmt.Println("error costing recipe:", err)
 		os.Exit(1)
 	}
 
-	rows, err := db.Query(`
-		SELECT item_type, ingredient_name, qty, ingredient_unit, line_cost
-		FROM recipe_items_expanded_detail_export
-		WHERE recipe_name = ?
-		ORDER BY item_type, ingredient_name
-	`, recipeName)
-	if err != nil {
-		fmt.Println("error loading recipe items:", err)
-		return
-	}
-	defer rows.Close()
+	yield, unit := rc.YieldQty, rc.YieldUnit
+	secYield, secUnit := rc.SecondaryYieldQty, rc.SecondaryYieldUnit
+	totalCost, costPerYield := rc.TotalCost, rc.CostPerYieldUnit()
+	costPerSecondary, hasSecondary := rc.CostPerSecondaryUnit()
 
 	type line struct {
 		Type     string  `json:"type"`
@@ -134,13 +128,14 @@ func exportRecipe(args []string) {
 	}
 
 	var lines []line
-	for rows.Next() {
-		var l line
-		if err := rows.Scan(&l.Type, &l.Name, &l.Qty, &l.Unit, &l.LineCost); err != nil {
-			fmt.Println("scan error:", err)
-			continue
-		}
-		lines = append(lines, l)
+	for _, l := range rc.Lines {
+		lines = append(lines, line{
+			Type:     l.Type,
+			Name:     l.Name,
+			Qty:      l.Qty,
+			Unit:     l.Unit,
+			LineCost: l.LineCost,
+		})
 	}
 
 	// JSON EXPORT
@@ -159,7 +154,7 @@ func exportRecipe(args []string) {
 			"totals": map[string]interface{}{
 				"total_cost":      totalCost,
 				"cost_per_unit":   costPerYield,
-				"cost_per_second": costPerSecondary.Float64,
+				"cost_per_second": costPerSecondary,
 			},
 		}
 
@@ -189,10 +184,10 @@ func exportRecipe(args []string) {
 	}
 
 	sb.WriteString("\n## Cost Summary\n\n")
-	sb.WriteString(fmt.Sprintf("- **Total Cost:** %.2f\n", totalCost))
+	sb.WriteString(fmt.Sprintf("- **Total Cost:** %s\n", internal.CurrentConfig().Money(totalCost)))
 	sb.WriteString(fmt.Sprintf("- **Cost per %s:** %.4f\n", unit, costPerYield))
-	if costPerSecondary.Valid {
-		sb.WriteString(fmt.Sprintf("- **Cost per %s:** %.4f\n", secUnit, costPerSecondary.Float64))
+	if hasSecondary {
+		sb.WriteString(fmt.Sprintf("- **Cost per %s:** %.4f\n", secUnit, costPerSecondary))
 	}
 
 	writeOutput(opts.outfile, sb.String())
@@ -202,36 +197,99 @@ func exportRecipe(args []string) {
 // EXPORT MARKET LIST
 ///////////////////////////////////////////////////////////////////////////////
 
+// marketlistItem is one exported market list line: the theoretical
+// quantity and, when the ingredient has a pack size, what to order.
+type marketlistItem struct {
+	Name     string  `json:"name"`
+	SKU      string  `json:"sku,omitempty"`
+	Qty      float64 `json:"qty"`
+	Unit     string  `json:"unit"`
+	Cost     float64 `json:"cost_per_unit"`
+	Est      float64 `json:"estimated_cost"`
+	Pack     string  `json:"pack,omitempty"`
+	Packs    float64 `json:"packs,omitempty"`
+	OrderQty float64 `json:"order_qty"`
+	OverQty  float64 `json:"over_qty,omitempty"`
+	OverCost float64 `json:"over_cost,omitempty"`
+
+	purchase internal.Purchase
+}
+
+func newMarketlistItem(u *internal.IngredientUsage, p internal.Purchase, sku string) marketlistItem {
+	it := marketlistItem{
+		Name:     u.Name,
+		SKU:      sku,
+		Qty:      internal.CleanFloat(u.Qty),
+		Unit:     u.Unit,
+		Cost:     internal.CleanFloat(u.CostPerUnit),
+		Est:      internal.RoundMoney(u.Cost()),
+		OrderQty: internal.CleanFloat(p.OrderQty),
+		OverQty:  internal.CleanFloat(p.OverQty),
+		OverCost: internal.RoundMoney(p.OverCost),
+		purchase: p,
+	}
+	if p.HasPack {
+		it.Pack = fmt.Sprintf("%g %s", p.Pack.Qty, p.Pack.Unit)
+		it.Packs = p.Packs
+	}
+	return it
+}
+
+func (it marketlistItem) markdownRow(withSKU bool) string {
+	pack, packs, orderQty, over, overCost := purchaseColumns(it.purchase)
+	sku := ""
+	if withSKU {
+		sku = " " + orDash(it.SKU) + " |"
+	}
+	return fmt.Sprintf(
+		"| %s |%s %.3f | %s | %s | %s | %s | %s | %.2f | %.2f | %s |\n",
+		it.Name, sku, it.Qty, it.Unit, pack, packs, orderQty, over, it.Cost, it.Est, overCost,
+	)
+}
+
+const (
+	marketlistMarkdownHeader = "| Ingredient | Qty | Unit | Pack | Packs | Order Qty | Over | Cost/Unit | Est Cost | Over Cost |\n" +
+		"|-----------|-----|------|------|-------|-----------|------|-----------|----------|-----------|\n"
+	marketlistMarkdownSKUHeader = "| Ingredient | SKU | Qty | Unit | Pack | Packs | Order Qty | Over | Cost/Unit | Est Cost | Over Cost |\n" +
+		"|-----------|-----|-----|------|------|-------|-----------|------|-----------|----------|-----------|\n"
+)
+
 func exportMarketlist(args []string) {
 	opts, _ := parseExportFlags(args)
 
-	db, _ := internal.OpenDB()
+	db := openDBOrExit()
 	defer db.Close()
 
-	rows, err := db.Query(`
-		SELECT ingredient_name, total_qty, unit, cost_per_unit, total_cost
-		FROM market_list
-		ORDER BY ingredient_name
-	`)
+	items, err := kitchen(db).MarketList()
 	if err != nil {
 		fmt.Println("error loading market list:", err)
-		return
+		os.Exit(1)
+	}
+	if opts.net {
+		items = netOfStockOrExit(db, items)
 	}
-	defer rows.Close()
 
-	type item struct {
-		Name string  `json:"name"`
-		Qty  float64 `json:"qty"`
-		Unit string  `json:"unit"`
-		Cost float64 `json:"cost"`
-		Est  float64 `json:"estimated_cost"`
+	plan, err := internal.PlanPurchases(db, items)
+	if err != nil {
+		fmt.Println("error rounding to pack sizes:", err)
+		os.Exit(1)
 	}
 
-	var list []item
-	for rows.Next() {
-		var it item
-		rows.Scan(&it.Name, &it.Qty, &it.Unit, &it.Cost, &it.Est)
-		list = append(list, it)
+	if opts.bySupplier {
+		groups, err := internal.GroupBySupplier(db, items)
+		if err != nil {
+			fmt.Println("error grouping by supplier:", err)
+			os.Exit(1)
+		}
+		exportMarketlistBySupplier(opts, groups, plan)
+		return
+	}
+
+	var list []marketlistItem
+	var overTotal float64
+	for _, u := range items {
+		list = append(list, newMarketlistItem(u, plan[u.IngredientID], ""))
+		overTotal += plan[u.IngredientID].OverCost
 	}
 
 	if opts.json {
@@ -243,15 +301,100 @@ func exportMarketlist(args []string) {
 	var sb strings.Builder
 
 	sb.WriteString("# Market List\n\n")
-	sb.WriteString("| Ingredient | Qty | Unit | Cost/Unit | Est Cost |\n")
-	sb.WriteString("|-----------|-----|------|-----------|----------|\n")
+	if opts.net {
+		sb.WriteString("_Net of stock on hand._\n\n")
+	}
+	sb.WriteString(marketlistMarkdownHeader)
 
 	for _, it := range list {
-		sb.WriteString(fmt.Sprintf(
-			"| %s | %.3f | %s | %.2f | %.2f |\n",
-			it.Name, it.Qty, it.Unit, it.Cost, it.Est,
-		))
+		sb.WriteString(it.markdownRow(false))
 	}
+	sb.WriteString(fmt.Sprintf("\n**Pack rounding:** +%s\n", internal.CurrentConfig().Money(overTotal)))
+
+	writeOutput(opts.outfile, sb.String())
+}
+
+// marketlistSection is one supplier's part of the market list.
+type marketlistSection struct {
+	Supplier     string           `json:"supplier"`
+	Contact      string           `json:"contact,omitempty"`
+	OrderDays    string           `json:"order_days,omitempty"`
+	LeadTimeDays int              `json:"lead_time_days,omitempty"`
+	MinOrder     float64          `json:"min_order,omitempty"`
+	Items        []marketlistItem `json:"items"`
+	Subtotal     float64          `json:"subtotal"`
+	OverCost     float64          `json:"over_cost"`
+	BelowMinimum bool             `json:"below_minimum,omitempty"`
+}
+
+func marketlistSections(groups []*internal.SupplierGroup, plan map[int]internal.Purchase) []marketlistSection {
+	var sections []marketlistSection
+	for _, g := range groups {
+		over := overPurchaseCost(g.Items, plan)
+		sec := marketlistSection{
+			Supplier:     g.Supplier.Name,
+			Contact:      g.Supplier.Contact,
+			OrderDays:    g.Supplier.OrderDays,
+			LeadTimeDays: g.Supplier.LeadTimeDays,
+			MinOrder:     g.Supplier.MinOrder,
+			Subtotal:     internal.RoundMoney(g.Subtotal()),
+			OverCost:     internal.RoundMoney(over),
+			BelowMinimum: g.Supplier.BelowMinimum(g.Subtotal() + over),
+		}
+		for _, u := range g.Items {
+			sec.Items = append(sec.Items, newMarketlistItem(u, plan[u.IngredientID], g.Links[u.IngredientID].SKU))
+		}
+		sections = append(sections, sec)
+	}
+	return sections
+}
+
+func exportMarketlistBySupplier(opts exportOptions, groups []*internal.SupplierGroup, plan map[int]internal.Purchase) {
+	sections := marketlistSections(groups, plan)
+
+	if opts.json {
+		data, _ := json.MarshalIndent(sections, "", "  ")
+		writeOutput(opts.outfile, string(data))
+		return
+	}
+
+	money := internal.CurrentConfig().Money
+	var sb strings.Builder
+	var total, totalOver float64
+
+	sb.WriteString("# Market List by Supplier\n")
+	if opts.net {
+		sb.WriteString("\n_Net of stock on hand._\n")
+	}
+	for _, sec := range sections {
+		sb.WriteString(fmt.Sprintf("\n## %s\n\n", sec.Supplier))
+		if sec.Contact != "" {
+			sb.WriteString(fmt.Sprintf("- **Contact:** %s\n", sec.Contact))
+		}
+		if sec.OrderDays != "" {
+			sb.WriteString(fmt.Sprintf("- **Order days:** %s\n", sec.OrderDays))
+		}
+		if sec.LeadTimeDays > 0 {
+			sb.WriteString(fmt.Sprintf("- **Lead time:** %s\n", leadTimeString(sec.LeadTimeDays)))
+		}
+		if sec.Contact != "" || sec.OrderDays != "" || sec.LeadTimeDays > 0 {
+			sb.WriteString("\n")
+		}
+
+		sb.WriteString(marketlistMarkdownSKUHeader)
+		for _, it := range sec.Items {
+			sb.WriteString(it.markdownRow(true))
+		}
+
+		sb.WriteString(fmt.Sprintf("\n**Subtotal:** %s (+%s pack rounding)", money(sec.Subtotal), money(sec.OverCost)))
+		if sec.BelowMinimum {
+			sb.WriteString(fmt.Sprintf(" (below minimum order %s)", money(sec.MinOrder)))
+		}
+		sb.WriteString("\n")
+		total += sec.Subtotal
+		totalOver += sec.OverCost
+	}
+	sb.WriteString(fmt.Sprintf("\n**Total:** %s (+%s pack rounding)\n", money(total), money(totalOver)))
 
 	writeOutput(opts.outfile, sb.String())
 }
@@ -263,15 +406,28 @@ func exportMarketlist(args []string) {
 func exportFullReport(args []string) {
 	opts, _ := parseExportFlags(args)
 
-	db, _ := internal.OpenDB()
+	db := openDBOrExit()
 	defer db.Close()
 
-	rows, err := db.Query(`SELECT name FROM recipes ORDER BY name`)
+	rows, err := db.Query(`SELECT id, name FROM recipes ORDER BY name`)
 	if err != nil {
 		fmt.Println("error loading recipes:", err)
 		return
 	}
-	defer rows.Close()
+
+	type recipeRef struct {
+		ID   int
+		Name string
+	}
+	var recipes []recipeRef
+	for rows.Next() {
+		var r recipeRef
+		rows.Scan(&r.ID, &r.Name)
+		recipes = append(recipes, r)
+	}
+	rows.Close()
+
+	coster := internal.NewCoster(db)
 
 	type line struct {
 		Type string
@@ -295,39 +451,30 @@ func exportFullReport(args []string) {
 		sb.WriteString("# ChefOps Full Report\n\n")
 	}
 
-	for rows.Next() {
-		var name string
-		rows.Scan(&name)
-
-		var y, sy float64
-		var u, su string
-
-		db.QueryRow(`
-			SELECT yield_qty, yield_unit, secondary_yield_qty, secondary_yield_unit
-			FROM recipes WHERE name = ?
-		`, name).Scan(&y, &u, &sy, &su)
-
-		var total, cpu float64
-		var cps sql.NullFloat64
+	for _, r := range recipes {
+		name := r.Name
 
-		db.QueryRow(`
-			SELECT total_cost, cost_per_yield_unit, cost_per_secondary_unit
-			FROM recipe_totals WHERE recipe_name = ?
-		`, name).Scan(&total, &cpu, &cps)
+		rc, err := coster.Cost(r.ID)
+		if err != nil {
+			fmt.Println("error costing recipe:", err)
+			os.Exit(1)
+		}
 
-		r2, _ := db.Query(`
-			SELECT item_type, ingredient_name, qty, ingredient_unit, line_cost
-			FROM recipe_items_expanded_detail_export
-			WHERE recipe_name = ?
-		`, name)
+		y, u := rc.YieldQty, rc.YieldUnit
+		sy, su := rc.SecondaryYieldQty, rc.SecondaryYieldUnit
+		total, cpu := rc.TotalCost, rc.CostPerYieldUnit()
+		cps, _ := rc.CostPerSecondaryUnit()
 
 		var lines []line
-		for r2.Next() {
-			var l line
-			r2.Scan(&l.Type, &l.Name, &l.Qty, &l.Unit, &l.Cost)
-			lines = append(lines, l)
+		for _, l := range rc.Lines {
+			lines = append(lines, line{
+				Type: l.Type,
+				Name: l.Name,
+				Qty:  l.Qty,
+				Unit: l.Unit,
+				Cost: l.LineCost,
+			})
 		}
-		r2.Close()
 
 		if opts.json {
 			all = append(all, block{
@@ -344,7 +491,7 @@ func exportFullReport(args []string) {
 				Totals: map[string]interface{}{
 					"total":           total,
 					"cost_per_unit":   cpu,
-					"cost_per_second": cps.Float64,
+					"cost_per_second": cps,
 				},
 			})
 		} else {
@@ -376,6 +523,12 @@ func writeOutput(outfile, content string) {
 		return
 	}
 
+	outfile = internal.CurrentConfig().ExportPath(outfile)
+	if err := os.MkdirAll(filepath.Dir(outfile), 0755); err != nil {
+		fmt.Println("error creating directory:", err)
+		return
+	}
+
 	err := os.WriteFile(outfile, []byte(content), 0644)
 	if err != nil {
 		fmt.Println("error writing file:", err)
@@ -6,10 +6,12 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/ChefChristoph/chefops"
 	"github.com/ChefChristoph/chefops/internal"
 )
 
@@ -20,6 +22,8 @@ type dishForecast struct {
 	Portions  float64
 	YieldQty  float64
 	YieldUnit string
+	Day       string // from a plan file, optional
+	Outlet    string // from a plan file, optional
 }
 
 // ------------------------------------------------------------
@@ -33,16 +37,21 @@ type dishForecast struct {
 //	  "DISH Lobster Roll=500" \
 //	  "DISH Turbo Hammour Popcorn=700"
 //
+//	chefops forecast --plan f1_weekend.yaml --out f1_forecast.csv
+//
 // ------------------------------------------------------------
 func forecastCommand(args []string) {
 	fs := flag.NewFlagSet("forecast", flag.ExitOnError)
 	outFile := fs.String("out", "forecast.csv", "output CSV file")
+	net := fs.Bool("net", false, "subtract stock on hand from the ingredient totals")
+	planFile := fs.String("plan", "", "read dishes and portions from a plan file (.yaml or .csv)")
 	fs.Parse(args)
 
 	specs := fs.Args()
-	if len(specs) == 0 {
+	if len(specs) == 0 && *planFile == "" {
 		fmt.Println("usage:")
-		fmt.Println("  chefops forecast --out forecast.csv \"DISH Name=PORTIONS\" ...")
+		fmt.Println("  chefops forecast --out forecast.csv [--net] \"DISH Name=PORTIONS\" ...")
+		fmt.Println("  chefops forecast --out forecast.csv [--net] --plan event.yaml")
 		fmt.Println("")
 		fmt.Println("example:")
 		fmt.Println("  chefops forecast --out f1_forecast.csv \\")
@@ -52,170 +61,86 @@ func forecastCommand(args []string) {
 		os.Exit(1)
 	}
 
-	db, _ := internal.OpenDB()
+	db := openDBOrExit()
 	defer db.Close()
 
 	// 1) Parse dish specs and resolve recipes
 	var dishes []dishForecast
-
-	for _, spec := range specs {
-		parts := strings.SplitN(spec, "=", 2)
-		if len(parts) != 2 {
-			fmt.Fprintf(os.Stderr, "invalid spec (expected NAME=QTY): %s\n", spec)
-			os.Exit(1)
-		}
-
-		rawName := strings.TrimSpace(parts[0])
-		qtyStr := strings.TrimSpace(parts[1])
-
-		if rawName == "" {
-			fmt.Fprintf(os.Stderr, "empty dish name in spec: %s\n", spec)
-			os.Exit(1)
-		}
-
-		portions, err := strconv.ParseFloat(qtyStr, 64)
-		if err != nil || portions <= 0 {
-			fmt.Fprintf(os.Stderr, "invalid portions in spec (need positive number): %s\n", spec)
-			os.Exit(1)
-		}
-
-		recipeID, recipeName, err := findRecipeByName(db, rawName)
-		if err != nil {
-			if err == sql.ErrNoRows {
-				fmt.Fprintf(os.Stderr, "recipe not found for forecast: %s\n", rawName)
-				os.Exit(1)
-			}
-			fmt.Fprintf(os.Stderr, "error resolving recipe %q: %v\n", rawName, err)
-			os.Exit(1)
-		}
-
-		var yieldQty float64
-		var yieldUnit string
-		err = db.QueryRow(`
-			SELECT yield_qty, yield_unit
-			FROM recipes
-			WHERE id = ?
-		`, recipeID).Scan(&yieldQty, &yieldUnit)
+	var plan *internal.Plan
+	if *planFile != "" {
+		var err error
+		plan, err = internal.LoadPlan(*planFile)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "error loading base yield for %s: %v\n", recipeName, err)
+			fmt.Fprintf(os.Stderr, "error reading plan: %v\n", err)
 			os.Exit(1)
 		}
-		if yieldQty <= 0 {
-			// Safety net – all your current recipes use 1.00 anyway
-			yieldQty = 1
-		}
-
-		dishes = append(dishes, dishForecast{
-			RecipeID:  recipeID,
-			Name:      recipeName,
-			Portions:  portions,
-			YieldQty:  yieldQty,
-			YieldUnit: yieldUnit,
-		})
+		dishes = planDishes(db, plan)
 	}
+	dishes = append(dishes, parseDishSpecs(db, specs)...)
 
-	// 2) Aggregate ingredients (full marketlist) + direct subrecipes
-
-	type ingAgg struct {
-		Name        string
-		Unit        string
-		CostPerUnit float64
-		TotalQty    float64
-		TotalCost   float64
+	planName := ""
+	if plan != nil {
+		planName = plan.Name
 	}
+	writeForecastCSV(db, dishes, planName, *outFile, *net)
+}
 
-	type subAgg struct {
-		Name     string
-		Unit     string
-		TotalQty float64
+// writeForecastCSV aggregates the dishes into the forecast CSV: dish
+// overview, ingredients, bulk-prep subrecipes and, when dishes carry days or
+// outlets, per-day and per-outlet subtotals.
+func writeForecastCSV(db *sql.DB, dishes []dishForecast, planName, outFile string, net bool) {
+	// 2) Aggregate ingredients (full marketlist) + subrecipes at every level
+	plan := make([]chefops.Dish, len(dishes))
+	for i, d := range dishes {
+		plan[i] = chefops.Dish{RecipeID: d.RecipeID, Portions: d.Portions}
+	}
+	coster := kitchen(db).Coster()
+	forecast, err := coster.Forecast(plan)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
 	}
 
-	ingredients := make(map[int]*ingAgg) // ingredient_id -> agg
-	subrecipes := make(map[int]*subAgg)  // subrecipe_id -> agg
-
+	// Per-day and per-outlet subtotals, worked out before the file is
+	// created so a dish that fails to cost leaves no partial CSV behind.
+	hasDays, hasOutlets := false, false
 	for _, d := range dishes {
-		scale := d.Portions / d.YieldQty
-
-		// --- ingredients via recipe_items_expanded (recursive) ---
-		ingRows, err := db.Query(`
-			SELECT e.ingredient_id, i.name, i.unit, i.cost_per_unit, e.total_qty
-			FROM recipe_items_expanded e
-			JOIN ingredients i ON i.id = e.ingredient_id
-			WHERE e.recipe_id = ?
-		`, d.RecipeID)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "error loading ingredients for %s: %v\n", d.Name, err)
-			os.Exit(1)
-		}
+		hasDays = hasDays || d.Day != ""
+		hasOutlets = hasOutlets || d.Outlet != ""
+	}
+	var daySubtotals, outletSubtotals []*forecastSubtotal
+	if hasDays {
+		daySubtotals, err = subtotalsBy(coster, dishes, func(d dishForecast) string { return d.Day })
+	}
+	if err == nil && hasOutlets {
+		outletSubtotals, err = subtotalsBy(coster, dishes, func(d dishForecast) string { return d.Outlet })
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
 
-		for ingRows.Next() {
-			var ingID int
-			var name, unit string
-			var cpu, baseQty float64
-
-			if err := ingRows.Scan(&ingID, &name, &unit, &cpu, &baseQty); err != nil {
-				fmt.Fprintf(os.Stderr, "scan error: %v\n", err)
-				os.Exit(1)
-			}
-
-			qty := baseQty * scale
-
-			agg, ok := ingredients[ingID]
-			if !ok {
-				agg = &ingAgg{
-					Name:        name,
-					Unit:        unit,
-					CostPerUnit: cpu,
-				}
-				ingredients[ingID] = agg
-			}
-
-			agg.TotalQty += qty
-			agg.TotalCost += qty * cpu
-		}
-		ingRows.Close()
-
-		// --- direct subrecipes (for bulk prep planning) ---
-		subRows, err := db.Query(`
-			SELECT rs.subrecipe_id, r.name, rs.unit, rs.qty
-			FROM recipe_subrecipes rs
-			JOIN recipes r ON r.id = rs.subrecipe_id
-			WHERE rs.recipe_id = ?
-		`, d.RecipeID)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "error loading subrecipes for %s: %v\n", d.Name, err)
-			os.Exit(1)
-		}
+	// 3) Round to purchase packs
+	usage := forecast.Ingredients
+	if net {
+		usage = netOfStockOrExit(db, usage)
+	}
+	purchases, err := internal.PlanPurchases(db, usage)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error rounding to pack sizes: %v\n", err)
+		os.Exit(1)
+	}
 
-		for subRows.Next() {
-			var subID int
-			var name, unit string
-			var baseQty float64
-
-			if err := subRows.Scan(&subID, &name, &unit, &baseQty); err != nil {
-				fmt.Fprintf(os.Stderr, "scan error (subrecipes): %v\n", err)
-				os.Exit(1)
-			}
-
-			qty := baseQty * scale
-
-			agg, ok := subrecipes[subID]
-			if !ok {
-				agg = &subAgg{
-					Name: name,
-					Unit: unit,
-				}
-				subrecipes[subID] = agg
-			}
-			agg.TotalQty += qty
-		}
-		subRows.Close()
+	// 4) Open CSV file
+	outFile = internal.CurrentConfig().ExportPath(outFile)
+	if err := os.MkdirAll(filepath.Dir(outFile), 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "cannot create directory for %s: %v\n", outFile, err)
+		os.Exit(1)
 	}
 
-	// 3) Open CSV file
-	f, err := os.Create(*outFile)
+	f, err := os.Create(outFile)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "cannot create %s: %v\n", *outFile, err)
+		fmt.Fprintf(os.Stderr, "cannot create %s: %v\n", outFile, err)
 		os.Exit(1)
 	}
 	defer f.Close()
@@ -223,72 +148,237 @@ func forecastCommand(args []string) {
 	w := csv.NewWriter(f)
 
 	// --- SECTION 1: Dish overview ---------------------------------
+	if planName != "" {
+		_ = w.Write([]string{"# Plan", planName})
+	}
 	_ = w.Write([]string{"# Dishes"})
-	_ = w.Write([]string{"Dish", "Portions", "Base yield", "Scale factor"})
+	header := []string{"Dish", "Portions", "Base yield", "Scale factor"}
+	if hasDays {
+		header = append(header, "Day")
+	}
+	if hasOutlets {
+		header = append(header, "Outlet")
+	}
+	_ = w.Write(header)
 
 	for _, d := range dishes {
 		scale := d.Portions / d.YieldQty
 		base := fmt.Sprintf("%.3f %s", d.YieldQty, d.YieldUnit)
-		_ = w.Write([]string{
+		row := []string{
 			d.Name,
 			fmt.Sprintf("%.3f", d.Portions),
 			base,
 			fmt.Sprintf("%.3f", scale),
-		})
+		}
+		if hasDays {
+			row = append(row, d.Day)
+		}
+		if hasOutlets {
+			row = append(row, d.Outlet)
+		}
+		_ = w.Write(row)
 	}
 
 	_ = w.Write([]string{}) // blank line
 
 	// --- SECTION 2: Ingredients (market list) ----------------------
-	_ = w.Write([]string{"# Ingredients (aggregated)"})
-	_ = w.Write([]string{"Ingredient", "Unit", "Total Qty", "Unit Cost", "Total Cost"})
-
-	// Sort by name for nicer output
-	ingSlice := make([]*ingAgg, 0, len(ingredients))
-	for _, v := range ingredients {
-		ingSlice = append(ingSlice, v)
+	if net {
+		_ = w.Write([]string{"# Ingredients (aggregated, net of stock on hand)"})
+	} else {
+		_ = w.Write([]string{"# Ingredients (aggregated)"})
 	}
-	sort.Slice(ingSlice, func(i, j int) bool {
-		return ingSlice[i].Name < ingSlice[j].Name
-	})
+	_ = w.Write([]string{"Ingredient", "Unit", "Total Qty", "Pack", "Packs", "Order Qty", "Over Qty", "Unit Cost", "Total Cost", "Over Cost"})
 
-	for _, ing := range ingSlice {
+	for _, ing := range usage {
+		pack, packs, orderQty, over, overCost := purchaseColumns(purchases[ing.IngredientID])
 		_ = w.Write([]string{
 			ing.Name,
 			ing.Unit,
-			fmt.Sprintf("%.3f", ing.TotalQty),
+			fmt.Sprintf("%.3f", ing.Qty),
+			pack,
+			packs,
+			orderQty,
+			over,
 			fmt.Sprintf("%.2f", ing.CostPerUnit),
-			fmt.Sprintf("%.2f", ing.TotalCost),
+			fmt.Sprintf("%.2f", ing.Cost()),
+			overCost,
 		})
 	}
 
 	_ = w.Write([]string{})
 
 	// --- SECTION 3: Subrecipes (aggregated) ------------------------
+	// Deepest first: every batch is listed before the ones it goes into.
 	_ = w.Write([]string{"# Subrecipes (aggregated, for bulk prep)"})
-	_ = w.Write([]string{"Subrecipe", "Unit", "Total Qty"})
-
-	subSlice := make([]*subAgg, 0, len(subrecipes))
-	for _, v := range subrecipes {
-		subSlice = append(subSlice, v)
-	}
-	sort.Slice(subSlice, func(i, j int) bool {
-		return subSlice[i].Name < subSlice[j].Name
-	})
+	_ = w.Write([]string{"Subrecipe", "Depth", "Unit", "Total Qty", "Used In"})
 
-	for _, s := range subSlice {
+	for _, s := range forecast.Subrecipes {
 		_ = w.Write([]string{
 			s.Name,
+			strconv.Itoa(s.Depth),
 			s.Unit,
-			fmt.Sprintf("%.3f", s.TotalQty),
+			fmt.Sprintf("%.3f", s.Qty),
+			strings.Join(s.UsedIn(), "; "),
 		})
 	}
 
+	// --- SECTION 4/5: Per-day and per-outlet subtotals -------------
+	writeSubtotals := func(title, keyName string, subtotals []*forecastSubtotal) {
+		_ = w.Write([]string{})
+		_ = w.Write([]string{title})
+		_ = w.Write([]string{keyName, "Dishes", "Portions", "Ingredient Cost"})
+		for _, st := range subtotals {
+			_ = w.Write([]string{
+				st.Key,
+				strconv.Itoa(st.Dishes),
+				fmt.Sprintf("%.3f", st.Portions),
+				fmt.Sprintf("%.2f", st.Cost),
+			})
+		}
+	}
+	if hasDays {
+		writeSubtotals("# Per-day subtotals", "Day", daySubtotals)
+	}
+	if hasOutlets {
+		writeSubtotals("# Per-outlet subtotals", "Outlet", outletSubtotals)
+	}
+
 	w.Flush()
 	if err := w.Error(); err != nil {
 		fmt.Fprintf(os.Stderr, "error writing csv: %v\n", err)
 		os.Exit(1)
 	}
 
-	fmt.Printf("Forecast exported to %s\n", *outFile)
+	fmt.Printf("Forecast exported to %s\n", outFile)
+}
+
+// parseDishSpecs resolves "DISH Name=PORTIONS" arguments, exiting on the
+// first invalid one.
+func parseDishSpecs(db *sql.DB, specs []string) []dishForecast {
+	var dishes []dishForecast
+
+	for _, spec := range specs {
+		parts := strings.SplitN(spec, "=", 2)
+		if len(parts) != 2 {
+			fmt.Fprintf(os.Stderr, "invalid spec (expected NAME=QTY): %s\n", spec)
+			os.Exit(1)
+		}
+
+		rawName := strings.TrimSpace(parts[0])
+		qtyStr := strings.TrimSpace(parts[1])
+
+		if rawName == "" {
+			fmt.Fprintf(os.Stderr, "empty dish name in spec: %s\n", spec)
+			os.Exit(1)
+		}
+
+		portions, err := strconv.ParseFloat(qtyStr, 64)
+		if err != nil || portions <= 0 {
+			fmt.Fprintf(os.Stderr, "invalid portions in spec (need positive number): %s\n", spec)
+			os.Exit(1)
+		}
+
+		dishes = append(dishes, resolveDish(db, rawName, portions))
+	}
+
+	return dishes
+}
+
+// planDishes resolves the entries of a plan file or event. Event entries
+// already carry their recipe and are not looked up by name again.
+func planDishes(db *sql.DB, plan *internal.Plan) []dishForecast {
+	var dishes []dishForecast
+	for _, e := range plan.Entries {
+		var d dishForecast
+		if e.RecipeID != 0 {
+			d = recipeDish(db, e.RecipeID, e.Portions)
+		} else {
+			d = resolveDish(db, e.Dish, e.Portions)
+		}
+		d.Day = e.Day
+		d.Outlet = e.Outlet
+		dishes = append(dishes, d)
+	}
+	return dishes
+}
+
+func resolveDish(db *sql.DB, rawName string, portions float64) dishForecast {
+	recipeID, _, err := findRecipeByName(db, rawName)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			fmt.Fprintf(os.Stderr, "recipe not found for forecast: %s\n", rawName)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "error resolving recipe %q: %v\n", rawName, err)
+		os.Exit(1)
+	}
+	return recipeDish(db, recipeID, portions)
+}
+
+// recipeDish is portions of the recipe with recipeID, with its yield.
+func recipeDish(db *sql.DB, recipeID int, portions float64) dishForecast {
+	recipe, err := sqliteStore(db).Recipe(recipeID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error loading recipe %d: %v\n", recipeID, err)
+		os.Exit(1)
+	}
+	if recipe.YieldQty <= 0 {
+		fmt.Fprintf(os.Stderr, "recipe %s has no positive yield\n", recipe.Name)
+		os.Exit(1)
+	}
+
+	return dishForecast{
+		RecipeID:  recipeID,
+		Name:      recipe.Name,
+		Portions:  portions,
+		YieldQty:  recipe.YieldQty,
+		YieldUnit: recipe.YieldUnit,
+	}
+}
+
+// forecastSubtotal is one row of the per-day or per-outlet sections.
+type forecastSubtotal struct {
+	Key      string
+	Dishes   int
+	Portions float64
+	Cost     float64
+}
+
+// subtotalsBy groups dishes by key (day or outlet), sorted, with dishes
+// without a key last under "(none)". A dish that cannot be costed is an
+// error rather than a subtotal that silently leaves it out.
+func subtotalsBy(coster chefops.Coster, dishes []dishForecast, key func(dishForecast) string) ([]*forecastSubtotal, error) {
+	byKey := make(map[string]*forecastSubtotal)
+	for _, d := range dishes {
+		k := key(d)
+		st, ok := byKey[k]
+		if !ok {
+			st = &forecastSubtotal{Key: k}
+			byKey[k] = st
+		}
+		st.Dishes++
+		st.Portions += d.Portions
+		rc, err := coster.Cost(d.RecipeID)
+		if err != nil {
+			return nil, fmt.Errorf("costing %s: %w", d.Name, err)
+		}
+		st.Cost += rc.CostPerYieldUnit() * d.Portions
+	}
+
+	list := make([]*forecastSubtotal, 0, len(byKey))
+	for _, st := range byKey {
+		list = append(list, st)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if (list[i].Key == "") != (list[j].Key == "") {
+			return list[j].Key == ""
+		}
+		return list[i].Key < list[j].Key
+	})
+	for _, st := range list {
+		if st.Key == "" {
+			st.Key = "(none)"
+		}
+	}
+	return list, nil
 }
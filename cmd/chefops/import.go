@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ChefChristoph/chefops/internal"
+)
+
+// ------------------------------------------------------------
+// import
+//
+// Example:
+//
+//	chefops import ingredients ingredients.csv --dry-run
+//	chefops import recipes recipes.csv --mode replace
+//
+// ------------------------------------------------------------
+func importCommand(args []string) {
+	if len(args) < 1 {
+		fmt.Println("usage: chefops import <ingredients|recipes> FILE.csv [--dry-run] ...")
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "ingredients":
+		importFile("import ingredients", args[1:], false)
+	case "recipes":
+		importFile("import recipes", args[1:], true)
+	default:
+		fmt.Println("unknown import subcommand:", args[0])
+		os.Exit(1)
+	}
+}
+
+// importFile checks a CSV file against the database, prints the changes
+// and, unless --dry-run, applies them in one transaction.
+func importFile(name string, args []string, recipes bool) {
+	paths, flagArgs := splitNameAndFlags(args)
+
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "show the changes without writing them")
+	mode := internal.ImportUpsert
+	if recipes {
+		fs.StringVar(&mode, "mode", internal.ImportUpsert,
+			"upsert: add or update the listed lines, keep the rest; replace: also delete lines the file does not list")
+	}
+	fs.Parse(flagArgs)
+	paths = append(paths, fs.Args()...)
+
+	if len(paths) != 1 {
+		if recipes {
+			fmt.Fprintln(os.Stderr, "usage: chefops import recipes FILE.csv [--dry-run] [--mode upsert|replace]")
+		} else {
+			fmt.Fprintln(os.Stderr, "usage: chefops import ingredients FILE.csv [--dry-run]")
+		}
+		os.Exit(1)
+	}
+	path := paths[0]
+
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cannot open %s: %v\n", path, err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	db := openDBOrExit()
+	defer db.Close()
+
+	var imp *internal.Import
+	if recipes {
+		imp, err = internal.PlanRecipeImport(db, f, mode)
+	} else {
+		imp, err = internal.PlanIngredientImport(db, f)
+	}
+	var rowErrs internal.ImportErrors
+	if errors.As(err, &rowErrs) {
+		for _, e := range rowErrs {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, e)
+		}
+		fmt.Fprintf(os.Stderr, "nothing imported: %d row(s) with errors\n", len(rowErrs))
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+		os.Exit(1)
+	}
+
+	for _, c := range imp.Changes {
+		switch c.Action {
+		case internal.ImportAdd:
+			fmt.Println("+", c.Name)
+		case internal.ImportUpdate:
+			fmt.Println("~", c.Name)
+		default:
+			continue
+		}
+		for _, d := range c.Details {
+			fmt.Println("   ", d)
+		}
+	}
+
+	added := imp.Count(internal.ImportAdd)
+	updated := imp.Count(internal.ImportUpdate)
+	unchanged := imp.Count(internal.ImportUnchanged)
+	if *dryRun {
+		fmt.Printf("Dry run, nothing written: %d to add, %d to update, %d unchanged.\n", added, updated, unchanged)
+		return
+	}
+	if err := imp.Apply(db); err != nil {
+		fmt.Fprintf(os.Stderr, "error importing %s, nothing written: %v\n", path, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Imported %s: %d added, %d updated, %d unchanged.\n", filepath.Base(path), added, updated, unchanged)
+}
@@ -38,16 +38,11 @@ func ingredientConversionAdd(args []string) {
 		os.Exit(1)
 	}
 
-	db, _ := internal.OpenDB()
+	db := openDBOrExit()
 	defer db.Close()
 
 	// Get ingredient ID
-	var ingID int
-	err := db.QueryRow(`SELECT id FROM ingredients WHERE name = ?`, *ingredientName).Scan(&ingID)
-	if err != nil {
-		fmt.Println("ingredient not found:", *ingredientName)
-		os.Exit(1)
-	}
+	ingID, _ := findIngredientOrExit(db, *ingredientName)
 
 	parse := func(s string) (qty float64, unit string) {
 		// Example input: 1kg, 10piece, 100g_breadcrumbs
@@ -60,18 +55,20 @@ func ingredientConversionAdd(args []string) {
 		qtyStr := s[:i]
 		unit = s[i:]
 		qty, _ = strconv.ParseFloat(qtyStr, 64)
+		unit = internal.CanonicalUnit(unit)
 		return
 	}
 
 	fromQty, fromUnit := parse(*fromStr)
 	toQty, toUnit := parse(*toStr)
 
-	_, err = db.Exec(`
-		INSERT INTO ingredient_conversions 
-		(ingredient_id, from_unit, from_qty, to_unit, to_qty)
-		VALUES (?, ?, ?, ?, ?)
-	`, ingID, fromUnit, fromQty, toUnit, toQty)
-
+	err := sqliteStore(db).AddConversion(internal.Conversion{
+		IngredientID: ingID,
+		FromQty:      fromQty,
+		FromUnit:     fromUnit,
+		ToQty:        toQty,
+		ToUnit:       toUnit,
+	})
 	if err != nil {
 		fmt.Println("error adding conversion:", err)
 		os.Exit(1)
@@ -87,28 +84,18 @@ func ingredientConversionList(args []string) {
 	}
 	name := args[0]
 
-	db, _ := internal.OpenDB()
+	db := openDBOrExit()
 	defer db.Close()
 
-	var ingID int
-	err := db.QueryRow(`SELECT id FROM ingredients WHERE name = ?`, name).Scan(&ingID)
+	ingID, name := findIngredientOrExit(db, name)
+	conversions, err := sqliteStore(db).Conversions(ingID)
 	if err != nil {
-		fmt.Println("ingredient not found:", name)
+		fmt.Fprintln(os.Stderr, "error loading conversions:", err)
 		os.Exit(1)
 	}
 
-	rows, _ := db.Query(`
-		SELECT from_qty, from_unit, to_qty, to_unit
-		FROM ingredient_conversions
-		WHERE ingredient_id = ?
-	`, ingID)
-	defer rows.Close()
-
 	fmt.Printf("\nConversions for %s:\n", name)
-	for rows.Next() {
-		var fq, tq float64
-		var fu, tu string
-		rows.Scan(&fq, &fu, &tq, &tu)
-		fmt.Printf("  %.3f %s → %.3f %s\n", fq, fu, tq, tu)
+	for _, c := range conversions {
+		fmt.Printf("  %.3f %s → %.3f %s\n", c.FromQty, c.FromUnit, c.ToQty, c.ToUnit)
 	}
 }
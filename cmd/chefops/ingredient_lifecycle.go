@@ -0,0 +1,191 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ChefChristoph/chefops/internal"
+)
+
+// ------------------------------------------------------------
+// ingredient rename / merge / delete
+//
+// Example:
+//
+//	chefops ingredient rename "Lemmon" --to "Lemon"
+//	chefops ingredient merge "Lemons" --into "Lemon"
+//	chefops ingredient delete "Stracciatella"
+//
+// ------------------------------------------------------------
+func ingredientRename(args []string) {
+	nameParts, flagArgs := splitNameAndFlags(args)
+
+	fs := flag.NewFlagSet("ingredient rename", flag.ExitOnError)
+	to := fs.String("to", "", "new name")
+	fs.Parse(flagArgs)
+	nameParts = append(nameParts, fs.Args()...)
+
+	if len(nameParts) == 0 || strings.TrimSpace(*to) == "" {
+		fmt.Println("usage: chefops ingredient rename NAME --to NEW_NAME")
+		os.Exit(1)
+	}
+
+	db := openDBOrExit()
+	defer db.Close()
+
+	ingID, oldName := findIngredientOrExit(db, strings.Join(nameParts, " "))
+	if err := internal.RenameIngredient(db, ingID, *to); err != nil {
+		fmt.Fprintf(os.Stderr, "error renaming ingredient: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Renamed %s → %s\n", oldName, strings.TrimSpace(*to))
+
+	uses, err := internal.IngredientWhereUsed(db, ingID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error looking up uses: %v\n", err)
+		os.Exit(1)
+	}
+	printAffectedRecipes(uses)
+}
+
+func ingredientMerge(args []string) {
+	nameParts, flagArgs := splitNameAndFlags(args)
+
+	fs := flag.NewFlagSet("ingredient merge", flag.ExitOnError)
+	into := fs.String("into", "", "ingredient to keep")
+	fs.Parse(flagArgs)
+	nameParts = append(nameParts, fs.Args()...)
+
+	if len(nameParts) == 0 || strings.TrimSpace(*into) == "" {
+		fmt.Println("usage: chefops ingredient merge NAME --into KEPT_NAME")
+		os.Exit(1)
+	}
+
+	db := openDBOrExit()
+	defer db.Close()
+
+	fromID, fromName := findIngredientOrExit(db, strings.Join(nameParts, " "))
+	intoID, intoName := findIngredientOrExit(db, *into)
+
+	res, err := internal.MergeIngredients(db, fromID, intoID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error merging ingredients: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Merged %s into %s\n", fromName, intoName)
+	fmt.Printf("  %d conversion(s), %d price(s), %d stock count(s), %d supplier link(s) moved\n",
+		res.Conversions, res.Prices, res.StockCounts, res.SupplierLinks)
+	if len(res.Conflicts) > 0 {
+		fmt.Fprintf(os.Stderr, "\nWARNING: these conversions disagree with %s's and were dropped:\n", intoName)
+		for _, c := range res.Conflicts {
+			fmt.Fprintf(os.Stderr, "  %s\n", c)
+		}
+	}
+
+	if len(res.Recipes) == 0 {
+		fmt.Println("\nNo recipes used it.")
+		return
+	}
+	fmt.Printf("\nRecipes updated (%d):\n", len(res.Recipes))
+	for _, r := range res.Recipes {
+		fmt.Printf("  %s\n", r)
+	}
+	if len(res.Summed) > 0 {
+		fmt.Printf("\nLines added together (these recipes used both):\n")
+		for _, r := range res.Summed {
+			fmt.Printf("  %s\n", r)
+		}
+	}
+	if len(res.Converted) > 0 {
+		fmt.Printf("\nLines rewritten in %s's unit (their units convert differently):\n", intoName)
+		for _, r := range res.Converted {
+			fmt.Printf("  %s\n", r)
+		}
+	}
+	if len(res.Duplicates) > 0 {
+		fmt.Fprintf(os.Stderr, "\nWARNING: these recipes now list %s twice (units do not convert); check them:\n", intoName)
+		for _, d := range res.Duplicates {
+			fmt.Fprintf(os.Stderr, "  chefops recipe show %q  (%v)\n", d.Recipe, d.Err)
+		}
+	}
+}
+
+func ingredientDelete(args []string) {
+	if len(args) == 0 {
+		fmt.Println("usage: chefops ingredient delete NAME")
+		os.Exit(1)
+	}
+
+	db := openDBOrExit()
+	defer db.Close()
+
+	ingID, ingName := findIngredientOrExit(db, strings.Join(args, " "))
+
+	refs, err := internal.IngredientReferences(db, ingID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error looking up uses: %v\n", err)
+		os.Exit(1)
+	}
+	if len(refs) > 0 {
+		fmt.Fprintf(os.Stderr, "Cannot delete %s: %d recipe(s) still use it.\n\n", ingName, len(refs))
+		for _, r := range refs {
+			fmt.Fprintf(os.Stderr, "  %s\n", r)
+		}
+		fmt.Fprintf(os.Stderr, "\nRemove it from those first, or merge it into another ingredient:\n  chefops ingredient merge %q --into OTHER\n", ingName)
+		os.Exit(1)
+	}
+
+	if err := internal.DeleteIngredient(db, ingID); err != nil {
+		fmt.Fprintf(os.Stderr, "error deleting ingredient: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Deleted %s (no recipes used it)\n", ingName)
+}
+
+// printAffectedRecipes lists the recipes that show an ingredient, directly
+// or through subrecipes.
+func printAffectedRecipes(uses []*internal.WhereUsed) {
+	if len(uses) == 0 {
+		fmt.Println("No recipes use it.")
+		return
+	}
+	fmt.Printf("Affected recipes (%d):\n", len(uses))
+	for _, u := range uses {
+		if u.Depth > 1 {
+			fmt.Printf("  %s (via %s)\n", u.Name, strings.Join(u.Via, " → "))
+		} else {
+			fmt.Printf("  %s\n", u.Name)
+		}
+	}
+}
+
+// findIngredientOrExit looks an ingredient up by name, case-insensitively
+// but preferring an exact match (duplicates often differ only in case). It
+// exits when there is no match, or with exitAmbiguous when only several
+// case variants match.
+func findIngredientOrExit(db *sql.DB, name string) (int, string) {
+	name = strings.TrimSpace(name)
+	matches, err := sqliteStore(db).IngredientsNamed(name)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error finding ingredient:", err)
+		os.Exit(1)
+	}
+	for _, m := range matches {
+		if m.Name == name {
+			return m.ID, m.Name
+		}
+	}
+	switch len(matches) {
+	case 0:
+		fmt.Fprintln(os.Stderr, "ingredient not found:", name)
+		os.Exit(1)
+	case 1:
+		return matches[0].ID, matches[0].Name
+	}
+	exitWithAmbiguity("ambiguous", "ingredient", name, ingredientNames(matches))
+	return 0, ""
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ChefChristoph/chefops/internal"
+)
+
+func ingredientSetPack(args []string) {
+	fs := flag.NewFlagSet("ingredient set-pack", flag.ExitOnError)
+	name := fs.String("ingredient", "", "ingredient name")
+	pack := fs.String("pack", "", "purchase pack, e.g. 5liter or 100g")
+	partial := fs.Bool("partial", false, "partial packs can be ordered (e.g. loose produce)")
+	clear := fs.Bool("clear", false, "remove the pack size")
+	fs.Parse(args)
+
+	if *name == "" || (*pack == "" && !*clear) {
+		fmt.Fprintln(os.Stderr, "usage: chefops ingredient set-pack --ingredient NAME --pack 5liter [--partial] | --clear")
+		os.Exit(1)
+	}
+
+	db := openDBOrExit()
+	defer db.Close()
+
+	store := sqliteStore(db)
+	ingID, _ := findIngredientOrExit(db, *name)
+	ing, err := store.Ingredient(ingID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error loading ingredient: %v\n", err)
+		os.Exit(1)
+	}
+	unit := ing.Unit
+
+	if *clear {
+		if err := store.SetPackSize(ingID, nil); err != nil {
+			fmt.Fprintf(os.Stderr, "error clearing pack size: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Pack size cleared: %s\n", ing.Name)
+		return
+	}
+
+	qty, packUnit, err := internal.ParseQtyUnit(*pack)
+	if err == nil && qty <= 0 {
+		err = fmt.Errorf("pack size must be positive: %s", *pack)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if packUnit == "" {
+		packUnit = unit
+	}
+
+	base, err := internal.ConvertQty(store, ingID, qty, packUnit, unit)
+	if err != nil {
+		if errors.Is(err, internal.ErrNoConversion) {
+			fmt.Fprintf(os.Stderr, "%s is measured in %s; add a conversion first:\n  chefops ingredient convert add --ingredient %q --from 1%s --to ?%s\n",
+				*name, unit, *name, packUnit, unit)
+		} else {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		os.Exit(1)
+	}
+
+	if err := store.SetPackSize(ingID, &internal.PackSize{Qty: qty, Unit: packUnit, AllowPartial: *partial}); err != nil {
+		fmt.Fprintf(os.Stderr, "error saving pack size: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Pack size saved: %s bought per %g %s (= %.3f %s)", ing.Name, qty, packUnit, base, unit)
+	if *partial {
+		fmt.Print(", partial packs allowed")
+	}
+	fmt.Println()
+}
+
+// purchaseColumns formats the pack columns of a market list line.
+// Ingredients without a pack size show dashes.
+func purchaseColumns(p internal.Purchase) (pack, packs, orderQty, over, overCost string) {
+	if !p.HasPack {
+		return "-", "-", fmt.Sprintf("%.3f", p.OrderQty), "-", "-"
+	}
+	pack = fmt.Sprintf("%g %s", p.Pack.Qty, p.Pack.Unit)
+	if p.Pack.AllowPartial {
+		pack += " (partial ok)"
+		packs = fmt.Sprintf("%.2f", p.Packs)
+	} else {
+		packs = fmt.Sprintf("%.0f", p.Packs)
+	}
+	return pack, packs, fmt.Sprintf("%.3f", p.OrderQty), fmt.Sprintf("%.3f", p.OverQty), fmt.Sprintf("%.2f", p.OverCost)
+}
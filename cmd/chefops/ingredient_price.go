@@ -0,0 +1,105 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ChefChristoph/chefops/internal"
+)
+
+func ingredientPriceCommand(args []string) {
+	if len(args) < 1 {
+		fmt.Println("usage: chefops ingredient price <set|history> ...")
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "set":
+		ingredientPriceSet(args[1:])
+	case "history":
+		ingredientPriceHistory(args[1:])
+	default:
+		fmt.Println("unknown price subcommand:", args[0])
+		os.Exit(1)
+	}
+}
+
+func ingredientPriceSet(args []string) {
+	fs := flag.NewFlagSet("ingredient price set", flag.ExitOnError)
+	name := fs.String("ingredient", "", "ingredient name")
+	cost := fs.Float64("cost", 0, "cost per unit")
+	date := fs.String("date", "", "effective date YYYY-MM-DD (default today)")
+	supplier := fs.String("supplier", "", "supplier name")
+	source := fs.String("source", "", "where the price came from, e.g. an invoice number")
+	fs.Parse(args)
+
+	if *name == "" || *cost <= 0 {
+		fmt.Fprintln(os.Stderr, "usage: chefops ingredient price set --ingredient NAME --cost COST [--date YYYY-MM-DD] [--supplier NAME] [--source TEXT]")
+		os.Exit(1)
+	}
+
+	effective, err := internal.ParseDate(*date)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	db := openDBOrExit()
+	defer db.Close()
+
+	store := sqliteStore(db)
+	ingID, _ := findIngredientOrExit(db, *name)
+	ing, err := store.Ingredient(ingID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error loading ingredient: %v\n", err)
+		os.Exit(1)
+	}
+	unit := ing.Unit
+
+	if err := store.RecordPrice(ingID, *cost, effective, *supplier, *source); err != nil {
+		fmt.Fprintf(os.Stderr, "error saving price: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Price saved: %s %.2f / %s from %s\n", ing.Name, *cost, unit, effective)
+}
+
+func ingredientPriceHistory(args []string) {
+	if len(args) < 1 {
+		fmt.Println("usage: chefops ingredient price history \"Ingredient\"")
+		os.Exit(1)
+	}
+	name := args[0]
+
+	db := openDBOrExit()
+	defer db.Close()
+
+	store := sqliteStore(db)
+	ingID, _ := findIngredientOrExit(db, name)
+	ing, err := store.Ingredient(ingID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error loading ingredient: %v\n", err)
+		os.Exit(1)
+	}
+
+	history, err := store.PriceHistory(ingID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error loading price history: %v\n", err)
+		os.Exit(1)
+	}
+
+	t := newTable(
+		column{"effective_date", "EFFECTIVE"},
+		column{"cost_per_unit", "COST/UNIT"},
+		column{"supplier", "SUPPLIER"},
+		column{"source", "SOURCE"},
+	)
+	for _, p := range history {
+		t.add(p.EffectiveDate, fixed{p.CostPerUnit, 4}, p.Supplier, p.Source)
+	}
+	printResult(result{Table: t, Text: func() {
+		fmt.Printf("\nPrice history for %s (current %.4f / %s):\n\n", ing.Name, ing.CostPerUnit, ing.Unit)
+		t.print()
+	}})
+}
@@ -2,11 +2,13 @@ package main
 
 import (
 	"database/sql"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
-	"text/tabwriter"
+	"strings"
 
+	"github.com/ChefChristoph/chefops"
 	"github.com/ChefChristoph/chefops/internal"
 )
 
@@ -14,24 +16,78 @@ func usage() {
 	fmt.Println("ChefOps CLI")
 	fmt.Println("")
 	fmt.Println("Usage:")
+	fmt.Println("  chefops [--db PATH] [--output table|json|csv] [--yes | --non-interactive] [--strict] <command> ...")
+	fmt.Println("")
 	fmt.Println("  chefops ingredient add        --name NAME --unit UNIT --cost COST")
 	fmt.Println("  chefops ingredient list")
+	fmt.Println("  chefops ingredient price set --ingredient NAME --cost COST [--date YYYY-MM-DD] [--supplier NAME] [--source TEXT]")
+	fmt.Println("  chefops ingredient price history \"INGREDIENT\"")
+	fmt.Println("  chefops ingredient set-pack --ingredient NAME --pack 5liter [--partial] | --clear")
+	fmt.Println("  chefops ingredient where-used \"INGREDIENT\"")
+	fmt.Println("  chefops ingredient set-par --ingredient NAME --par QTY [--min QTY] [--unit UNIT] | --clear")
+	fmt.Println("  chefops ingredient rename \"INGREDIENT\" --to NEW_NAME")
+	fmt.Println("  chefops ingredient merge \"INGREDIENT\" --into KEPT_NAME")
+	fmt.Println("  chefops ingredient delete \"INGREDIENT\"")
 	fmt.Println("")
 	fmt.Println("  chefops recipe new            --name NAME --yield QTY --unit UNIT [--syield QTY --sunit UNIT]")
-	fmt.Println("  chefops recipe list")
+	fmt.Println("  chefops recipe list           [--archived | --all]")
 	fmt.Println("  chefops recipe show           \"RECIPE NAME\"")
-	fmt.Println("  chefops recipe cost           \"RECIPE NAME\"")
-	fmt.Println("  chefops recipe add-item       --recipe NAME --ingredient NAME --qty QTY")
-	fmt.Println("  chefops recipe add-subrecipe  --recipe NAME --sub NAME --qty QTY --unit UNIT")
+	fmt.Println("  chefops recipe cost           \"RECIPE NAME\" [--as-of YYYY-MM-DD]")
+	fmt.Println("  chefops recipe tree           \"RECIPE NAME\" [--qty QTY] [--unit UNIT]")
+	fmt.Println("  chefops recipe where-used     \"RECIPE NAME\"")
+	fmt.Println("  chefops recipe check-cycles")
+	fmt.Println("  chefops recipe add-item       --recipe NAME --ingredient NAME --qty QTY [--unit UNIT] [--existing add|replace]")
+	fmt.Println("  chefops recipe add-subrecipe  --recipe NAME --sub NAME --qty QTY [--unit UNIT]")
 	fmt.Println("  chefops recipe scale          \"RECIPE NAME\" --qty QTY --unit UNIT")
 	fmt.Println("  chefops recipe set-meta       \"RECIPE NAME\" FILEPATH")
 	fmt.Println("  chefops recipe export-meta    \"RECIPE NAME\" [--format=json|md]")
 	fmt.Println("  chefops recipe note import   --recipe \"NAME\" --file path/to/file.md")
 	fmt.Println("  chefops recipe note show     \"RECIPE NAME\"")
+	fmt.Println("  chefops recipe set-prep       --recipe NAME [--lead DAYS] [--batch 5kg] [--shelf-life DAYS] [--station NAME] | --clear")
+	fmt.Println("  chefops recipe rename         \"RECIPE NAME\" --to NEW_NAME")
+	fmt.Println("  chefops recipe clone          \"RECIPE NAME\" --name NEW_NAME [--with-subrecipes] [--suffix TEXT]")
+	fmt.Println("  chefops recipe delete         \"RECIPE NAME\"")
+	fmt.Println("  chefops recipe archive        \"RECIPE NAME\"")
+	fmt.Println("  chefops recipe unarchive      \"RECIPE NAME\"")
+	fmt.Println("")
+	fmt.Println("  chefops units")
+	fmt.Println("")
+	fmt.Println("  chefops db migrate")
+	fmt.Println("  chefops db status")
+	fmt.Println("  chefops doctor                [--fix] [--format text|json]")
+	fmt.Println("  chefops config show")
+	fmt.Println("  chefops config path")
+	fmt.Println("  chefops serve                 [--addr 127.0.0.1:8080] [--token TOKEN]")
+	fmt.Println("  chefops web                   [--addr 127.0.0.1:8080]")
+	fmt.Println("")
+	fmt.Println("  chefops import ingredients    FILE.csv [--dry-run]")
+	fmt.Println("  chefops import recipes        FILE.csv [--dry-run] [--mode upsert|replace]")
+	fmt.Println("")
+	fmt.Println("  chefops forecast              --out FILE.csv [--net] \"DISH NAME=PORTIONS\" ...")
+	fmt.Println("  chefops forecast              --out FILE.csv [--net] --plan PLAN.yaml|PLAN.csv")
 	fmt.Println("")
-	fmt.Println("  chefops forecast              \"DISH NAME\" --portions N")
+	fmt.Println("  chefops prep plan             [--day YYYY-MM-DD] [--out FILE.csv] \"DISH NAME=PORTIONS\" ...")
+	fmt.Println("  chefops prep plan             [--out FILE.csv] --plan PLAN.yaml|PLAN.csv | --event NAME")
 	fmt.Println("")
-	fmt.Println("  chefops marketlist")
+	fmt.Println("  chefops event new             --name NAME --start YYYY-MM-DD [--end YYYY-MM-DD] [--covers N]")
+	fmt.Println("  chefops event list")
+	fmt.Println("  chefops event set-covers      --event NAME --covers N [--day YYYY-MM-DD]")
+	fmt.Println("  chefops event add-dish        --event NAME --dish \"DISH NAME\" --mix PERCENT [--replace]")
+	fmt.Println("  chefops event remove-dish     --event NAME --dish \"DISH NAME\"")
+	fmt.Println("  chefops event forecast        --event NAME [--out FILE.csv] [--net]")
+	fmt.Println("  chefops event report          \"EVENT NAME\"")
+	fmt.Println("")
+	fmt.Println("  chefops marketlist            [--by-supplier] [--net]")
+	fmt.Println("")
+	fmt.Println("  chefops stock count           --ingredient NAME --qty QTY [--unit UNIT] [--location NAME] [--at \"YYYY-MM-DD HH:MM\"]")
+	fmt.Println("  chefops stock import          FILE.csv [--location NAME]")
+	fmt.Println("  chefops stock list            [--location NAME]")
+	fmt.Println("  chefops reorder               [--out FILE.csv|FILE.json] [--all] [\"DISH NAME=PORTIONS\" ...]")
+	fmt.Println("")
+	fmt.Println("  chefops supplier add          --name NAME [--contact TEXT] [--lead-time DAYS] [--order-days mon,thu] [--min-order VALUE]")
+	fmt.Println("  chefops supplier list")
+	fmt.Println("  chefops supplier show         \"SUPPLIER\"")
+	fmt.Println("  chefops supplier link         --ingredient NAME --supplier NAME [--sku SKU] [--pack 25kg] [--preferred]")
 	fmt.Println("")
 	fmt.Println("Examples:")
 	fmt.Println("  chefops recipe show \"BULK Batter\"")
@@ -45,28 +101,54 @@ func usage() {
 }
 
 func main() {
-	if len(os.Args) < 2 {
+	args, dbFlag := parseGlobalFlags(os.Args[1:])
+
+	if _, err := internal.LoadConfig(dbFlag); err != nil {
+		fmt.Fprintf(os.Stderr, "error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	if !validOutputFormat(outputFormat) {
+		fmt.Fprintf(os.Stderr, "--output must be table, json or csv, not %q\n", outputFormat)
+		os.Exit(1)
+	}
+
+	if len(args) < 1 {
 		usage()
 	}
 
-	switch os.Args[1] {
+	switch args[0] {
 
 	// -------------------
 	// INGREDIENT COMMANDS
 	// -------------------
 	case "ingredient":
-		if len(os.Args) < 3 {
+		if len(args) < 2 {
 			usage()
 		}
-		switch os.Args[2] {
+		switch args[1] {
 		case "add":
-			ingredientAdd(os.Args[3:])
+			ingredientAdd(args[2:])
 		case "list":
-			ingredientList(os.Args[3:])
+			ingredientList(args[2:])
 		case "find":
-			ingredientFind(os.Args[3:])
+			ingredientFind(args[2:])
 		case "convert":
-			ingredientConversionCommand(os.Args[3:])
+			ingredientConversionCommand(args[2:])
+		case "price":
+			ingredientPriceCommand(args[2:])
+		case "set-pack":
+			ingredientSetPack(args[2:])
+		case "set-par":
+			ingredientSetPar(args[2:])
+		case "where-used":
+			ingredientWhereUsed(args[2:])
+		case "rename":
+			ingredientRename(args[2:])
+		case "merge":
+			ingredientMerge(args[2:])
+		case "delete":
+			ingredientDelete(args[2:])
 		default:
 			usage()
 		}
@@ -75,51 +157,128 @@ func main() {
 	// RECIPE COMMANDS
 	// --------------
 	case "recipe":
-		if len(os.Args) < 3 {
+		if len(args) < 2 {
 			usage()
 		}
-		switch os.Args[2] {
+		switch args[1] {
 		case "new":
-			recipeNew(os.Args[3:])
+			recipeNew(args[2:])
 		case "list":
-			recipeList(os.Args[3:])
+			recipeList(args[2:])
 		case "add-item":
-			recipeAddItem(os.Args[3:])
+			recipeAddItem(args[2:])
 		case "show":
-			recipeShow(os.Args[3:])
+			recipeShow(args[2:])
 		case "cost":
-			recipeCost(os.Args[3:])
+			recipeCost(args[2:])
 		case "remove-item":
-			recipeRemoveItem(os.Args[3:])
+			recipeRemoveItem(args[2:])
 		case "add-subrecipe":
-			recipeAddSubrecipe(os.Args[3:])
+			recipeAddSubrecipe(args[2:])
 		case "remove-subrecipe":
-			recipeRemoveSubrecipe(os.Args[3:])
+			recipeRemoveSubrecipe(args[2:])
 		case "scale":
-			recipeScale(os.Args[3:])
+			recipeScale(args[2:])
 		case "set-meta":
-			handleSetMetadata(os.Args[3:])
+			handleSetMetadata(args[2:])
 		case "export-meta":
-			handleExportMetadata(os.Args[3:])
+			handleExportMetadata(args[2:])
 		case "note":
-			recipeNoteCommand(os.Args[3:])
+			recipeNoteCommand(args[2:])
+		case "set-prep":
+			recipeSetPrep(args[2:])
+		case "tree":
+			recipeTree(args[2:])
+		case "where-used":
+			recipeWhereUsed(args[2:])
+		case "check-cycles":
+			recipeCheckCycles(args[2:])
+		case "rename":
+			recipeRename(args[2:])
+		case "clone":
+			recipeClone(args[2:])
+		case "delete":
+			recipeDelete(args[2:])
+		case "archive":
+			recipeArchive(args[2:])
+		case "unarchive":
+			recipeUnarchive(args[2:])
 		default:
 			usage()
 		}
 		// FORECAST COMMANDS
 	case "forecast":
-		forecastCommand(os.Args[2:])
+		forecastCommand(args[1:])
 	// -------------------------
 	// MARKETLIST COMMANDS
 	// -------------------------
 	case "marketlist":
-		marketlist(os.Args[2:])
+		marketlist(args[1:])
+
+	// -------------------------
+	// INVENTORY COMMANDS
+	// -------------------------
+	case "stock":
+		stockCommand(args[1:])
+	case "reorder":
+		reorderCommand(args[1:])
+
+	// -------------------------
+	// PREP COMMANDS
+	// -------------------------
+	case "prep":
+		prepCommand(args[1:])
+
+	// -------------------------
+	// EVENT COMMANDS
+	// -------------------------
+	case "event":
+		eventCommand(args[1:])
+
+	// -------------------------
+	// SUPPLIER COMMANDS
+	// -------------------------
+	case "supplier":
+		supplierCommand(args[1:])
 
 		// -------------------------
 		// export COMMANDS
 	// -------------------------
 	case "export":
-		exportCommand(os.Args[2:])
+		exportCommand(args[1:])
+
+	// -------------------------
+	// CSV IMPORT
+	// -------------------------
+	case "import":
+		importCommand(args[1:])
+
+	// -------------------------
+	// DATABASE COMMANDS
+	// -------------------------
+	case "db":
+		dbCommand(args[1:])
+
+	case "config":
+		configCommand(args[1:])
+
+	case "doctor":
+		doctorCommand(args[1:])
+
+	// -------------------------
+	// UNIT LIBRARY
+	// -------------------------
+	case "units":
+		unitsList(args[1:])
+
+	// -------------------------
+	// HTTP API
+	// -------------------------
+	case "serve":
+		serveCommand(args[1:])
+
+	case "web":
+		webCommand(args[1:])
 
 	// -------------------------
 	// UNKNOWN TOP-LEVEL COMMAND
@@ -128,15 +287,80 @@ func main() {
 		usage()
 	}
 }
+
+// parseGlobalFlags strips flags that apply to every command (--db PATH /
+// --db=PATH, --output FORMAT, --yes / --non-interactive and --strict) from
+// args, wherever they appear before "--". All but --db are recorded in
+// outputFormat, assumeYes, nonInteractive and strictNames.
+func parseGlobalFlags(args []string) ([]string, string) {
+	var rest []string
+	var dbPath string
+
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+
+		if a == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+
+		switch {
+		case (a == "--db" || a == "-db") && i+1 < len(args):
+			dbPath = args[i+1]
+			i++
+		case strings.HasPrefix(a, "--db="):
+			dbPath = strings.TrimPrefix(a, "--db=")
+		case strings.HasPrefix(a, "-db="):
+			dbPath = strings.TrimPrefix(a, "-db=")
+		case a == "--output" && i+1 < len(args):
+			outputFormat = args[i+1]
+			i++
+		case strings.HasPrefix(a, "--output="):
+			outputFormat = strings.TrimPrefix(a, "--output=")
+		case a == "--yes" || a == "-y":
+			assumeYes = true
+			nonInteractive = true
+		case a == "--non-interactive":
+			nonInteractive = true
+		case a == "--strict":
+			strictNames = true
+		default:
+			rest = append(rest, a)
+		}
+	}
+
+	return rest, dbPath
+}
+
 func openDBOrExit() *sql.DB {
 	db, err := internal.OpenDB()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error opening DB: %v\n", err)
-		os.Exit(1)
+		exitOpenError(err)
 	}
 	return db
 }
 
+// exitOpenError reports a database that could not be opened and exits.
+// A missing file gets a hint, since it is usually a mistyped --db.
+func exitOpenError(err error) {
+	fmt.Fprintf(os.Stderr, "error opening DB: %v\n", err)
+	if errors.Is(err, internal.ErrNoDatabase) {
+		fmt.Fprintln(os.Stderr, "check --db / CHEFOPS_DB, or create a new database with `chefops db migrate`")
+	}
+	os.Exit(1)
+}
+
+// sqliteStore returns the chefops library's Store over db, with the lookups
+// and edits the commands need.
+func sqliteStore(db *sql.DB) *chefops.SQLiteStore {
+	return chefops.NewSQLiteStore(db)
+}
+
+// kitchen returns the chefops library's costing services over db.
+func kitchen(db *sql.DB) *chefops.Kitchen {
+	return chefops.New(sqliteStore(db))
+}
+
 func ingredientAdd(args []string) {
 	fs := flag.NewFlagSet("ingredient add", flag.ExitOnError)
 	name := fs.String("name", "", "ingredient name")
@@ -150,19 +374,18 @@ func ingredientAdd(args []string) {
 		os.Exit(1)
 	}
 
+	canonical, err := internal.NormalizeUnit(*unit)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	*unit = canonical
+
 	db := openDBOrExit()
 	defer db.Close()
 
-	const q = `
-		INSERT INTO ingredients (name, unit, cost_per_unit)
-		VALUES (?, ?, ?)
-		ON CONFLICT(name) DO UPDATE SET
-		    unit = excluded.unit,
-		    cost_per_unit = excluded.cost_per_unit;
-	`
-	_, err := db.Exec(q, *name, *unit, *cost)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "error inserting ingredient: %v\n", err)
+	if _, err := sqliteStore(db).SaveIngredient(*name, *unit, *cost, "ingredient add"); err != nil {
+		fmt.Fprintf(os.Stderr, "error saving ingredient: %v\n", err)
 		os.Exit(1)
 	}
 
@@ -176,27 +399,25 @@ func ingredientList(args []string) {
 	db := openDBOrExit()
 	defer db.Close()
 
-	rows, err := db.Query(`SELECT id, name, unit, cost_per_unit FROM ingredients ORDER BY name;`)
+	ingredients, err := sqliteStore(db).Ingredients()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error querying ingredients: %v\n", err)
 		os.Exit(1)
 	}
-	defer rows.Close()
-
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "ID\tNAME\tUNIT\tCOST/UNIT")
-	for rows.Next() {
-		var (
-			id   int
-			name string
-			unit string
-			cost float64
-		)
-		if err := rows.Scan(&id, &name, &unit, &cost); err != nil {
-			fmt.Fprintf(os.Stderr, "error scanning row: %v\n", err)
-			os.Exit(1)
-		}
-		fmt.Fprintf(w, "%d\t%s\t%s\t%.4f\n", id, name, unit, cost)
+
+	t := newIngredientTable()
+	for _, ing := range ingredients {
+		t.add(ing.ID, ing.Name, ing.Unit, fixed{ing.CostPerUnit, 4})
 	}
-	w.Flush()
+	printResult(result{Table: t})
+}
+
+// newIngredientTable is the layout of `ingredient list` and `ingredient find`.
+func newIngredientTable() *table {
+	return newTable(
+		column{"id", "ID"},
+		column{"name", "NAME"},
+		column{"unit", "UNIT"},
+		column{"cost_per_unit", "COST/UNIT"},
+	)
 }
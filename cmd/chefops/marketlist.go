@@ -1,56 +1,193 @@
 package main
 
 import (
-    "flag"
-    "fmt"
-    "os"
-    "text/tabwriter"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
 
-    "github.com/ChefChristoph/chefops/internal"
+	"github.com/ChefChristoph/chefops/internal"
 )
 
 func marketlist(args []string) {
-    fs := flag.NewFlagSet("marketlist", flag.ExitOnError)
-    fs.Parse(args)
-
-    db, _ := internal.OpenDB()
-    defer db.Close()
-
-    const q = `
-        SELECT
-            ingredient_name,
-            unit,
-            cost_per_unit,
-            total_qty,
-            total_cost
-        FROM market_list
-        ORDER BY ingredient_name;
-    `
-
-    rows, err := db.Query(q)
-    if err != nil {
-        fmt.Fprintf(os.Stderr, "error querying market list: %v\n", err)
-        os.Exit(1)
-    }
-    defer rows.Close()
-
-    w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-    fmt.Fprintln(w, "INGREDIENT\tUNIT\tTOTAL QTY\tUNIT COST\tTOTAL COST")
-
-    for rows.Next() {
-        var name, unit string
-        var qty, unitCost, totalCost float64
-
-        if err := rows.Scan(&name, &unit, &unitCost, &qty, &totalCost); err != nil {
-            fmt.Fprintf(os.Stderr, "scan error: %v\n", err)
-            os.Exit(1)
-        }
-
-        fmt.Fprintf(
-            w, "%s\t%s\t%.3f\t%.2f\t%.2f\n",
-            name, unit, qty, unitCost, totalCost,
-        )
-    }
-
-    w.Flush()
+	fs := flag.NewFlagSet("marketlist", flag.ExitOnError)
+	bySupplier := fs.Bool("by-supplier", false, "one section per supplier with subtotals")
+	net := fs.Bool("net", false, "subtract stock on hand")
+	fs.Parse(args)
+
+	db := openDBOrExit()
+	defer db.Close()
+
+	items, err := kitchen(db).MarketList()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error building market list: %v\n", err)
+		os.Exit(1)
+	}
+	if *net {
+		items = netOfStockOrExit(db, items)
+	}
+
+	plan, err := internal.PlanPurchases(db, items)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error rounding to pack sizes: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *bySupplier {
+		groups, err := internal.GroupBySupplier(db, items)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error grouping by supplier: %v\n", err)
+			os.Exit(1)
+		}
+		sections := marketlistSections(groups, plan)
+		t := newMarketlistTable(true)
+		for _, sec := range sections {
+			for _, it := range sec.Items {
+				t.addItem(sec.Supplier, it)
+			}
+		}
+		printResult(result{Table: t.table, Doc: sections, Text: func() {
+			printNetNote(*net)
+			printMarketlistBySupplier(groups, plan)
+		}})
+		return
+	}
+
+	list := []marketlistItem{}
+	t := newMarketlistTable(false)
+	for _, u := range items {
+		it := newMarketlistItem(u, plan[u.IngredientID], "")
+		list = append(list, it)
+		t.addItem("", it)
+	}
+	printResult(result{Table: t.table, Doc: list, Text: func() {
+		printNetNote(*net)
+		printMarketlistTable(items, plan)
+	}})
+}
+
+func printNetNote(net bool) {
+	if net {
+		fmt.Println("Net of stock on hand")
+	}
+}
+
+// marketlistTable is the CSV layout of the market list, one row per
+// ingredient, with the supplier first when grouped by supplier.
+type marketlistTable struct {
+	*table
+	bySupplier bool
+}
+
+func newMarketlistTable(bySupplier bool) marketlistTable {
+	var cols []column
+	if bySupplier {
+		cols = append(cols, column{"supplier", "SUPPLIER"}, column{"sku", "SKU"})
+	}
+	cols = append(cols,
+		column{"name", "INGREDIENT"},
+		column{"unit", "UNIT"},
+		column{"qty", "TOTAL QTY"},
+		column{"pack", "PACK"},
+		column{"packs", "PACKS"},
+		column{"order_qty", "ORDER QTY"},
+		column{"over_qty", "OVER"},
+		column{"cost_per_unit", "UNIT COST"},
+		column{"estimated_cost", "TOTAL COST"},
+		column{"over_cost", "OVER COST"},
+	)
+	return marketlistTable{table: newTable(cols...), bySupplier: bySupplier}
+}
+
+func (t marketlistTable) addItem(supplier string, it marketlistItem) {
+	var cells []any
+	if t.bySupplier {
+		cells = append(cells, supplier, it.SKU)
+	}
+	var packs any
+	if it.Pack != "" {
+		packs = it.Packs
+	}
+	cells = append(cells, it.Name, it.Unit, it.Qty, it.Pack, packs, it.OrderQty, it.OverQty, it.Cost, it.Est, it.OverCost)
+	t.add(cells...)
+}
+
+// printMarketlistTable prints theoretical quantities next to what to order
+// in whole packs.
+func printMarketlistTable(items []*internal.IngredientUsage, plan map[int]internal.Purchase) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "INGREDIENT\tUNIT\tTOTAL QTY\tPACK\tPACKS\tORDER QTY\tOVER\tUNIT COST\tTOTAL COST\tOVER COST")
+
+	for _, it := range items {
+		pack, packs, orderQty, over, overCost := purchaseColumns(plan[it.IngredientID])
+		fmt.Fprintf(
+			w, "%s\t%s\t%.3f\t%s\t%s\t%s\t%s\t%.2f\t%.2f\t%s\n",
+			it.Name, it.Unit, it.Qty, pack, packs, orderQty, over, it.CostPerUnit, it.Cost(), overCost,
+		)
+	}
+
+	w.Flush()
+}
+
+// overPurchaseCost sums the cost of rounding up to whole packs.
+func overPurchaseCost(items []*internal.IngredientUsage, plan map[int]internal.Purchase) float64 {
+	var total float64
+	for _, it := range items {
+		total += plan[it.IngredientID].OverCost
+	}
+	return total
+}
+
+func printMarketlistBySupplier(groups []*internal.SupplierGroup, plan map[int]internal.Purchase) {
+	money := internal.CurrentConfig().Money
+	var total, totalOver float64
+
+	for _, g := range groups {
+		fmt.Printf("\n== %s ==\n", g.Supplier.Name)
+		if info := supplierOrderInfo(g.Supplier); info != "" {
+			fmt.Println(info)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "INGREDIENT\tSKU\tUNIT\tTOTAL QTY\tPACK\tPACKS\tORDER QTY\tOVER\tUNIT COST\tTOTAL COST\tOVER COST")
+		for _, it := range g.Items {
+			pack, packs, orderQty, over, overCost := purchaseColumns(plan[it.IngredientID])
+			fmt.Fprintf(
+				w, "%s\t%s\t%s\t%.3f\t%s\t%s\t%s\t%s\t%.2f\t%.2f\t%s\n",
+				it.Name, orDash(g.Links[it.IngredientID].SKU), it.Unit, it.Qty,
+				pack, packs, orderQty, over, it.CostPerUnit, it.Cost(), overCost,
+			)
+		}
+		w.Flush()
+
+		over := overPurchaseCost(g.Items, plan)
+		fmt.Printf("Subtotal: %s (+%s pack rounding)", money(g.Subtotal()), money(over))
+		if g.Supplier.BelowMinimum(g.Subtotal() + over) {
+			fmt.Printf("  (below minimum order %s)", money(g.Supplier.MinOrder))
+		}
+		fmt.Println()
+		total += g.Subtotal()
+		totalOver += over
+	}
+
+	fmt.Printf("\nTotal: %s (+%s pack rounding)\n", money(total), money(totalOver))
+}
+
+// supplierOrderInfo summarises when and how a supplier takes orders.
+func supplierOrderInfo(s internal.Supplier) string {
+	var parts []string
+	if s.Contact != "" {
+		parts = append(parts, "contact "+s.Contact)
+	}
+	if s.OrderDays != "" {
+		parts = append(parts, "orders "+s.OrderDays)
+	}
+	if s.LeadTimeDays > 0 {
+		parts = append(parts, "lead time "+leadTimeString(s.LeadTimeDays))
+	}
+	if s.MinOrder > 0 {
+		parts = append(parts, "min order "+internal.CurrentConfig().Money(s.MinOrder))
+	}
+	return strings.Join(parts, " · ")
 }
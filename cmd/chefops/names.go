@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/ChefChristoph/chefops/internal"
+)
+
+// exitAmbiguous is the exit status when a name does not pick out exactly
+// one recipe or ingredient and chefops may not ask which one was meant.
+const exitAmbiguous = 3
+
+// Name-matching modes, set by global flags (see parseGlobalFlags).
+var (
+	// nonInteractive (--non-interactive, or --yes) never prompts:
+	// ambiguous names fail with exitAmbiguous, and so do confirmations
+	// unless assumeYes is set.
+	nonInteractive bool
+	// assumeYes (--yes) answers confirmations yes.
+	assumeYes bool
+	// strictNames (--strict) only accepts exact names (case-insensitive);
+	// no fuzzy matching or suggestions are used.
+	strictNames bool
+)
+
+// ambiguity is the machine-readable error printed for exitAmbiguous.
+type ambiguity struct {
+	Error      string   `json:"error"` // "ambiguous" or "no exact match"
+	Kind       string   `json:"kind"`  // "recipe", "ingredient", ...
+	Input      string   `json:"input"`
+	Candidates []string `json:"candidates"`
+}
+
+// exitWithAmbiguity prints the candidates for input as one JSON line on
+// stderr and exits with exitAmbiguous.
+func exitWithAmbiguity(reason, kind, input string, candidates []string) {
+	if candidates == nil {
+		candidates = []string{}
+	}
+	data, _ := json.Marshal(ambiguity{Error: reason, Kind: kind, Input: input, Candidates: candidates})
+	fmt.Fprintln(os.Stderr, string(data))
+	os.Exit(exitAmbiguous)
+}
+
+// confirm asks a yes/no question on stdout; --yes answers it. Under
+// --non-interactive alone it exits with status 1 rather than guess.
+func confirm(question string) bool {
+	if nonInteractive && !assumeYes {
+		fmt.Fprintf(os.Stderr, "confirmation needed: %s (pass --yes to confirm)\n", question)
+		os.Exit(1)
+	}
+	fmt.Print(question + " (y/N): ")
+	if assumeYes {
+		fmt.Println("y")
+		return true
+	}
+	var answer string
+	fmt.Scanln(&answer)
+	return answer == "y" || answer == "Y"
+}
+
+func recipeNames(recipes []internal.Recipe) []string {
+	names := make([]string, len(recipes))
+	for i, r := range recipes {
+		names[i] = r.Name
+	}
+	return names
+}
+
+func ingredientNames(ingredients []internal.Ingredient) []string {
+	names := make([]string, len(ingredients))
+	for i, ing := range ingredients {
+		names[i] = ing.Name
+	}
+	return names
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/ChefChristoph/chefops/internal"
+)
+
+// Output formats of the read commands, chosen with the global --output flag.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputCSV   = "csv"
+)
+
+// outputFormat is set by parseGlobalFlags.
+var outputFormat = outputTable
+
+func validOutputFormat(f string) bool {
+	return f == outputTable || f == outputJSON || f == outputCSV
+}
+
+// column is one field of a result row.
+type column struct {
+	Key    string // JSON key and CSV header
+	Header string // table header
+}
+
+// table is the rows a read command produces. Cells are plain values
+// (string, int, float64, bool, nil) or fixed numbers.
+type table struct {
+	Columns []column
+	Rows    [][]any
+}
+
+func newTable(columns ...column) *table {
+	return &table{Columns: columns}
+}
+
+func (t *table) add(cells ...any) {
+	t.Rows = append(t.Rows, cells)
+}
+
+// fixed is a number shown with Prec decimals in the table view and written
+// in full, less float noise, to JSON and CSV.
+type fixed struct {
+	V    float64
+	Prec int
+}
+
+func (f fixed) MarshalJSON() ([]byte, error) {
+	return json.Marshal(internal.CleanFloat(f.V))
+}
+
+// result is what a read command prints. Table feeds CSV and, unless Doc is
+// set, JSON (as an array of objects). Text is the table view; without it
+// Table is printed in aligned columns.
+type result struct {
+	Table *table
+	Doc   any
+	Text  func()
+}
+
+// printResult renders r in outputFormat on stdout.
+func printResult(r result) {
+	var err error
+	switch outputFormat {
+	case outputJSON:
+		doc := r.Doc
+		if doc == nil {
+			doc = r.Table.objects()
+		}
+		var data []byte
+		if data, err = json.MarshalIndent(doc, "", "  "); err == nil {
+			fmt.Println(string(data))
+		}
+	case outputCSV:
+		err = r.Table.writeCSV()
+	default:
+		if r.Text != nil {
+			r.Text()
+		} else {
+			r.Table.print()
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error writing %s: %v\n", outputFormat, err)
+		os.Exit(1)
+	}
+}
+
+// print writes the table in aligned columns; empty cells show as "-".
+func (t *table) print() {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for i, c := range t.Columns {
+		if i > 0 {
+			fmt.Fprint(w, "\t")
+		}
+		fmt.Fprint(w, c.Header)
+	}
+	fmt.Fprintln(w)
+	for _, row := range t.Rows {
+		for i, cell := range row {
+			if i > 0 {
+				fmt.Fprint(w, "\t")
+			}
+			fmt.Fprint(w, orDash(cellText(cell, true)))
+		}
+		fmt.Fprintln(w)
+	}
+	w.Flush()
+}
+
+func (t *table) writeCSV() error {
+	cw := csv.NewWriter(os.Stdout)
+	header := make([]string, len(t.Columns))
+	for i, c := range t.Columns {
+		header[i] = c.Key
+	}
+	cw.Write(header)
+	for _, row := range t.Rows {
+		record := make([]string, len(row))
+		for i, cell := range row {
+			record[i] = cellText(cell, false)
+		}
+		cw.Write(record)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// objects returns the rows as JSON objects with keys in column order.
+func (t *table) objects() []orderedRow {
+	rows := make([]orderedRow, len(t.Rows))
+	for i, cells := range t.Rows {
+		rows[i] = orderedRow{columns: t.Columns, cells: cells}
+	}
+	return rows
+}
+
+// orderedRow marshals a table row as a JSON object, keeping column order.
+type orderedRow struct {
+	columns []column
+	cells   []any
+}
+
+func (r orderedRow) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, c := range r.columns {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(c.Key)
+		buf.Write(key)
+		buf.WriteByte(':')
+		var cell any
+		if i < len(r.cells) {
+			cell = r.cells[i]
+		}
+		if v, ok := cell.(float64); ok {
+			cell = internal.CleanFloat(v)
+		}
+		val, err := json.Marshal(cell)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(val)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// csvFloat writes v in full but without float noise (6.4512, not
+// 6.451199999999999).
+func csvFloat(v float64) string {
+	return strconv.FormatFloat(internal.CleanFloat(v), 'f', -1, 64)
+}
+
+// cellText formats a cell for the table view (rounded) or CSV (in full).
+func cellText(cell any, rounded bool) string {
+	switch v := cell.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case fixed:
+		if rounded {
+			return strconv.FormatFloat(v.V, 'f', v.Prec, 64)
+		}
+		return csvFloat(v.V)
+	case float64:
+		return csvFloat(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ChefChristoph/chefops/internal"
+)
+
+func prepCommand(args []string) {
+	if len(args) < 1 {
+		fmt.Println("usage: chefops prep plan ...")
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "plan":
+		prepPlan(args[1:])
+	default:
+		fmt.Println("unknown prep subcommand:", args[0])
+		os.Exit(1)
+	}
+}
+
+// ------------------------------------------------------------
+// prep plan
+//
+// Example:
+//
+//	chefops prep plan --plan forecasts/f1_weekend_plan.yaml
+//	chefops prep plan --event "F1 Weekend" --out prep.csv
+//	chefops prep plan --day 2026-12-05 "DISH Lobster Roll=500"
+//
+// ------------------------------------------------------------
+func prepPlan(args []string) {
+	fs := flag.NewFlagSet("prep plan", flag.ExitOnError)
+	planFile := fs.String("plan", "", "read dishes and portions from a plan file (.yaml or .csv)")
+	eventName := fs.String("event", "", "plan the prep for an event")
+	day := fs.String("day", "", "service day for dishes without one, YYYY-MM-DD (default today)")
+	outFile := fs.String("out", "", "also write the schedule to a CSV file")
+	fs.Parse(args)
+
+	specs := fs.Args()
+	if len(specs) == 0 && *planFile == "" && *eventName == "" {
+		fmt.Println("usage:")
+		fmt.Println("  chefops prep plan [--day YYYY-MM-DD] [--out FILE.csv] \"DISH Name=PORTIONS\" ...")
+		fmt.Println("  chefops prep plan [--out FILE.csv] --plan event.yaml")
+		fmt.Println("  chefops prep plan [--out FILE.csv] --event NAME")
+		os.Exit(1)
+	}
+
+	serviceDay, err := internal.ParseDate(*day)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	db := openDBOrExit()
+	defer db.Close()
+
+	var dishes []dishForecast
+	title := ""
+	if *planFile != "" {
+		plan, err := internal.LoadPlan(*planFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error reading plan: %v\n", err)
+			os.Exit(1)
+		}
+		dishes = append(dishes, planDishes(db, plan)...)
+		title = plan.Name
+	}
+	if *eventName != "" {
+		plan := loadEventOrExit(db, *eventName).Plan()
+		if len(plan.Entries) == 0 {
+			fmt.Fprintf(os.Stderr, "%s has no portions to plan; set covers and add dishes first\n", plan.Name)
+			os.Exit(1)
+		}
+		dishes = append(dishes, planDishes(db, plan)...)
+		title = plan.Name
+	}
+	dishes = append(dishes, parseDishSpecs(db, specs)...)
+
+	demand := make([]internal.PrepDemand, 0, len(dishes))
+	for _, d := range dishes {
+		if d.Day == "" {
+			d.Day = serviceDay
+		}
+		demand = append(demand, internal.PrepDemand{RecipeID: d.RecipeID, Qty: d.Portions, Day: d.Day})
+	}
+
+	settings, err := internal.LoadPrepSettings(db)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error loading prep settings: %v\n", err)
+		os.Exit(1)
+	}
+	tasks, err := internal.NewCoster(db).PrepSchedule(settings, demand)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error planning prep: %v\n", err)
+		os.Exit(1)
+	}
+
+	printPrepSchedule(title, tasks)
+
+	if *outFile != "" {
+		path := internal.CurrentConfig().ExportPath(*outFile)
+		if err := writePrepCSV(path, tasks); err != nil {
+			fmt.Fprintf(os.Stderr, "error writing %s: %v\n", path, err)
+			os.Exit(1)
+		}
+		fmt.Printf("\nPrep schedule exported to %s\n", path)
+	}
+}
+
+func printPrepSchedule(title string, tasks []*internal.PrepTask) {
+	if len(tasks) == 0 {
+		fmt.Println("Nothing to prep: the dishes use no subrecipes.")
+		return
+	}
+
+	if title != "" {
+		fmt.Printf("Prep schedule: %s\n", title)
+	} else {
+		fmt.Println("Prep schedule")
+	}
+
+	today := internal.Today()
+	for start := 0; start < len(tasks); {
+		end := start
+		for end < len(tasks) && tasks[end].Day == tasks[start].Day {
+			end++
+		}
+
+		fmt.Printf("\n%s", tasks[start].Day)
+		if tasks[start].Day < today {
+			fmt.Print("  (already past: start as soon as possible)")
+		}
+		fmt.Println()
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "  STATION\tRECIPE\tMAKE\tBATCHES\tNEEDED\tUSE BY\tFOR")
+		for _, t := range tasks[start:end] {
+			useBy := orDash(t.UseBy())
+			if t.ExceedsShelfLife() {
+				useBy += " (!)"
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%.3f %s\t%s\t%s\t%s\t%s\n",
+				stationString(t.Station), t.Name, t.MakeQty, t.Unit, batchString(t),
+				neededString(t), useBy, strings.Join(t.UsedIn, ", "))
+		}
+		w.Flush()
+
+		start = end
+	}
+
+	for _, t := range tasks {
+		if t.ExceedsShelfLife() {
+			fmt.Println("\n(!) needed after its use-by day: lead time is longer than shelf life")
+			break
+		}
+	}
+}
+
+func writePrepCSV(path string, tasks []*internal.PrepTask) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	_ = w.Write([]string{"Day", "Station", "Recipe", "Required Qty", "Make Qty", "Unit", "Batches", "Batch Size", "Needed", "Last Needed", "Use By", "Used In"})
+	for _, t := range tasks {
+		batches, batchSize := "", ""
+		if t.BatchQty > 0 {
+			batches = strconv.FormatFloat(t.Batches, 'f', 0, 64)
+			batchSize = fmt.Sprintf("%.3f", t.BatchQty)
+		}
+		_ = w.Write([]string{
+			t.Day,
+			t.Station,
+			t.Name,
+			fmt.Sprintf("%.3f", t.Qty),
+			fmt.Sprintf("%.3f", t.MakeQty),
+			t.Unit,
+			batches,
+			batchSize,
+			t.NeededBy,
+			t.LastNeeded,
+			t.UseBy(),
+			strings.Join(t.UsedIn, "; "),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func stationString(station string) string {
+	if station == "" {
+		return "(no station)"
+	}
+	return station
+}
+
+func batchString(t *internal.PrepTask) string {
+	if t.BatchQty <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%g × %g %s", t.Batches, t.BatchQty, t.Unit)
+}
+
+// neededString shows the first day a run is used, and the last when it
+// covers several.
+func neededString(t *internal.PrepTask) string {
+	if t.LastNeeded != t.NeededBy {
+		return t.NeededBy + " – " + t.LastNeeded
+	}
+	return t.NeededBy
+}
+
+// ------------------------------------------------------------
+// recipe set-prep
+//
+// Example:
+//
+//	chefops recipe set-prep --recipe "BULK Citrus Aioli" \
+//	  --lead 1 --batch 5kg --shelf-life 3 --station "Garde Manger"
+//
+// ------------------------------------------------------------
+func recipeSetPrep(args []string) {
+	fs := flag.NewFlagSet("recipe set-prep", flag.ExitOnError)
+	name := fs.String("recipe", "", "recipe name")
+	lead := fs.Int("lead", -1, "days before use it has to be made")
+	batch := fs.String("batch", "", "batch size, e.g. 5kg (default unit: the recipe's yield unit)")
+	shelfLife := fs.Int("shelf-life", -1, "days it keeps once made")
+	station := fs.String("station", "", "station that makes it")
+	clear := fs.Bool("clear", false, "remove all prep settings")
+	fs.Parse(args)
+
+	if *name == "" || (!*clear && *lead < 0 && *batch == "" && *shelfLife < 0 && *station == "") {
+		fmt.Fprintln(os.Stderr, "usage: chefops recipe set-prep --recipe NAME [--lead DAYS] [--batch 5kg] [--shelf-life DAYS] [--station NAME] | --clear")
+		os.Exit(1)
+	}
+
+	db := openDBOrExit()
+	defer db.Close()
+
+	store := sqliteStore(db)
+	recipeID, recipeName := findRecipeOrExit(db, *name)
+
+	recipe, err := store.Recipe(recipeID)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error loading recipe:", err)
+		os.Exit(1)
+	}
+	yieldUnit := recipe.YieldUnit
+
+	if *clear {
+		if err := store.SetPrepSettings(recipeID, internal.PrepSettings{}); err != nil {
+			fmt.Fprintf(os.Stderr, "error clearing prep settings: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Prep settings cleared: %s\n", recipeName)
+		return
+	}
+
+	// Only the given flags are changed.
+	s, err := store.PrepSettings(recipeID)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error loading prep settings:", err)
+		os.Exit(1)
+	}
+	if *lead >= 0 {
+		s.LeadDays = *lead
+	}
+	if *shelfLife >= 0 {
+		s.ShelfLifeDays = *shelfLife
+	}
+	if *station != "" {
+		s.Station = *station
+	}
+	if *batch != "" {
+		qty, unit, err := internal.ParseQtyUnit(*batch)
+		if err == nil && qty <= 0 {
+			err = fmt.Errorf("batch size must be positive: %s", *batch)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		rc, err := internal.CostRecipe(db, recipeID)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if s.BatchQty, err = internal.ConvertYieldQty(rc, qty, unit); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	if err := store.SetPrepSettings(recipeID, s); err != nil {
+		fmt.Fprintf(os.Stderr, "error saving prep settings: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Prep settings saved: %s\n", recipeName)
+	fmt.Printf("  Lead time:  %d day(s)\n", s.LeadDays)
+	if s.BatchQty > 0 {
+		fmt.Printf("  Batch:      %.3f %s\n", s.BatchQty, yieldUnit)
+	} else {
+		fmt.Println("  Batch:      -")
+	}
+	if s.ShelfLifeDays > 0 {
+		fmt.Printf("  Shelf life: %d day(s)\n", s.ShelfLifeDays)
+	} else {
+		fmt.Println("  Shelf life: -")
+	}
+	fmt.Printf("  Station:    %s\n", stationString(s.Station))
+}
@@ -1,12 +1,14 @@
 package main
 
 import (
+	"database/sql"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
-  "strconv"
+	"strconv"
 	"strings"
-	"database/sql"
+
 	"github.com/ChefChristoph/chefops/internal"
 )
 
@@ -25,20 +27,16 @@ func recipeNew(args []string) {
 		os.Exit(1)
 	}
 
-	db, _ := internal.OpenDB()
+	db := openDBOrExit()
 	defer db.Close()
 
-	const q = `
-	INSERT INTO recipes (name, yield_qty, yield_unit, secondary_yield_qty, secondary_yield_unit)
-	VALUES (?, ?, ?, ?, ?)
-	ON CONFLICT(name) DO UPDATE SET
-		yield_qty = excluded.yield_qty,
-		yield_unit = excluded.yield_unit,
-		secondary_yield_qty = excluded.secondary_yield_qty,
-		secondary_yield_unit = excluded.secondary_yield_unit;
-	`
-
-	_, err := db.Exec(q, *name, *yieldQty, *yieldUnit, *secYieldQty, *secYieldUnit)
+	_, err := sqliteStore(db).SaveRecipe(internal.Recipe{
+		Name:               *name,
+		YieldQty:           *yieldQty,
+		YieldUnit:          *yieldUnit,
+		SecondaryYieldQty:  *secYieldQty,
+		SecondaryYieldUnit: *secYieldUnit,
+	})
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error creating recipe: %v\n", err)
 		os.Exit(1)
@@ -50,131 +48,108 @@ func recipeNew(args []string) {
 	}
 	fmt.Println(")")
 }
+
 // recipeList start
 func recipeList(args []string) {
 	fs := flag.NewFlagSet("recipe list", flag.ExitOnError)
+	archived := fs.Bool("archived", false, "list only archived recipes")
+	all := fs.Bool("all", false, "list active and archived recipes")
 	fs.Parse(args)
 
-	db, _ := internal.OpenDB()
+	db := openDBOrExit()
 	defer db.Close()
 
-	rows, err := db.Query(`SELECT id, name, yield_qty, yield_unit FROM recipes ORDER BY name;`)
+	recipes, err := sqliteStore(db).AllRecipes()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error listing recipes: %v\n", err)
 		os.Exit(1)
 	}
-	defer rows.Close()
-
-	// Clean, copy-safe output
-	fmt.Printf("%-4s %-45s %-7s %s\n", "ID", "NAME", "YIELD", "UNIT")
-	for rows.Next() {
-		var id int
-		var name, unit string
-		var qty float64
-		rows.Scan(&id, &name, &qty, &unit)
 
-		// Left-align name but without trailing padding
-		fmt.Printf("%-4d %-45s %-7.2f %s\n", id, name, qty, unit)
+	t := newTable(
+		column{"id", "ID"},
+		column{"name", "NAME"},
+		column{"yield_qty", "YIELD"},
+		column{"yield_unit", "UNIT"},
+	)
+	for _, r := range recipes {
+		if *archived && !r.Archived || !*archived && !*all && r.Archived {
+			continue
+		}
+		t.add(r.ID, r.Name, fixed{r.YieldQty, 2}, r.YieldUnit)
 	}
+	printResult(result{Table: t})
 }
+
 // recipeList end
 func recipeAddItem(args []string) {
 	fs := flag.NewFlagSet("recipe add-item", flag.ExitOnError)
 	recipeName := fs.String("recipe", "", "recipe name")
 	ingredientName := fs.String("ingredient", "", "ingredient name")
 	qty := fs.Float64("qty", 0, "quantity")
+	unit := fs.String("unit", "", "unit of qty (optional; defaults to the ingredient unit)")
+	existing := fs.String("existing", "", "if the recipe already has the ingredient: add or replace (default: ask)")
 	fs.Parse(args)
 
+	if *existing != "" && *existing != "add" && *existing != "replace" {
+		fmt.Fprintln(os.Stderr, "--existing must be add or replace")
+		os.Exit(1)
+	}
+
 	if *recipeName == "" || *ingredientName == "" || *qty <= 0 {
 		fmt.Fprintln(os.Stderr, "recipe, ingredient and positive qty are required")
 		fs.Usage()
 		os.Exit(1)
 	}
 
-	db, _ := internal.OpenDB()
+	if *unit != "" {
+		canonical, err := internal.NormalizeUnit(*unit)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		*unit = canonical
+	}
+
+	db := openDBOrExit()
 	defer db.Close()
 
+	store := sqliteStore(db)
+
 	// ---------------------------
 	// Find recipe
 	// ---------------------------
-	var recipeID int
-	err := db.QueryRow(`SELECT id FROM recipes WHERE name = ?`, *recipeName).Scan(&recipeID)
+	recipe, err := store.RecipeByName(*recipeName)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "recipe not found: %s\n", *recipeName)
 		os.Exit(1)
 	}
-
-	// We track the final ingredient name used for printing:
-	actualIngredientName := *ingredientName
+	recipeID := recipe.ID
 
 	// ---------------------------
 	// Fuzzy search for ingredient
 	// ---------------------------
-	var ingredientID int
-	err = db.QueryRow(`SELECT id FROM ingredients WHERE name = ?`, *ingredientName).Scan(&ingredientID)
-
+	var ingredient internal.Ingredient
+	named, err := store.IngredientsNamed(*ingredientName)
 	if err != nil {
+		fmt.Fprintf(os.Stderr, "error finding ingredient: %v\n", err)
+		os.Exit(1)
+	}
+	for _, ing := range named {
+		if ing.Name == *ingredientName {
+			ingredient = ing
+		}
+	}
+
+	if ingredient.ID == 0 {
 		// Ingredient not found → fuzzy match
 		input := *ingredientName
 		fmt.Fprintf(os.Stderr, "ingredient not found: %s\n\n", input)
 		fmt.Println("🔍 Searching for similar ingredients...")
 
-		type suggestion struct {
-			ID   int
-			Name string
-		}
-		var suggestions []suggestion
-
-		// Pass 1: contains pattern
-		const q1 = `
-			SELECT id, name FROM ingredients
-			WHERE LOWER(name) LIKE LOWER(?)
-			ORDER BY name LIMIT 10;
-		`
-		rows1, _ := db.Query(q1, "%"+input+"%")
-		for rows1.Next() {
-			var id int
-			var name string
-			rows1.Scan(&id, &name)
-			suggestions = append(suggestions, suggestion{ID: id, Name: name})
-		}
-		rows1.Close()
-
-		// Pass 2: prefix match (2 chars)
-		if len(suggestions) == 0 && len(input) >= 2 {
-			const q2 = `
-				SELECT id, name FROM ingredients
-				WHERE SUBSTR(LOWER(name),1,2) = SUBSTR(LOWER(?),1,2)
-				ORDER BY name LIMIT 10;
-			`
-			rows2, _ := db.Query(q2, input)
-			for rows2.Next() {
-				var id int
-				var name string
-				rows2.Scan(&id, &name)
-				suggestions = append(suggestions, suggestion{ID: id, Name: name})
-			}
-			rows2.Close()
-		}
-
-		// Pass 3: first word prefix match (fallback)
-		if len(suggestions) == 0 {
-			parts := strings.Split(strings.ToLower(input), " ")
-			base := parts[0]
-
-			const q3 = `
-				SELECT id, name FROM ingredients
-				WHERE LOWER(name) LIKE ?
-				ORDER BY name LIMIT 10;
-			`
-			rows3, _ := db.Query(q3, base+"%")
-			for rows3.Next() {
-				var id int
-				var name string
-				rows3.Scan(&id, &name)
-				suggestions = append(suggestions, suggestion{ID: id, Name: name})
-			}
-			rows3.Close()
+		suggestions, err := store.SuggestIngredients(input)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error searching ingredients: %v\n", err)
+			os.Exit(1)
 		}
 
 		if len(suggestions) == 0 {
@@ -182,12 +157,33 @@ func recipeAddItem(args []string) {
 			os.Exit(1)
 		}
 
+		// --strict: only the exact name, in any case, will do.
+		if strictNames {
+			var exact []internal.Ingredient
+			for _, s := range suggestions {
+				if strings.EqualFold(s.Name, input) {
+					exact = append(exact, s)
+				}
+			}
+			switch len(exact) {
+			case 0:
+				exitWithAmbiguity("no exact match", "ingredient", input, ingredientNames(suggestions))
+			case 1:
+				suggestions = exact
+			default:
+				exitWithAmbiguity("ambiguous", "ingredient", input, ingredientNames(exact))
+			}
+		}
+
 		// Auto-select if exactly one match
 		if len(suggestions) == 1 {
 			fmt.Printf("Using: %s\n", suggestions[0].Name)
-			ingredientID = suggestions[0].ID
-			actualIngredientName = suggestions[0].Name
+			ingredient = suggestions[0]
 		} else {
+			if nonInteractive {
+				exitWithAmbiguity("ambiguous", "ingredient", input, ingredientNames(suggestions))
+			}
+
 			// Interactive selection
 			fmt.Println("Did you mean:")
 			for i, s := range suggestions {
@@ -210,62 +206,80 @@ func recipeAddItem(args []string) {
 				os.Exit(1)
 			}
 
-			ingredientID = suggestions[choice-1].ID
-			actualIngredientName = suggestions[choice-1].Name
-			fmt.Printf("Using: %s\n", actualIngredientName)
+			ingredient = suggestions[choice-1]
+			fmt.Printf("Using: %s\n", ingredient.Name)
 		}
 	}
+	ingredientID := ingredient.ID
+	actualIngredientName := ingredient.Name
 
 	// ---------------------------
-	// Check for duplicate item
+	// Resolve line unit
 	// ---------------------------
-	var existingQty float64
-	err = db.QueryRow(`
-		SELECT qty FROM recipe_items
-		WHERE recipe_id = ? AND ingredient_id = ?
-	`, recipeID, ingredientID).Scan(&existingQty)
+	baseUnit := ingredient.Unit
+	lineUnit := baseUnit
+	if *unit != "" {
+		lineUnit = *unit
+	}
 
-	if err == nil {
-		// Ingredient exists → choose add / replace
-		fmt.Printf("\nIngredient '%s' already exists in recipe '%s'.\n", actualIngredientName, *recipeName)
-		fmt.Printf("Current amount: %.3f\n\n", existingQty)
+	if _, err := internal.ConvertQty(store, ingredientID, *qty, lineUnit, baseUnit); err != nil {
+		fmt.Fprintf(os.Stderr, "%s is costed per %s: %v\n", actualIngredientName, baseUnit, err)
+		fmt.Fprintf(os.Stderr, "add one with: chefops ingredient convert add --ingredient %q --from 1%s --to ...\n",
+			actualIngredientName, lineUnit)
+		os.Exit(1)
+	}
 
-		fmt.Println("Choose:")
-		fmt.Println("  1) Add to existing amount (+=)")
-		fmt.Println("  2) Replace existing amount (=)")
-		fmt.Println("  3) Cancel")
+	// ---------------------------
+	// Check for duplicate item
+	// ---------------------------
+	line, err := store.IngredientLine(recipeID, ingredientID)
+	if err == nil {
+		existingQty, existingUnit := line.Qty, line.Unit
 
-		fmt.Print("\nEnter choice: ")
+		// Ingredient exists → choose add / replace
+		fmt.Printf("\nIngredient '%s' already exists in recipe '%s'.\n", actualIngredientName, recipe.Name)
+		fmt.Printf("Current amount: %.3f %s\n\n", existingQty, existingUnit)
 
 		var choice string
-		fmt.Scanln(&choice)
+		switch {
+		case *existing == "add":
+			choice = "1"
+		case *existing == "replace":
+			choice = "2"
+		case nonInteractive:
+			exitWithAmbiguity("ambiguous", "existing line", actualIngredientName, []string{"add", "replace"})
+		default:
+			fmt.Println("Choose:")
+			fmt.Println("  1) Add to existing amount (+=)")
+			fmt.Println("  2) Replace existing amount (=)")
+			fmt.Println("  3) Cancel")
+
+			fmt.Print("\nEnter choice: ")
+			fmt.Scanln(&choice)
+		}
 
 		switch choice {
 		case "1":
-			newQty := existingQty + *qty
-			_, err := db.Exec(`
-				UPDATE recipe_items
-				SET qty = ?
-				WHERE recipe_id = ? AND ingredient_id = ?
-			`, newQty, recipeID, ingredientID)
+			// Add in the unit already on the line
+			addQty, err := internal.ConvertQty(store, ingredientID, *qty, lineUnit, existingUnit)
 			if err != nil {
+				fmt.Fprintf(os.Stderr, "cannot add %s to %s: %v\n", lineUnit, existingUnit, err)
+				os.Exit(1)
+			}
+			newQty := existingQty + addQty
+			if err := store.UpdateLine(internal.LineIngredient, line.ID, newQty, existingUnit); err != nil {
 				fmt.Fprintf(os.Stderr, "update error: %v\n", err)
 				os.Exit(1)
 			}
-			fmt.Printf("Updated: %.3f → %.3f (added)\n", existingQty, newQty)
+			fmt.Printf("Updated: %.3f → %.3f %s (added)\n", existingQty, newQty, existingUnit)
 			return
 
 		case "2":
-			_, err := db.Exec(`
-				UPDATE recipe_items
-				SET qty = ?
-				WHERE recipe_id = ? AND ingredient_id = ?
-			`, *qty, recipeID, ingredientID)
-			if err != nil {
+			if err := store.UpdateLine(internal.LineIngredient, line.ID, *qty, lineUnit); err != nil {
 				fmt.Fprintf(os.Stderr, "update error: %v\n", err)
 				os.Exit(1)
 			}
-			fmt.Printf("Updated: %.3f → %.3f (replaced)\n", existingQty, *qty)
+			fmt.Printf("Updated: %.3f %s → %.3f %s (replaced)\n", existingQty, existingUnit, *qty, lineUnit)
 			return
 
 		case "3", "":
@@ -276,223 +290,261 @@ func recipeAddItem(args []string) {
 			fmt.Println("Invalid choice. Cancelled.")
 			return
 		}
+	} else if !errors.Is(err, internal.ErrNotFound) {
+		fmt.Fprintf(os.Stderr, "error reading recipe lines: %v\n", err)
+		os.Exit(1)
 	}
 
 	// ---------------------------
 	// Insert new item (no duplicate)
 	// ---------------------------
-	_, err = db.Exec(`
-		INSERT INTO recipe_items (recipe_id, ingredient_id, qty)
-		VALUES (?, ?, ?)
-	`, recipeID, ingredientID, *qty)
-
-	if err != nil {
+	if _, err := store.AddIngredientLine(recipeID, ingredientID, *qty, lineUnit); err != nil {
 		fmt.Fprintf(os.Stderr, "insert error: %v\n", err)
 		os.Exit(1)
 	}
 
-	fmt.Printf("Added %.3f × %s to %s\n", *qty, actualIngredientName, *recipeName)
+	fmt.Printf("Added %.3f %s × %s to %s\n", *qty, lineUnit, actualIngredientName, recipe.Name)
 }
+
 // func recipeShow start
 func recipeShow(args []string) {
-    if len(args) == 0 {
-        fmt.Println("usage: chefops recipe show NAME")
-        os.Exit(1)
-    }
-
-    raw := strings.Join(args, " ")
-    db, _ := internal.OpenDB()
-    defer db.Close()
-
-    recipeID, recipeName, err := findRecipeByName(db, raw)
-    if err != nil {
-        if err == sql.ErrNoRows {
-            fmt.Println("recipe not found:", raw)
-            os.Exit(1)
-        }
-        fmt.Println("error finding recipe:", err)
-        os.Exit(1)
-    }
-
-    fmt.Printf("\nRecipe: %s\n", recipeName)
-    fmt.Println("-----------------------------------")
-    fmt.Println("| Type       | Name               | Qty     | Unit | Line cost |")
-    fmt.Println("|------------|--------------------|---------|------|-----------|")
-
-    rows, err := db.Query(`
-        SELECT type, name, qty, unit, line_cost
-        FROM recipe_raw_lines
-        WHERE recipe_id = ?
-        ORDER BY type, name;
-    `, recipeID)
-    if err != nil {
-        fmt.Println("error loading recipe:", err)
-        return
-    }
-    defer rows.Close()
-
-    for rows.Next() {
-        var itemType, iname, unit string
-        var qty, lineCost float64
-
-        if err := rows.Scan(&itemType, &iname, &qty, &unit, &lineCost); err != nil {
-            fmt.Println("scan error:", err)
-            return
-        }
-
-        fmt.Printf(
-            "| %-10s | %-18s | %-7.3f | %-4s | %-9.2f |\n",
-            itemType, iname, qty, unit, lineCost,
-        )
-    }
-
-    fmt.Println()
+	if len(args) == 0 {
+		fmt.Println("usage: chefops recipe show NAME")
+		os.Exit(1)
+	}
+
+	raw := strings.Join(args, " ")
+	db := openDBOrExit()
+	defer db.Close()
+
+	recipeID, recipeName, err := findRecipeByName(db, raw)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			fmt.Println("recipe not found:", raw)
+			os.Exit(1)
+		}
+		fmt.Println("error finding recipe:", err)
+		os.Exit(1)
+	}
+
+	rc, err := kitchen(db).Cost(recipeID)
+	if err != nil {
+		fmt.Println("error loading recipe:", err)
+		os.Exit(1)
+	}
+
+	t := costLinesTable(rc, 1)
+	printResult(result{
+		Table: t,
+		Doc: recipeDoc{
+			Recipe:    recipeName,
+			YieldQty:  internal.CleanFloat(rc.YieldQty),
+			YieldUnit: rc.YieldUnit,
+			TotalCost: internal.CleanFloat(rc.TotalCost),
+			Lines:     t.objects(),
+		},
+		Text: func() {
+			fmt.Printf("\nRecipe: %s\n", recipeName)
+			fmt.Println("-----------------------------------")
+			t.print()
+			fmt.Println()
+		},
+	})
 }
+
+// recipeDoc is the JSON form of `recipe show` and `recipe scale`.
+type recipeDoc struct {
+	Recipe     string       `json:"recipe"`
+	YieldQty   float64      `json:"yield_qty"`
+	YieldUnit  string       `json:"yield_unit"`
+	TargetQty  float64      `json:"target_qty,omitempty"`
+	TargetUnit string       `json:"target_unit,omitempty"`
+	Factor     float64      `json:"factor,omitempty"`
+	TotalCost  float64      `json:"total_cost"`
+	Lines      []orderedRow `json:"lines"`
+}
+
+// costLinesTable lists a recipe's lines with quantities and costs
+// multiplied by factor.
+func costLinesTable(rc *internal.RecipeCost, factor float64) *table {
+	t := newTable(
+		column{"type", "TYPE"},
+		column{"name", "NAME"},
+		column{"qty", "QTY"},
+		column{"unit", "UNIT"},
+		column{"line_cost", "LINE COST"},
+	)
+	for _, l := range rc.Lines {
+		t.add(l.Type, l.Name, fixed{l.Qty * factor, 3}, l.Unit, fixed{l.LineCost * factor, 2})
+	}
+	return t
+}
+
 // func recipeShow end
 // func recipeCost start
 func recipeCost(args []string) {
-    if len(args) == 0 {
-        fmt.Println("usage: chefops recipe cost NAME")
-        os.Exit(1)
-    }
-
-    raw := strings.Join(args, " ")
-
-    db, _ := internal.OpenDB()
-    defer db.Close()
-
-    recipeID, recipeName, err := findRecipeByName(db, raw)
-    if err != nil {
-        if err == sql.ErrNoRows {
-            fmt.Println("recipe not found:", raw)
-            os.Exit(1)
-        }
-        fmt.Println("error finding recipe:", err)
-        os.Exit(1)
-    }
-
-    _ = recipeID   // silence unused var; we'll use this later in forecasting
-const q = `
-        SELECT
-            recipe_name,
-            yield_qty,
-            yield_unit,
-            secondary_yield_qty,
-            secondary_yield_unit,
-            total_cost,
-            cost_per_yield_unit,
-            cost_per_secondary_unit
-        FROM recipe_totals
-        WHERE recipe_name = ?
-    `
-
-    var unit, secUnit string
-    var yield, secYield, total, perYield float64
-    var perSecYield sql.NullFloat64
-
-    err = db.QueryRow(q, recipeName).Scan(
-        &recipeName,
-        &yield,
-        &unit,
-        &secYield,
-        &secUnit,
-        &total,
-        &perYield,
-        &perSecYield,
-    )
-    if err != nil {
-        fmt.Fprintf(os.Stderr, "error calculating cost: %v\n", err)
-        os.Exit(1)
-    }
-
-    fmt.Printf("\nCost Breakdown for: %s\n", recipeName)
-    fmt.Println("-----------------------------------")
-    fmt.Printf("Total Cost:          %.2f\n", total)
-
-    fmt.Printf("Yield:               %.2f %s\n", yield, unit)
-    fmt.Printf("Cost per %s:         %.4f\n", unit, perYield)
-
-    if secUnit != "" && secYield > 0 && perSecYield.Valid {
-        fmt.Printf("Secondary Yield:     %.2f %s\n", secYield, secUnit)
-        fmt.Printf("Cost per %s:         %.4f\n", secUnit, perSecYield.Float64)
-    }
-
-    fmt.Println()
+	nameParts, flagArgs := splitNameAndFlags(args)
+
+	fs := flag.NewFlagSet("recipe cost", flag.ExitOnError)
+	asOf := fs.String("as-of", "", "price ingredients as of YYYY-MM-DD")
+	fs.Parse(flagArgs)
+	nameParts = append(nameParts, fs.Args()...)
+
+	if len(nameParts) == 0 {
+		fmt.Println("usage: chefops recipe cost NAME [--as-of YYYY-MM-DD]")
+		os.Exit(1)
+	}
+
+	if *asOf != "" {
+		date, err := internal.ParseDate(*asOf)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		*asOf = date
+	}
+
+	raw := strings.Join(nameParts, " ")
+
+	db := openDBOrExit()
+	defer db.Close()
+
+	recipeID, recipeName, err := findRecipeByName(db, raw)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			fmt.Println("recipe not found:", raw)
+			os.Exit(1)
+		}
+		fmt.Println("error finding recipe:", err)
+		os.Exit(1)
+	}
+
+	rc, err := kitchen(db).AsOf(*asOf).Cost(recipeID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error calculating cost: %v\n", err)
+		os.Exit(1)
+	}
+
+	t := newTable(
+		column{"recipe", "RECIPE"},
+		column{"as_of", "AS OF"},
+		column{"total_cost", "TOTAL COST"},
+		column{"yield_qty", "YIELD"},
+		column{"yield_unit", "UNIT"},
+		column{"cost_per_yield_unit", "COST/UNIT"},
+		column{"secondary_yield_qty", "SECONDARY YIELD"},
+		column{"secondary_yield_unit", "SECONDARY UNIT"},
+		column{"cost_per_secondary_unit", "COST/SECONDARY UNIT"},
+	)
+	// An empty date means today's prices: null in JSON, blank in CSV.
+	var asOfCell any
+	if rc.AsOf != "" {
+		asOfCell = rc.AsOf
+	}
+	perSec, hasSec := rc.CostPerSecondaryUnit()
+	if hasSec {
+		t.add(recipeName, asOfCell, rc.TotalCost, rc.YieldQty, rc.YieldUnit, rc.CostPerYieldUnit(),
+			rc.SecondaryYieldQty, rc.SecondaryYieldUnit, perSec)
+	} else {
+		t.add(recipeName, asOfCell, rc.TotalCost, rc.YieldQty, rc.YieldUnit, rc.CostPerYieldUnit(), nil, nil, nil)
+	}
+
+	printResult(result{Table: t, Doc: t.objects()[0], Text: func() {
+		fmt.Printf("\nCost Breakdown for: %s\n", recipeName)
+		if rc.AsOf != "" {
+			fmt.Printf("Prices as of:        %s\n", rc.AsOf)
+		}
+		fmt.Println("-----------------------------------")
+		fmt.Printf("Total Cost:          %s\n", internal.CurrentConfig().Money(rc.TotalCost))
+
+		fmt.Printf("Yield:               %.2f %s\n", rc.YieldQty, rc.YieldUnit)
+		fmt.Printf("Cost per %s:         %.4f\n", rc.YieldUnit, rc.CostPerYieldUnit())
+
+		if hasSec {
+			fmt.Printf("Secondary Yield:     %.2f %s\n", rc.SecondaryYieldQty, rc.SecondaryYieldUnit)
+			fmt.Printf("Cost per %s:         %.4f\n", rc.SecondaryYieldUnit, perSec)
+		}
+
+		fmt.Println()
+	}})
 }
-// func recipeCost end 
 
+// func recipeCost end
+
+// helper func splitNameAndFlags
+//
+// Splits "NAME WITH SPACES --flag x" into the name tokens and the flag
+// arguments, so a recipe name can be given unquoted before its flags.
+func splitNameAndFlags(args []string) ([]string, []string) {
+	for i, a := range args {
+		if strings.HasPrefix(a, "-") {
+			return args[:i], args[i:]
+		}
+	}
+	return args, nil
+}
 
 // helper func findRecipeByName
+//
+// Exact names win; otherwise a unique fuzzy (contains) match is used, and
+// several matches are offered as a numbered choice. --strict turns fuzzy
+// matching off and --yes turns the choice into an exitAmbiguous error.
 
 func findRecipeByName(db *sql.DB, input string) (int, string, error) {
-    name := strings.TrimSpace(input)
-
-    // 1) exact, case-sensitive
-    var id int
-    err := db.QueryRow(`SELECT id, name FROM recipes WHERE name = ?`, name).Scan(&id, &name)
-    if err == nil {
-        return id, name, nil
-    }
-
-    // 2) exact, case-insensitive
-    err = db.QueryRow(`SELECT id, name FROM recipes WHERE LOWER(name) = LOWER(?)`, name).Scan(&id, &name)
-    if err == nil {
-        return id, name, nil
-    }
-
-    // 3) fuzzy: contains (case-insensitive)
-    rows, err := db.Query(`
-        SELECT id, name
-        FROM recipes
-        WHERE LOWER(name) LIKE '%' || LOWER(?) || '%'
-        ORDER BY name
-        LIMIT 10;
-    `, name)
-    if err != nil {
-        return 0, "", err
-    }
-    defer rows.Close()
-
-    var matches []struct {
-        ID   int
-        Name string
-    }
-
-    for rows.Next() {
-        var mid int
-        var mname string
-        rows.Scan(&mid, &mname)
-        matches = append(matches, struct {
-            ID   int
-            Name string
-        }{mid, mname})
-    }
-
-    if len(matches) == 0 {
-        return 0, "", sql.ErrNoRows
-    }
-    if len(matches) == 1 {
-        return matches[0].ID, matches[0].Name, nil
-    }
-
-    fmt.Println("Multiple recipes match:")
-    for i, m := range matches {
-        fmt.Printf("  %d) %s\n", i+1, m.Name)
-    }
-    fmt.Print("Choose number or press Enter to cancel: ")
-
-    var choiceStr string
-    fmt.Scanln(&choiceStr)
-    if choiceStr == "" {
-        return 0, "", fmt.Errorf("cancelled")
-    }
-
-    choice, convErr := strconv.Atoi(choiceStr)
-    if convErr != nil || choice < 1 || choice > len(matches) {
-        return 0, "", fmt.Errorf("invalid choice")
-    }
-
-    return matches[choice-1].ID, matches[choice-1].Name, nil
+	name := strings.TrimSpace(input)
+
+	store := sqliteStore(db)
+
+	// 1) exact, case-sensitive; 2) exact, case-insensitive
+	matches, err := store.RecipesNamed(name)
+	if err != nil {
+		return 0, "", err
+	}
+	for _, m := range matches {
+		if m.Name == name {
+			return m.ID, m.Name, nil
+		}
+	}
+
+	// 3) fuzzy: contains (case-insensitive)
+	if len(matches) == 0 {
+		matches, err = store.SearchRecipes(name, 10)
+		if err != nil {
+			return 0, "", err
+		}
+		if strictNames && len(matches) > 0 {
+			exitWithAmbiguity("no exact match", "recipe", name, recipeNames(matches))
+		}
+	}
+
+	if len(matches) == 0 {
+		return 0, "", sql.ErrNoRows
+	}
+	if len(matches) == 1 {
+		return matches[0].ID, matches[0].Name, nil
+	}
+	if nonInteractive {
+		exitWithAmbiguity("ambiguous", "recipe", name, recipeNames(matches))
+	}
+
+	fmt.Println("Multiple recipes match:")
+	for i, m := range matches {
+		fmt.Printf("  %d) %s\n", i+1, m.Name)
+	}
+	fmt.Print("Choose number or press Enter to cancel: ")
+
+	var choiceStr string
+	fmt.Scanln(&choiceStr)
+	if choiceStr == "" {
+		return 0, "", fmt.Errorf("cancelled")
+	}
+
+	choice, convErr := strconv.Atoi(choiceStr)
+	if convErr != nil || choice < 1 || choice > len(matches) {
+		return 0, "", fmt.Errorf("invalid choice")
+	}
+
+	return matches[choice-1].ID, matches[choice-1].Name, nil
 }
 
 // ------------------------------------------------------------
@@ -500,100 +552,82 @@ func findRecipeByName(db *sql.DB, input string) (int, string, error) {
 // ------------------------------------------------------------
 
 func recipeScale(args []string) {
-    if len(args) == 0 {
-        fmt.Println("usage: chefops recipe scale NAME --qty X --unit UNIT")
-        os.Exit(1)
-    }
-
-    // 1) Extract recipe name first (all tokens until first --flag)
-    recipeParts := []string{}
-    flagStart := -1
-
-    for i, a := range args {
-        if strings.HasPrefix(a, "--") {
-            flagStart = i
-            break
-        }
-        recipeParts = append(recipeParts, a)
-    }
-
-    // Safety: if no flags detected → error
-    if flagStart == -1 {
-        fmt.Println("qty and unit required")
-        os.Exit(1)
-    }
-
-    recipeNameInput := strings.Join(recipeParts, " ")
-
-    // 2) Parse flags AFTER the recipe name
-    fs := flag.NewFlagSet("recipe scale", flag.ExitOnError)
-    qty := fs.Float64("qty", 0, "target yield quantity")
-    unit := fs.String("unit", "", "target yield unit")
-    fs.Parse(args[flagStart:])
-
-    if *qty <= 0 || *unit == "" {
-        fmt.Println("qty and unit required")
-        os.Exit(1)
-    }
-
-    db, _ := internal.OpenDB()
-    defer db.Close()
-
-    recipeID, recipeName, err := findRecipeByName(db, recipeNameInput)
-    if err != nil {
-        fmt.Println("recipe not found:", recipeNameInput)
-        os.Exit(1)
-    }
-
-    // Fetch base yield
-    var baseQty float64
-    var baseUnit string
-    err = db.QueryRow(`
-        SELECT yield_qty, yield_unit
-        FROM recipes WHERE id = ?
-    `, recipeID).Scan(&baseQty, &baseUnit)
-    if err != nil {
-        fmt.Println("error loading base yield:", err)
-        os.Exit(1)
-    }
-
-    factor := *qty / baseQty
-
-    // UI
-    fmt.Printf("\n📐 Scale Recipe: %s\n", recipeName)
-    fmt.Println("--------------------------------------")
-    fmt.Printf("Original Yield: %.3f %s\n", baseQty, baseUnit)
-    fmt.Printf("Target Yield:   %.3f %s\n", *qty, *unit)
-    fmt.Printf("Scale Factor:   %.3f\n\n", factor)
-
-    // Query expanded lines
-    rows, err := db.Query(`
-        SELECT item_type, ingredient_name, qty, unit
-        FROM recipe_items_expanded_detail
-        WHERE recipe_id = ?
-        ORDER BY item_type, ingredient_name
-    `, recipeID)
-
-    if err != nil {
-        fmt.Println("error loading recipe:", err)
-        os.Exit(1)
-    }
-    defer rows.Close()
-
-    fmt.Printf("| %-10s | %-20s | %-10s | %-6s |\n", "Type", "Name", "New Qty", "Unit")
-    fmt.Println("|------------|----------------------|------------|--------|")
-
-    for rows.Next() {
-        var itemType, name, u string
-        var q float64
-        if err := rows.Scan(&itemType, &name, &q, &u); err != nil {
-            fmt.Println("scan error:", err)
-            os.Exit(1)
-        }
-
-        fmt.Printf("| %-10s | %-20s | %-10.3f | %-6s |\n",
-            itemType, name, q*factor, u)
-    }
-
-    fmt.Println()
+	if len(args) == 0 {
+		fmt.Println("usage: chefops recipe scale NAME --qty X --unit UNIT")
+		os.Exit(1)
+	}
+
+	// 1) Extract recipe name first (all tokens until first --flag)
+	recipeParts := []string{}
+	flagStart := -1
+
+	for i, a := range args {
+		if strings.HasPrefix(a, "--") {
+			flagStart = i
+			break
+		}
+		recipeParts = append(recipeParts, a)
+	}
+
+	// Safety: if no flags detected → error
+	if flagStart == -1 {
+		fmt.Println("qty and unit required")
+		os.Exit(1)
+	}
+
+	recipeNameInput := strings.Join(recipeParts, " ")
+
+	// 2) Parse flags AFTER the recipe name
+	fs := flag.NewFlagSet("recipe scale", flag.ExitOnError)
+	qty := fs.Float64("qty", 0, "target yield quantity")
+	unit := fs.String("unit", "", "target yield unit")
+	fs.Parse(args[flagStart:])
+
+	if *qty <= 0 || *unit == "" {
+		fmt.Println("qty and unit required")
+		os.Exit(1)
+	}
+
+	db := openDBOrExit()
+	defer db.Close()
+
+	recipeID, recipeName, err := findRecipeByName(db, recipeNameInput)
+	if err != nil {
+		fmt.Println("recipe not found:", recipeNameInput)
+		os.Exit(1)
+	}
+
+	sr, err := kitchen(db).Scale(recipeID, *qty, *unit)
+	if err != nil {
+		fmt.Println("error:", err)
+		os.Exit(1)
+	}
+
+	rc := sr.Recipe
+	baseQty, baseUnit := rc.YieldQty, rc.YieldUnit
+	factor := sr.Factor
+
+	t := costLinesTable(rc, factor)
+	printResult(result{
+		Table: t,
+		Doc: recipeDoc{
+			Recipe:     recipeName,
+			YieldQty:   internal.CleanFloat(baseQty),
+			YieldUnit:  baseUnit,
+			TargetQty:  internal.CleanFloat(*qty),
+			TargetUnit: *unit,
+			Factor:     internal.CleanFloat(factor),
+			TotalCost:  internal.CleanFloat(sr.TotalCost),
+			Lines:      t.objects(),
+		},
+		Text: func() {
+			fmt.Printf("\n📐 Scale Recipe: %s\n", recipeName)
+			fmt.Println("--------------------------------------")
+			fmt.Printf("Original Yield: %.3f %s\n", baseQty, baseUnit)
+			fmt.Printf("Target Yield:   %.3f %s\n", *qty, *unit)
+			fmt.Printf("Scale Factor:   %.3f\n\n", factor)
+			t.print()
+			fmt.Println()
+		},
+	})
 }
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ChefChristoph/chefops/internal"
+)
+
+// ------------------------------------------------------------
+// recipe rename / clone / delete / archive
+//
+// Example:
+//
+//	chefops recipe rename "BULK Aioli" --to "BULK Citrus Aioli"
+//	chefops recipe clone "DISH Lobster Roll" --name "DISH Lobster Roll (Brunch)"
+//	chefops recipe clone "BULK Lobster Mac And Cheese" --name "BULK Crab Mac And Cheese" --with-subrecipes --suffix " (crab)"
+//	chefops recipe delete "BULK Old Sauce"
+//	chefops recipe archive "DISH Winter Special"
+//	chefops recipe unarchive "DISH Winter Special"
+//
+// ------------------------------------------------------------
+func recipeRename(args []string) {
+	nameParts, flagArgs := splitNameAndFlags(args)
+
+	fs := flag.NewFlagSet("recipe rename", flag.ExitOnError)
+	to := fs.String("to", "", "new name")
+	fs.Parse(flagArgs)
+	nameParts = append(nameParts, fs.Args()...)
+
+	if len(nameParts) == 0 || strings.TrimSpace(*to) == "" {
+		fmt.Println("usage: chefops recipe rename NAME --to NEW_NAME")
+		os.Exit(1)
+	}
+
+	db := openDBOrExit()
+	defer db.Close()
+
+	recipeID, oldName := findRecipeOrExit(db, strings.Join(nameParts, " "))
+	if err := internal.RenameRecipe(db, recipeID, *to); err != nil {
+		fmt.Fprintf(os.Stderr, "error renaming recipe: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Renamed %s → %s\n", oldName, strings.TrimSpace(*to))
+	fmt.Println("Plan files and scripts that use the old name need updating.")
+}
+
+func recipeClone(args []string) {
+	nameParts, flagArgs := splitNameAndFlags(args)
+
+	fs := flag.NewFlagSet("recipe clone", flag.ExitOnError)
+	newName := fs.String("name", "", "name of the copy")
+	deep := fs.Bool("with-subrecipes", false, "also copy every subrecipe below it")
+	suffix := fs.String("suffix", " (copy)", "appended to the names of copied subrecipes")
+	fs.Parse(flagArgs)
+	nameParts = append(nameParts, fs.Args()...)
+
+	if len(nameParts) == 0 || strings.TrimSpace(*newName) == "" {
+		fmt.Println("usage: chefops recipe clone NAME --name NEW_NAME [--with-subrecipes] [--suffix TEXT]")
+		os.Exit(1)
+	}
+
+	db := openDBOrExit()
+	defer db.Close()
+
+	recipeID, srcName := findRecipeOrExit(db, strings.Join(nameParts, " "))
+	if _, err := internal.CloneRecipe(db, recipeID, *newName, *deep, *suffix); err != nil {
+		fmt.Fprintf(os.Stderr, "error cloning recipe: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Cloned %s → %s\n", srcName, strings.TrimSpace(*newName))
+	if *deep {
+		fmt.Printf("Subrecipes were copied too, named with %q appended.\n", *suffix)
+	}
+}
+
+func recipeDelete(args []string) {
+	if len(args) == 0 {
+		fmt.Println("usage: chefops recipe delete NAME")
+		os.Exit(1)
+	}
+
+	db := openDBOrExit()
+	defer db.Close()
+
+	recipeID, recipeName := findRecipeOrExit(db, strings.Join(args, " "))
+
+	refs, err := internal.RecipeReferences(db, recipeID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error looking up uses: %v\n", err)
+		os.Exit(1)
+	}
+	if len(refs) > 0 {
+		fmt.Fprintf(os.Stderr, "Cannot delete %s: it is still used.\n\n", recipeName)
+		for _, r := range refs {
+			fmt.Fprintf(os.Stderr, "  %s\n", r)
+		}
+		fmt.Fprintf(os.Stderr, "\nRemove it from those first, or keep it for history with:\n  chefops recipe archive %q\n", recipeName)
+		os.Exit(1)
+	}
+
+	if err := internal.DeleteRecipe(db, recipeID); err != nil {
+		fmt.Fprintf(os.Stderr, "error deleting recipe: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Deleted %s\n", recipeName)
+}
+
+func recipeArchive(args []string) {
+	setRecipeArchived(args, true)
+}
+
+func recipeUnarchive(args []string) {
+	setRecipeArchived(args, false)
+}
+
+func setRecipeArchived(args []string, archived bool) {
+	if len(args) == 0 {
+		if archived {
+			fmt.Println("usage: chefops recipe archive NAME")
+		} else {
+			fmt.Println("usage: chefops recipe unarchive NAME")
+		}
+		os.Exit(1)
+	}
+
+	db := openDBOrExit()
+	defer db.Close()
+
+	recipeID, recipeName := findRecipeOrExit(db, strings.Join(args, " "))
+	if err := internal.ArchiveRecipe(db, recipeID, archived); err != nil {
+		fmt.Fprintf(os.Stderr, "error updating recipe: %v\n", err)
+		os.Exit(1)
+	}
+
+	if !archived {
+		fmt.Printf("Restored %s\n", recipeName)
+		return
+	}
+	fmt.Printf("Archived %s\n", recipeName)
+
+	// Archived subrecipes are still costed and expanded inside the recipes
+	// that use them; say so.
+	uses, err := internal.RecipeWhereUsed(db, recipeID)
+	if err == nil && len(uses) > 0 {
+		fmt.Println("It is still used by:")
+		for _, u := range uses {
+			fmt.Printf("  %s\n", u.Name)
+		}
+	}
+}
@@ -4,9 +4,12 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
 	"text/tabwriter"
 
+	"github.com/ChefChristoph/chefops"
 	"github.com/ChefChristoph/chefops/internal"
 )
 
@@ -21,46 +24,25 @@ func recipeRemoveItem(args []string) {
 		os.Exit(1)
 	}
 
-	db, _ := internal.OpenDB()
+	db := openDBOrExit()
 	defer db.Close()
+	store := sqliteStore(db)
 
-	// --- Find recipe ---
-	var recipeID int
-	err := db.QueryRow(`SELECT id FROM recipes WHERE name = ?`, *recipeName).Scan(&recipeID)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "recipe not found: %s\n", *recipeName)
-		os.Exit(1)
-	}
+	recipeID, recipe := findRecipeOrExit(db, *recipeName)
 
 	// --- Find matching items inside recipe ---
-	const q = `
-		SELECT ri.id, i.name, ri.qty, i.unit
-		FROM recipe_items ri
-		JOIN ingredients i ON i.id = ri.ingredient_id
-		WHERE ri.recipe_id = ?
-		  AND LOWER(i.name) LIKE LOWER(?)
-		ORDER BY i.name;
-	`
-	rows, err := db.Query(q, recipeID, "%"+*ingredientName+"%")
+	lines, err := store.Lines(recipeID)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "query error: %v\n", err)
+		fmt.Fprintf(os.Stderr, "error loading recipe lines: %v\n", err)
 		os.Exit(1)
 	}
-	defer rows.Close()
-
-	type item struct {
-		ID    int
-		Name  string
-		Qty   float64
-		Unit  string
-	}
-	var matches []item
-
-	for rows.Next() {
-		var it item
-		rows.Scan(&it.ID, &it.Name, &it.Qty, &it.Unit)
-		matches = append(matches, it)
+	var matches []internal.Line
+	for _, l := range lines {
+		if l.Type == internal.LineIngredient && strings.Contains(strings.ToLower(l.Name), strings.ToLower(*ingredientName)) {
+			matches = append(matches, l)
+		}
 	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Name < matches[j].Name })
 
 	// Nothing found?
 	if len(matches) == 0 {
@@ -68,25 +50,43 @@ func recipeRemoveItem(args []string) {
 		os.Exit(1)
 	}
 
+	// --strict: only the exact ingredient name, in any case
+	if strictNames {
+		var exact []internal.Line
+		var names []string
+		for _, m := range matches {
+			names = append(names, m.Name)
+			if strings.EqualFold(m.Name, *ingredientName) {
+				exact = append(exact, m)
+			}
+		}
+		if len(exact) == 0 {
+			exitWithAmbiguity("no exact match", "recipe line", *ingredientName, names)
+		}
+		matches = exact
+	}
+
 	// One match → confirm removal
 	if len(matches) == 1 {
-		fmt.Printf("Remove %.3f %s of %s from %s? (y/N): ",
-			matches[0].Qty, matches[0].Unit, matches[0].Name, *recipeName)
-
-		var choice string
-		fmt.Scanln(&choice)
-
-		if choice != "y" && choice != "Y" {
+		question := fmt.Sprintf("Remove %.3f %s of %s from %s?",
+			matches[0].Qty, matches[0].Unit, matches[0].Name, recipe)
+		if !confirm(question) {
 			fmt.Println("Cancelled.")
 			os.Exit(0)
 		}
 
-		db.Exec(`DELETE FROM recipe_items WHERE id = ?`, matches[0].ID)
-		fmt.Println("Removed.")
+		removeLine(store, matches[0])
 		return
 	}
 
 	// Multiple matches → interactive picker
+	if nonInteractive {
+		var names []string
+		for _, m := range matches {
+			names = append(names, fmt.Sprintf("%s %.3f %s", m.Name, m.Qty, m.Unit))
+		}
+		exitWithAmbiguity("ambiguous", "recipe line", *ingredientName, names)
+	}
 	fmt.Println("Multiple items match that ingredient name:")
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
 	fmt.Fprintln(w, "INDEX\tINGREDIENT\tQTY\tUNIT")
@@ -112,16 +112,20 @@ func recipeRemoveItem(args []string) {
 
 	// Confirm delete
 	chosen := matches[pick-1]
-	fmt.Printf("Remove %.3f %s of %s from %s? (y/N): ",
-		chosen.Qty, chosen.Unit, chosen.Name, *recipeName)
-
-	var confirm string
-	fmt.Scanln(&confirm)
-	if confirm != "y" && confirm != "Y" {
+	question := fmt.Sprintf("Remove %.3f %s of %s from %s?",
+		chosen.Qty, chosen.Unit, chosen.Name, recipe)
+	if !confirm(question) {
 		fmt.Println("Cancelled.")
 		return
 	}
 
-	db.Exec(`DELETE FROM recipe_items WHERE id = ?`, chosen.ID)
+	removeLine(store, chosen)
+}
+
+func removeLine(store *chefops.SQLiteStore, l internal.Line) {
+	if err := store.DeleteLine(l.Type, l.ID); err != nil {
+		fmt.Fprintf(os.Stderr, "error removing %s: %v\n", l.Name, err)
+		os.Exit(1)
+	}
 	fmt.Println("Removed.")
 }
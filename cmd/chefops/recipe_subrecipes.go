@@ -2,9 +2,11 @@ package main
 
 import (
 	"database/sql"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/ChefChristoph/chefops/internal"
 )
@@ -13,77 +15,58 @@ import (
 // ADD SUBRECIPE
 // ----------------------------------------------------------------------
 func recipeAddSubrecipe(args []string) {
-    fs := flag.NewFlagSet("recipe add-subrecipe", flag.ExitOnError)
-    recipeName := fs.String("recipe", "", "parent recipe")
-    subName := fs.String("sub", "", "subrecipe name")
-    qty := fs.Float64("qty", 0, "quantity of subrecipe used")
-    unit := fs.String("unit", "", "unit (optional; defaults to parent recipe unit)")
-    fs.Parse(args)
-
-    if *recipeName == "" || *subName == "" || *qty <= 0 {
-        fmt.Println("usage: --recipe NAME --sub NAME --qty X [--unit unit]")
-        os.Exit(1)
-    }
-
-    db, _ := internal.OpenDB()
-    defer db.Close()
-
-    // ---------------------------------------------------------
-    // Look up parent recipe and default unit
-    // ---------------------------------------------------------
-    var recipeID int
-    var parentUnit string
-
-    err := db.QueryRow(`
-        SELECT id, yield_unit
-        FROM recipes
-        WHERE name = ?
-    `, *recipeName).Scan(&recipeID, &parentUnit)
-
-    if err != nil {
-        fmt.Println("recipe not found:", *recipeName)
-        os.Exit(1)
-    }
-
-    // ---------------------------------------------------------
-    // Subrecipe lookup
-    // ---------------------------------------------------------
-    var subID int
-    err = db.QueryRow(`SELECT id FROM recipes WHERE name = ?`, *subName).Scan(&subID)
-    if err != nil {
-        fmt.Println("subrecipe not found:", *subName)
-        os.Exit(1)
-    }
-
-    if recipeID == subID {
-        fmt.Println("A recipe cannot reference itself.")
-        os.Exit(1)
-    }
-
-    // ---------------------------------------------------------
-    // Select effective unit (explicit > inherited)
-    // ---------------------------------------------------------
-    effectiveUnit := parentUnit
-    if *unit != "" {
-        effectiveUnit = *unit
-    }
-
-    // ---------------------------------------------------------
-    // Insert
-    // ---------------------------------------------------------
-    _, err = db.Exec(`
-        INSERT INTO recipe_subrecipes (recipe_id, subrecipe_id, qty, unit)
-        VALUES (?, ?, ?, ?)
-    `, recipeID, subID, *qty, effectiveUnit)
-
-    if err != nil {
-        fmt.Println("error adding subrecipe:", err)
-        os.Exit(1)
-    }
-
-    fmt.Printf("Added subrecipe %s (%.3f %s) to %s\n",
-        *subName, *qty, effectiveUnit, *recipeName)
+	fs := flag.NewFlagSet("recipe add-subrecipe", flag.ExitOnError)
+	recipeName := fs.String("recipe", "", "parent recipe")
+	subName := fs.String("sub", "", "subrecipe name")
+	qty := fs.Float64("qty", 0, "quantity of subrecipe used")
+	unit := fs.String("unit", "", "unit (optional; defaults to the subrecipe yield unit)")
+	fs.Parse(args)
+
+	if *recipeName == "" || *subName == "" || *qty <= 0 {
+		fmt.Println("usage: --recipe NAME --sub NAME --qty X [--unit unit]")
+		os.Exit(1)
+	}
+
+	if *unit != "" {
+		canonical, err := internal.NormalizeUnit(*unit)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		*unit = canonical
+	}
+
+	db := openDBOrExit()
+	defer db.Close()
+	store := sqliteStore(db)
+
+	recipeID, parent := findRecipeOrExit(db, *recipeName)
+	subID, sub := findRecipeOrExit(db, *subName)
+
+	// AddSubrecipeLine refuses loops and units the subrecipe's yields do
+	// not convert to.
+	lineID, err := store.AddSubrecipeLine(recipeID, subID, *qty, *unit)
+	if err != nil {
+		if errors.Is(err, internal.ErrSubrecipeCycle) {
+			fmt.Printf("Cannot add %s to %s: it would create a loop.\n", sub, parent)
+		}
+		fmt.Println("error:", err)
+		os.Exit(1)
+	}
+
+	effectiveUnit := *unit
+	if lines, err := store.Lines(recipeID); err == nil {
+		for _, l := range lines {
+			if l.Type == internal.LineSubrecipe && l.ID == lineID {
+				effectiveUnit = l.Unit
+			}
+		}
+	}
+
+	fmt.Printf("Added subrecipe %s (%.3f %s) to %s\n",
+		sub, *qty, effectiveUnit, parent)
 }
+
 // ----------------------------------------------------------------------
 // REMOVE SUBRECIPE
 // ----------------------------------------------------------------------
@@ -98,34 +81,63 @@ func recipeRemoveSubrecipe(args []string) {
 		os.Exit(1)
 	}
 
-	db, _ := internal.OpenDB()
+	db := openDBOrExit()
 	defer db.Close()
+	store := sqliteStore(db)
+
+	recipeID, parent := findRecipeOrExit(db, *recipeName)
+	subID, sub := findRecipeOrExit(db, *subName)
 
-	var recipeID int
-	err := db.QueryRow(`SELECT id FROM recipes WHERE name = ?`, *recipeName).Scan(&recipeID)
+	lines, err := store.Lines(recipeID)
 	if err != nil {
-		fmt.Println("recipe not found:", *recipeName)
+		fmt.Println("error loading recipe lines:", err)
 		os.Exit(1)
 	}
-
-	var subID int
-	err = db.QueryRow(`SELECT id FROM recipes WHERE name = ?`, *subName).Scan(&subID)
-	if err != nil {
-		fmt.Println("subrecipe not found:", *subName)
+	removed := 0
+	for _, l := range lines {
+		if l.Type != internal.LineSubrecipe || l.SubrecipeID != subID {
+			continue
+		}
+		if err := store.DeleteLine(l.Type, l.ID); err != nil {
+			fmt.Println("error removing subrecipe:", err)
+			os.Exit(1)
+		}
+		removed++
+	}
+	if removed == 0 {
+		fmt.Printf("%s does not use %s\n", parent, sub)
 		os.Exit(1)
 	}
 
-	_, err = db.Exec(`
-		DELETE FROM recipe_subrecipes
-		WHERE recipe_id = ? AND subrecipe_id = ?
-	`, recipeID, subID)
+	fmt.Printf("Removed subrecipe %s from %s\n", sub, parent)
+}
+
+// ----------------------------------------------------------------------
+// CHECK FOR SUBRECIPE CYCLES
+// ----------------------------------------------------------------------
+func recipeCheckCycles(args []string) {
+	fs := flag.NewFlagSet("recipe check-cycles", flag.ExitOnError)
+	fs.Parse(args)
+
+	db := openDBOrExit()
+	defer db.Close()
 
+	cycles, err := internal.FindSubrecipeCycles(db)
 	if err != nil {
-		fmt.Println("error removing subrecipe:", err)
+		fmt.Fprintf(os.Stderr, "error checking subrecipes: %v\n", err)
 		os.Exit(1)
 	}
+	if len(cycles) == 0 {
+		fmt.Println("No subrecipe cycles found.")
+		return
+	}
 
-	fmt.Printf("Removed subrecipe %s from %s\n", *subName, *recipeName)
+	fmt.Printf("Found %d subrecipe cycle(s):\n", len(cycles))
+	for _, c := range cycles {
+		fmt.Printf("  %s\n", strings.Join(c, " → "))
+	}
+	fmt.Println("\nBreak each loop with: chefops recipe remove-subrecipe --recipe NAME --sub NAME")
+	os.Exit(1)
 }
 
 // ----------------------------------------------------------------------
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ChefChristoph/chefops/internal"
+)
+
+// ------------------------------------------------------------
+// recipe tree
+//
+// Example:
+//
+//	chefops recipe tree "DISH Full Throttle Lobster, Mac And Cheese Croquette"
+//	chefops recipe tree "BULK Lobster Mac And Cheese" --qty 10 --unit kg
+//
+// ------------------------------------------------------------
+func recipeTree(args []string) {
+	nameParts, flagArgs := splitNameAndFlags(args)
+
+	fs := flag.NewFlagSet("recipe tree", flag.ExitOnError)
+	qty := fs.Float64("qty", 0, "amount to expand (default: one batch at the recipe's yield)")
+	unit := fs.String("unit", "", "unit of --qty (default: the recipe's yield unit)")
+	fs.Parse(flagArgs)
+	nameParts = append(nameParts, fs.Args()...)
+
+	if len(nameParts) == 0 {
+		fmt.Println("usage: chefops recipe tree NAME [--qty QTY] [--unit UNIT]")
+		os.Exit(1)
+	}
+
+	db := openDBOrExit()
+	defer db.Close()
+
+	recipeID, _ := findRecipeOrExit(db, strings.Join(nameParts, " "))
+
+	coster := internal.NewCoster(db)
+	rc, err := coster.Cost(recipeID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error loading recipe: %v\n", err)
+		os.Exit(1)
+	}
+
+	target := rc.YieldQty
+	if *qty > 0 {
+		target, err = internal.ConvertYieldQty(rc, *qty, *unit)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	root, err := coster.Tree(recipeID, target)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error building tree: %v\n", err)
+		os.Exit(1)
+	}
+
+	money := internal.CurrentConfig().Money
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "%s\t%.3f %s\t%s\n", root.Name, root.Qty, root.Unit, money(root.Cost))
+	printTreeChildren(w, root, "", money)
+	w.Flush()
+}
+
+func printTreeChildren(w io.Writer, node *internal.TreeNode, prefix string, money func(float64) string) {
+	for i, c := range node.Children {
+		branch, indent := "├── ", "│   "
+		if i == len(node.Children)-1 {
+			branch, indent = "└── ", "    "
+		}
+		fmt.Fprintf(w, "%s%s%s\t%.3f %s\t%s\n", prefix, branch, c.Name, c.Qty, c.Unit, money(c.Cost))
+		printTreeChildren(w, c, prefix+indent, money)
+	}
+}
+
+// ------------------------------------------------------------
+// where-used
+//
+// Example:
+//
+//	chefops ingredient where-used "Lobster Meat"
+//	chefops recipe where-used "SUB Mac And Cheese Base"
+//
+// ------------------------------------------------------------
+func ingredientWhereUsed(args []string) {
+	if len(args) == 0 {
+		fmt.Println("usage: chefops ingredient where-used NAME")
+		os.Exit(1)
+	}
+
+	db := openDBOrExit()
+	defer db.Close()
+
+	ingID, name := findIngredientOrExit(db, strings.Join(args, " "))
+
+	uses, err := internal.IngredientWhereUsed(db, ingID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error looking up uses: %v\n", err)
+		os.Exit(1)
+	}
+	printWhereUsed(name, uses)
+}
+
+func recipeWhereUsed(args []string) {
+	if len(args) == 0 {
+		fmt.Println("usage: chefops recipe where-used NAME")
+		os.Exit(1)
+	}
+
+	db := openDBOrExit()
+	defer db.Close()
+
+	recipeID, recipeName := findRecipeOrExit(db, strings.Join(args, " "))
+
+	uses, err := internal.RecipeWhereUsed(db, recipeID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error looking up uses: %v\n", err)
+		os.Exit(1)
+	}
+	printWhereUsed(recipeName, uses)
+}
+
+func printWhereUsed(name string, uses []*internal.WhereUsed) {
+	t := newTable(
+		column{"recipe", "RECIPE"},
+		column{"depth", "LEVEL"},
+		column{"qty", "QTY"},
+		column{"unit", "UNIT"},
+		column{"via", "VIA"},
+	)
+	direct := 0
+	for _, u := range uses {
+		if u.Depth == 1 {
+			direct++
+			t.add(u.Name, u.Depth, fixed{u.Qty, 3}, u.Unit, nil)
+		} else {
+			t.add(u.Name, u.Depth, nil, nil, strings.Join(u.Via, " → "))
+		}
+	}
+
+	printResult(result{Table: t, Text: func() {
+		if len(uses) == 0 {
+			fmt.Printf("%s is not used in any recipe.\n", name)
+			return
+		}
+
+		fmt.Printf("Where used: %s\n\n", name)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "RECIPE\tLEVEL\tQTY\tVIA")
+		for _, u := range uses {
+			level, qty := fmt.Sprint(u.Depth), "-"
+			if u.Depth == 1 {
+				level = "direct"
+				qty = strings.TrimSpace(fmt.Sprintf("%.3f %s", u.Qty, u.Unit))
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", u.Name, level, qty, orDash(strings.Join(u.Via, " → ")))
+		}
+		w.Flush()
+
+		fmt.Printf("\n%d recipe(s): %d direct, %d through subrecipes\n", len(uses), direct, len(uses)-direct)
+	}})
+}
+
+// findRecipeOrExit resolves a recipe name with findRecipeByName, exiting
+// when there is no match.
+func findRecipeOrExit(db *sql.DB, name string) (int, string) {
+	id, recipeName, err := findRecipeByName(db, name)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			fmt.Fprintln(os.Stderr, "recipe not found:", name)
+		} else {
+			fmt.Fprintln(os.Stderr, "error finding recipe:", err)
+		}
+		os.Exit(1)
+	}
+	return id, recipeName
+}
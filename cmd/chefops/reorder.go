@@ -0,0 +1,262 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ChefChristoph/chefops/internal"
+)
+
+// ------------------------------------------------------------
+// reorder command
+//
+// Example:
+//
+//	chefops reorder --out reorder.csv \
+//	  "DISH Pole Position Burger=600" \
+//	  "DISH Lobster Roll=500"
+//
+// Without dish specs only par levels are considered.
+// ------------------------------------------------------------
+func reorderCommand(args []string) {
+	fs := flag.NewFlagSet("reorder", flag.ExitOnError)
+	outFile := fs.String("out", "", "also write the suggestions to FILE (.csv or .json)")
+	all := fs.Bool("all", false, "list ingredients that need no order too")
+	fs.Parse(args)
+
+	db := openDBOrExit()
+	defer db.Close()
+
+	dishes := parseDishSpecs(db, fs.Args())
+
+	coster := internal.NewCoster(db)
+	usage := make(map[int]*internal.IngredientUsage)
+	for _, d := range dishes {
+		if err := coster.ExpandIngredients(d.RecipeID, d.Portions, usage); err != nil {
+			fmt.Fprintf(os.Stderr, "error expanding ingredients for %s: %v\n", d.Name, err)
+			os.Exit(1)
+		}
+	}
+
+	lines, err := internal.ReorderSuggestions(db, usage)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error building reorder suggestions: %v\n", err)
+		os.Exit(1)
+	}
+	if !*all {
+		var toOrder []*internal.ReorderLine
+		for _, l := range lines {
+			if l.Reorder() {
+				toOrder = append(toOrder, l)
+			}
+		}
+		lines = toOrder
+	}
+
+	printReorderReport(lines)
+
+	if *outFile != "" {
+		path := internal.CurrentConfig().ExportPath(*outFile)
+		if err := writeReorderFile(path, lines); err != nil {
+			fmt.Fprintf(os.Stderr, "error writing %s: %v\n", path, err)
+			os.Exit(1)
+		}
+		fmt.Printf("\nReorder list exported to %s\n", path)
+	}
+}
+
+func printReorderReport(lines []*internal.ReorderLine) {
+	if len(lines) == 0 {
+		fmt.Println("Nothing to reorder.")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "INGREDIENT\tUNIT\tON HAND\tFORECAST\tPROJECTED\tMIN\tPAR\tSUGGEST\tPACK\tPACKS\tORDER QTY\tORDER COST")
+	var total float64
+	for _, l := range lines {
+		pack, packs, orderQty, _, _ := purchaseColumns(l.Purchase)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%.2f\n",
+			l.Name, l.Unit, qty3(l.OnHand), qty3(l.Forecast), qty3(l.Projected), qty3(l.MinLevel), qty3(l.ParLevel),
+			qty3(l.SuggestQty), pack, packs, orderQty, l.OrderCost())
+		total += l.OrderCost()
+	}
+	w.Flush()
+
+	fmt.Printf("\nEstimated order value: %s\n", internal.CurrentConfig().Money(total))
+}
+
+// qty3 formats a quantity with three decimals, showing values that round
+// to zero as 0.000 rather than -0.000.
+func qty3(v float64) string {
+	if math.Abs(v) < 0.0005 {
+		v = 0
+	}
+	return fmt.Sprintf("%.3f", v)
+}
+
+type reorderRecord struct {
+	Ingredient  string  `json:"ingredient"`
+	Unit        string  `json:"unit"`
+	OnHand      float64 `json:"on_hand"`
+	Forecast    float64 `json:"forecast"`
+	Projected   float64 `json:"projected"`
+	MinLevel    float64 `json:"min_level"`
+	ParLevel    float64 `json:"par_level"`
+	Suggest     float64 `json:"suggested_qty"`
+	Pack        string  `json:"pack,omitempty"`
+	Packs       float64 `json:"packs,omitempty"`
+	OrderQty    float64 `json:"order_qty"`
+	CostPerUnit float64 `json:"cost_per_unit"`
+	OrderCost   float64 `json:"order_cost"`
+}
+
+func writeReorderFile(path string, lines []*internal.ReorderLine) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	records := make([]reorderRecord, 0, len(lines))
+	for _, l := range lines {
+		r := reorderRecord{
+			Ingredient:  l.Name,
+			Unit:        l.Unit,
+			OnHand:      l.OnHand,
+			Forecast:    l.Forecast,
+			Projected:   l.Projected,
+			MinLevel:    l.MinLevel,
+			ParLevel:    l.ParLevel,
+			Suggest:     l.SuggestQty,
+			OrderQty:    l.Purchase.OrderQty,
+			CostPerUnit: l.CostPerUnit,
+			OrderCost:   l.OrderCost(),
+		}
+		if l.Purchase.HasPack {
+			r.Pack = fmt.Sprintf("%g %s", l.Purchase.Pack.Qty, l.Purchase.Pack.Unit)
+			r.Packs = l.Purchase.Packs
+		}
+		records = append(records, r)
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		data, err := json.MarshalIndent(records, "", "  ")
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(path, data, 0644)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	_ = w.Write([]string{"Ingredient", "Unit", "On Hand", "Forecast", "Projected", "Min", "Par", "Suggested Qty", "Pack", "Packs", "Order Qty", "Unit Cost", "Order Cost"})
+	for _, r := range records {
+		packs := ""
+		if r.Pack != "" {
+			packs = fmt.Sprintf("%g", r.Packs)
+		}
+		_ = w.Write([]string{
+			r.Ingredient,
+			r.Unit,
+			qty3(r.OnHand),
+			qty3(r.Forecast),
+			qty3(r.Projected),
+			qty3(r.MinLevel),
+			qty3(r.ParLevel),
+			qty3(r.Suggest),
+			r.Pack,
+			packs,
+			qty3(r.OrderQty),
+			fmt.Sprintf("%.2f", r.CostPerUnit),
+			fmt.Sprintf("%.2f", r.OrderCost),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func ingredientSetPar(args []string) {
+	fs := flag.NewFlagSet("ingredient set-par", flag.ExitOnError)
+	name := fs.String("ingredient", "", "ingredient name")
+	parQty := fs.Float64("par", -1, "stock to order back up to")
+	minQty := fs.Float64("min", -1, "reorder below this level (default: par)")
+	unit := fs.String("unit", "", "unit of --par and --min (default: the ingredient's unit)")
+	clear := fs.Bool("clear", false, "remove min and par levels")
+	fs.Parse(args)
+
+	if *name == "" || (*parQty < 0 && !*clear) {
+		fmt.Fprintln(os.Stderr, "usage: chefops ingredient set-par --ingredient NAME --par QTY [--min QTY] [--unit UNIT] | --clear")
+		os.Exit(1)
+	}
+	if *minQty > *parQty && !*clear {
+		fmt.Fprintln(os.Stderr, "--min cannot be above --par")
+		os.Exit(1)
+	}
+
+	db := openDBOrExit()
+	defer db.Close()
+
+	var ingID int
+	var baseUnit string
+	if err := db.QueryRow(`SELECT id, unit FROM ingredients WHERE name = ?`, *name).Scan(&ingID, &baseUnit); err != nil {
+		fmt.Fprintln(os.Stderr, "ingredient not found:", *name)
+		os.Exit(1)
+	}
+
+	if *clear {
+		if _, err := db.Exec(`UPDATE ingredients SET min_level = NULL, par_level = NULL WHERE id = ?`, ingID); err != nil {
+			fmt.Fprintf(os.Stderr, "error clearing par level: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Par level cleared: %s\n", *name)
+		return
+	}
+
+	if *unit == "" {
+		*unit = baseUnit
+	} else {
+		canonical, err := internal.NormalizeUnit(*unit)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		*unit = canonical
+	}
+
+	parBase, err := internal.ConvertIngredientQty(db, ingID, *parQty, *unit, baseUnit)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", *name, err)
+		os.Exit(1)
+	}
+	var minBase any // NULL: reorder below par
+	if *minQty >= 0 {
+		v, err := internal.ConvertIngredientQty(db, ingID, *minQty, *unit, baseUnit)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", *name, err)
+			os.Exit(1)
+		}
+		minBase = v
+	}
+
+	if _, err := db.Exec(`UPDATE ingredients SET min_level = ?, par_level = ? WHERE id = ?`, minBase, parBase, ingID); err != nil {
+		fmt.Fprintf(os.Stderr, "error saving par level: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Par level saved: %s par %.3f %s", *name, parBase, baseUnit)
+	if v, ok := minBase.(float64); ok {
+		fmt.Printf(", min %.3f %s", v, baseUnit)
+	}
+	fmt.Println()
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/ChefChristoph/chefops/internal"
+	"github.com/ChefChristoph/chefops/internal/api"
+)
+
+// ------------------------------------------------------------
+// serve
+//
+// Example:
+//
+//	chefops serve
+//	chefops serve --addr :8080 --token "$CHEFOPS_API_TOKEN"
+//
+// ------------------------------------------------------------
+func serveCommand(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", "127.0.0.1:8080", "address to listen on (:8080 for every interface)")
+	token := fs.String("token", os.Getenv("CHEFOPS_API_TOKEN"), "require \"Authorization: Bearer TOKEN\" (default $CHEFOPS_API_TOKEN)")
+	fs.Parse(args)
+
+	db := openDBOrExit()
+	defer db.Close()
+
+	fmt.Printf("ChefOps API on http://%s/api (database %s)\n", *addr, internal.CurrentConfig().DBPath)
+	if *token == "" {
+		fmt.Println("No --token set: anyone who can reach this address can change the database.")
+	}
+	if err := http.ListenAndServe(*addr, api.NewServer(db, *token)); err != nil {
+		fmt.Fprintf(os.Stderr, "error serving: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ChefChristoph/chefops/internal"
+)
+
+func stockCommand(args []string) {
+	if len(args) < 1 {
+		fmt.Println("usage: chefops stock <count|import|list> ...")
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "count":
+		stockCount(args[1:])
+	case "import":
+		stockImport(args[1:])
+	case "list":
+		stockList(args[1:])
+	default:
+		fmt.Println("unknown stock subcommand:", args[0])
+		os.Exit(1)
+	}
+}
+
+func stockCount(args []string) {
+	fs := flag.NewFlagSet("stock count", flag.ExitOnError)
+	name := fs.String("ingredient", "", "ingredient name")
+	qty := fs.Float64("qty", -1, "counted quantity")
+	unit := fs.String("unit", "", "unit of the count (default: the ingredient's unit)")
+	location := fs.String("location", internal.DefaultLocation, "storage location, e.g. walk-in, dry-store")
+	at := fs.String("at", "", "time of the count, YYYY-MM-DD [HH:MM] (default now)")
+	note := fs.String("note", "", "optional note")
+	fs.Parse(args)
+
+	if *name == "" || *qty < 0 {
+		fmt.Fprintln(os.Stderr, "usage: chefops stock count --ingredient NAME --qty QTY [--unit UNIT] [--location NAME] [--at \"YYYY-MM-DD HH:MM\"]")
+		os.Exit(1)
+	}
+
+	countedAt, err := internal.ParseTimestamp(*at)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if *unit != "" {
+		canonical, err := internal.NormalizeUnit(*unit)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		*unit = canonical
+	}
+
+	db := openDBOrExit()
+	defer db.Close()
+
+	var ingID int
+	var baseUnit string
+	if err := db.QueryRow(`SELECT id, unit FROM ingredients WHERE name = ?`, *name).Scan(&ingID, &baseUnit); err != nil {
+		fmt.Fprintln(os.Stderr, "ingredient not found:", *name)
+		os.Exit(1)
+	}
+
+	baseQty, err := internal.RecordStockCount(db, ingID, *qty, *unit, *location, countedAt, *note)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Stock counted: %s %.3f %s at %s (%s)\n", *name, baseQty, baseUnit, *location, countedAt)
+}
+
+// stockImport reads counts from a CSV file with a header row. Required
+// columns: ingredient, qty. Optional: unit, location, counted_at, note.
+func stockImport(args []string) {
+	fs := flag.NewFlagSet("stock import", flag.ExitOnError)
+	location := fs.String("location", internal.DefaultLocation, "location for rows without one")
+	at := fs.String("at", "", "count time for rows without counted_at (default now)")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: chefops stock import FILE.csv [--location NAME] [--at \"YYYY-MM-DD HH:MM\"]")
+		os.Exit(1)
+	}
+	path := fs.Arg(0)
+
+	defaultAt, err := internal.ParseTimestamp(*at)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cannot open %s: %v\n", path, err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "reading %s: %v\n", path, err)
+		os.Exit(1)
+	}
+	col := make(map[string]int)
+	for i, h := range header {
+		col[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	if _, ok := col["ingredient"]; !ok {
+		fmt.Fprintln(os.Stderr, "CSV needs an 'ingredient' column")
+		os.Exit(1)
+	}
+	if _, ok := col["qty"]; !ok {
+		fmt.Fprintln(os.Stderr, "CSV needs a 'qty' column")
+		os.Exit(1)
+	}
+	field := func(rec []string, name string) string {
+		i, ok := col[name]
+		if !ok || i >= len(rec) {
+			return ""
+		}
+		return strings.TrimSpace(rec[i])
+	}
+
+	db := openDBOrExit()
+	defer db.Close()
+
+	imported, failed := 0, 0
+	line := 1
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "line %d: %v\n", line, err)
+			failed++
+			continue
+		}
+
+		name := field(rec, "ingredient")
+		if name == "" {
+			continue
+		}
+		qty, err := strconv.ParseFloat(field(rec, "qty"), 64)
+		if err != nil || qty < 0 {
+			fmt.Fprintf(os.Stderr, "line %d: %s: invalid qty %q\n", line, name, field(rec, "qty"))
+			failed++
+			continue
+		}
+
+		unit := field(rec, "unit")
+		if unit != "" {
+			if unit, err = internal.NormalizeUnit(unit); err != nil {
+				fmt.Fprintf(os.Stderr, "line %d: %s: %v\n", line, name, err)
+				failed++
+				continue
+			}
+		}
+		loc := field(rec, "location")
+		if loc == "" {
+			loc = *location
+		}
+		countedAt := defaultAt
+		if v := field(rec, "counted_at"); v != "" {
+			if countedAt, err = internal.ParseTimestamp(v); err != nil {
+				fmt.Fprintf(os.Stderr, "line %d: %s: %v\n", line, name, err)
+				failed++
+				continue
+			}
+		}
+
+		var ingID int
+		if err := db.QueryRow(`SELECT id FROM ingredients WHERE name = ?`, name).Scan(&ingID); err != nil {
+			fmt.Fprintf(os.Stderr, "line %d: ingredient not found: %s\n", line, name)
+			failed++
+			continue
+		}
+
+		if _, err := internal.RecordStockCount(db, ingID, qty, unit, loc, countedAt, field(rec, "note")); err != nil {
+			fmt.Fprintf(os.Stderr, "line %d: %v\n", line, err)
+			failed++
+			continue
+		}
+		imported++
+	}
+
+	fmt.Printf("Imported %d stock count(s) from %s", imported, path)
+	if failed > 0 {
+		fmt.Printf(", %d failed", failed)
+	}
+	fmt.Println()
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func stockList(args []string) {
+	fs := flag.NewFlagSet("stock list", flag.ExitOnError)
+	location := fs.String("location", "", "only this location")
+	fs.Parse(args)
+
+	db := openDBOrExit()
+	defer db.Close()
+
+	levels, err := internal.StockLevels(db, *location)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error loading stock: %v\n", err)
+		os.Exit(1)
+	}
+
+	t := newTable(
+		column{"ingredient", "INGREDIENT"},
+		column{"location", "LOCATION"},
+		column{"qty", "ON HAND"},
+		column{"unit", "UNIT"},
+		column{"counted_at", "COUNTED AT"},
+	)
+	for _, s := range levels {
+		t.add(s.Name, s.Location, fixed{s.Qty, 3}, s.Unit, s.CountedAt)
+	}
+	printResult(result{Table: t})
+}
+
+// netOfStockOrExit subtracts on-hand stock from a market list.
+func netOfStockOrExit(db *sql.DB, items []*internal.IngredientUsage) []*internal.IngredientUsage {
+	onHand, err := internal.OnHand(db)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error loading stock on hand: %v\n", err)
+		os.Exit(1)
+	}
+	return internal.NetOfStock(items, onHand)
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/ChefChristoph/chefops/internal"
+)
+
+func supplierCommand(args []string) {
+	if len(args) < 1 {
+		fmt.Println("usage: chefops supplier <add|list|show|link> ...")
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "add":
+		supplierAdd(args[1:])
+	case "list":
+		supplierList(args[1:])
+	case "show":
+		supplierShow(args[1:])
+	case "link":
+		supplierLink(args[1:])
+	default:
+		fmt.Println("unknown supplier subcommand:", args[0])
+		os.Exit(1)
+	}
+}
+
+func supplierAdd(args []string) {
+	fs := flag.NewFlagSet("supplier add", flag.ExitOnError)
+	name := fs.String("name", "", "supplier name")
+	contact := fs.String("contact", "", "contact person, phone or email")
+	leadTime := fs.Int("lead-time", 0, "lead time in days")
+	orderDays := fs.String("order-days", "", "days orders are accepted, e.g. mon,thu")
+	minOrder := fs.Float64("min-order", 0, "minimum order value")
+	notes := fs.String("notes", "", "free-form notes")
+	fs.Parse(args)
+
+	if *name == "" {
+		fmt.Fprintln(os.Stderr, "usage: chefops supplier add --name NAME [--contact TEXT] [--lead-time DAYS] [--order-days mon,thu] [--min-order VALUE]")
+		os.Exit(1)
+	}
+
+	db := openDBOrExit()
+	defer db.Close()
+
+	_, err := db.Exec(`
+		INSERT INTO suppliers (name, contact, lead_time_days, order_days, min_order, notes)
+		VALUES (?, NULLIF(?, ''), NULLIF(?, 0), NULLIF(?, ''), NULLIF(?, 0), NULLIF(?, ''))
+		ON CONFLICT(name) DO UPDATE SET
+		    contact = excluded.contact,
+		    lead_time_days = excluded.lead_time_days,
+		    order_days = excluded.order_days,
+		    min_order = excluded.min_order,
+		    notes = excluded.notes
+	`, *name, *contact, *leadTime, *orderDays, *minOrder, *notes)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error saving supplier: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Supplier saved: %s\n", *name)
+}
+
+func supplierList(args []string) {
+	db := openDBOrExit()
+	defer db.Close()
+
+	suppliers, err := internal.ListSuppliers(db)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error loading suppliers: %v\n", err)
+		os.Exit(1)
+	}
+
+	t := newTable(
+		column{"name", "NAME"},
+		column{"contact", "CONTACT"},
+		column{"lead_time_days", "LEAD TIME"},
+		column{"order_days", "ORDER DAYS"},
+		column{"min_order", "MIN ORDER"},
+	)
+	for _, s := range suppliers {
+		t.add(s.Name, s.Contact, s.LeadTimeDays, s.OrderDays, s.MinOrder)
+	}
+
+	printResult(result{Table: t, Text: func() {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tCONTACT\tLEAD TIME\tORDER DAYS\tMIN ORDER")
+		for _, s := range suppliers {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+				s.Name, orDash(s.Contact), leadTimeString(s.LeadTimeDays), orDash(s.OrderDays), minOrderString(s.MinOrder))
+		}
+		w.Flush()
+	}})
+}
+
+func supplierShow(args []string) {
+	if len(args) < 1 {
+		fmt.Println("usage: chefops supplier show \"Supplier\"")
+		os.Exit(1)
+	}
+
+	db := openDBOrExit()
+	defer db.Close()
+
+	s, err := internal.GetSupplierByName(db, args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("\nSupplier:    %s\n", s.Name)
+	fmt.Printf("Contact:     %s\n", orDash(s.Contact))
+	fmt.Printf("Lead time:   %s\n", leadTimeString(s.LeadTimeDays))
+	fmt.Printf("Order days:  %s\n", orDash(s.OrderDays))
+	fmt.Printf("Min order:   %s\n", minOrderString(s.MinOrder))
+	if s.Notes != "" {
+		fmt.Printf("Notes:       %s\n", s.Notes)
+	}
+
+	links, err := internal.SupplierLinks(db, s.ID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error loading ingredients: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "INGREDIENT\tSKU\tPACK\tPREFERRED")
+	for _, l := range links {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", l.IngredientName, orDash(l.SKU), packString(l.PackQty, l.PackUnit), yesNo(l.Preferred))
+	}
+	w.Flush()
+}
+
+func supplierLink(args []string) {
+	fs := flag.NewFlagSet("supplier link", flag.ExitOnError)
+	ingredientName := fs.String("ingredient", "", "ingredient name")
+	supplierName := fs.String("supplier", "", "supplier name")
+	sku := fs.String("sku", "", "supplier's article number")
+	pack := fs.String("pack", "", "pack size, e.g. 25kg or 12piece")
+	preferred := fs.Bool("preferred", false, "order this ingredient from this supplier")
+	fs.Parse(args)
+
+	if *ingredientName == "" || *supplierName == "" {
+		fmt.Fprintln(os.Stderr, "usage: chefops supplier link --ingredient NAME --supplier NAME [--sku SKU] [--pack 25kg] [--preferred]")
+		os.Exit(1)
+	}
+
+	db := openDBOrExit()
+	defer db.Close()
+
+	var ingID int
+	var ingUnit string
+	if err := db.QueryRow(`SELECT id, unit FROM ingredients WHERE name = ?`, *ingredientName).Scan(&ingID, &ingUnit); err != nil {
+		fmt.Fprintln(os.Stderr, "ingredient not found:", *ingredientName)
+		os.Exit(1)
+	}
+
+	s, err := internal.GetSupplierByName(db, *supplierName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	var packQty float64
+	var packUnit string
+	if *pack != "" {
+		packQty, packUnit, err = internal.ParseQtyUnit(*pack)
+		if err == nil && packQty <= 0 {
+			err = fmt.Errorf("pack size must be positive: %s", *pack)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if packUnit == "" {
+			packUnit = ingUnit
+		}
+		if _, err := internal.ConvertIngredientQty(db, ingID, packQty, packUnit, ingUnit); err != nil {
+			if errors.Is(err, internal.ErrNoConversion) {
+				fmt.Fprintf(os.Stderr, "%s is measured in %s; add a conversion first:\n  chefops ingredient convert add --ingredient %q --from 1%s --to ?%s\n",
+					*ingredientName, ingUnit, *ingredientName, packUnit, ingUnit)
+			} else {
+				fmt.Fprintln(os.Stderr, err)
+			}
+			os.Exit(1)
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error saving link: %v\n", err)
+		os.Exit(1)
+	}
+	defer tx.Rollback()
+
+	if *preferred {
+		if _, err := tx.Exec(`UPDATE ingredient_suppliers SET preferred = 0 WHERE ingredient_id = ?`, ingID); err != nil {
+			fmt.Fprintf(os.Stderr, "error saving link: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO ingredient_suppliers (ingredient_id, supplier_id, sku, pack_qty, pack_unit, preferred)
+		VALUES (?, ?, NULLIF(?, ''), NULLIF(?, 0), NULLIF(?, ''), ?)
+		ON CONFLICT(ingredient_id, supplier_id) DO UPDATE SET
+		    sku = COALESCE(excluded.sku, sku),
+		    pack_qty = COALESCE(excluded.pack_qty, pack_qty),
+		    pack_unit = COALESCE(excluded.pack_unit, pack_unit),
+		    preferred = MAX(excluded.preferred, preferred)
+	`, ingID, s.ID, *sku, packQty, packUnit, *preferred)
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error saving link: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Linked %s → %s", *ingredientName, s.Name)
+	if packQty > 0 {
+		fmt.Printf(" (pack %s)", packString(packQty, packUnit))
+	}
+	fmt.Println()
+}
+
+func leadTimeString(days int) string {
+	if days <= 0 {
+		return "-"
+	}
+	if days == 1 {
+		return "1 day"
+	}
+	return fmt.Sprintf("%d days", days)
+}
+
+func minOrderString(v float64) string {
+	if v <= 0 {
+		return "-"
+	}
+	return internal.CurrentConfig().Money(v)
+}
+
+func packString(qty float64, unit string) string {
+	if qty <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%g %s", qty, unit)
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ChefChristoph/chefops/internal"
+)
+
+// unitsList prints the built-in unit library.
+func unitsList(args []string) {
+	fs := flag.NewFlagSet("units", flag.ExitOnError)
+	fs.Parse(args)
+
+	base := map[internal.Dimension]string{
+		internal.DimensionMass:    "g",
+		internal.DimensionVolume:  "ml",
+		internal.DimensionCount:   "piece",
+		internal.DimensionPortion: "portion",
+	}
+
+	t := newTable(
+		column{"unit", "UNIT"},
+		column{"dimension", "DIMENSION"},
+		column{"factor", "FACTOR"},
+		column{"base_unit", "BASE"},
+		column{"aliases", "ALIASES"},
+	)
+	for _, u := range internal.StandardUnits() {
+		t.add(u.Name, string(u.Dimension), u.Factor, base[u.Dimension], strings.Join(u.Aliases, ", "))
+	}
+
+	printResult(result{Table: t, Text: func() {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "UNIT\tDIMENSION\tEQUALS\tALIASES")
+		for _, u := range internal.StandardUnits() {
+			fmt.Fprintf(w, "%s\t%s\t%g %s\t%s\n",
+				u.Name, u.Dimension, u.Factor, base[u.Dimension], strings.Join(u.Aliases, ", "))
+		}
+		w.Flush()
+
+		fmt.Println()
+		fmt.Println("Units convert freely within a dimension. Across dimensions (e.g. piece → kg,")
+		fmt.Println("liter → kg) add a per-ingredient conversion with `chefops ingredient convert add`.")
+	}})
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/ChefChristoph/chefops/internal"
+	"github.com/ChefChristoph/chefops/internal/web"
+)
+
+// ------------------------------------------------------------
+// web
+//
+// Example:
+//
+//	chefops web
+//	chefops web --addr :8080
+//
+// ------------------------------------------------------------
+func webCommand(args []string) {
+	fs := flag.NewFlagSet("web", flag.ExitOnError)
+	addr := fs.String("addr", "127.0.0.1:8080", "address to listen on (:8080 for every interface)")
+	fs.Parse(args)
+
+	db := openDBOrExit()
+	defer db.Close()
+
+	fmt.Printf("ChefOps dashboard on http://%s/ (database %s)\n", *addr, internal.CurrentConfig().DBPath)
+	if err := http.ListenAndServe(*addr, web.Handler(db)); err != nil {
+		fmt.Fprintf(os.Stderr, "error serving: %v\n", err)
+		os.Exit(1)
+	}
+}
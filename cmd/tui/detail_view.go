@@ -9,13 +9,16 @@ import (
 
 func detailView(m Model) string {
 	if m.activeRecipe == nil {
+		if m.detailError != "" {
+			return "Error: " + m.detailError
+		}
 		return "Error: no recipe loaded"
 	}
 
 	r := m.activeRecipe
 
 	// Load notes for this recipe
-	notes, err := tui.LoadRecipeNotes(m.db, r.ID)
+	notes, err := tui.LoadRecipeNotes(m.store, r.ID)
 	if err != nil {
 		notes = "Error loading notes"
 	}
@@ -1,6 +1,11 @@
 package main
 
-import "strings"
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ChefChristoph/chefops/internal/tui"
+)
 
 func listView(m Model) string {
 	var b strings.Builder
@@ -9,13 +14,23 @@ func listView(m Model) string {
 	b.WriteString("──────────────────────────\n\n")
 
 	for i, recipe := range m.recipes {
+		row := fmt.Sprintf("%-48s %10s", recipe.Name, costCell(recipe))
 		if i == m.cursor {
-			b.WriteString(activeItemStyle.Render("> " + recipe.Name))
+			b.WriteString(activeItemStyle.Render("> " + row))
 		} else {
-			b.WriteString(listStyle.Render("  " + recipe.Name))
+			b.WriteString(listStyle.Render("  " + row))
 		}
 		b.WriteString("\n")
 	}
 
 	return b.String()
 }
+
+// costCell is the cost column of the recipe list; a recipe that cannot be
+// costed is marked, and opening it shows the error.
+func costCell(r tui.RecipeSummary) string {
+	if r.CostErr != nil {
+		return "cost error"
+	}
+	return fmt.Sprintf("%.2f", r.TotalCost)
+}
@@ -1,23 +1,33 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 
 	tea "github.com/charmbracelet/bubbletea"
 
+	"github.com/ChefChristoph/chefops"
 	"github.com/ChefChristoph/chefops/internal"
 )
 
 func main() {
-	db, err := internal.OpenDB()
+	dbPath := flag.String("db", "", "database path (overrides CHEFOPS_DB and the config file)")
+	flag.Parse()
+
+	if _, err := internal.LoadConfig(*dbPath); err != nil {
+		fmt.Println("Failed to load config:", err)
+		os.Exit(1)
+	}
+
+	store, err := chefops.Open(internal.CurrentConfig().DBPath)
 	if err != nil {
 		fmt.Println("Failed to open DB:", err)
 		os.Exit(1)
 	}
-	defer db.Close()
+	defer store.Close()
 
-	m, err := NewModel(db)
+	m, err := NewModel(store)
 	if err != nil {
 		fmt.Println("Failed to load recipes:", err)
 		os.Exit(1)
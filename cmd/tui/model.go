@@ -1,11 +1,10 @@
 package main
 
 import (
-	"database/sql"
-	"fmt"
 	"path/filepath"
 	"strings"
 
+	"github.com/ChefChristoph/chefops"
 	"github.com/ChefChristoph/chefops/internal"
 	"github.com/ChefChristoph/chefops/internal/tui"
 	tea "github.com/charmbracelet/bubbletea"
@@ -50,7 +49,7 @@ const (
 )
 
 type Model struct {
-	db *sql.DB
+	store *chefops.SQLiteStore
 
 	currentScreen screen
 	screen        Screen
@@ -62,6 +61,7 @@ type Model struct {
 
 	// detail screen
 	activeRecipe *tui.RecipeDetail
+	detailError  string
 	detailCursor int
 	exportPath   string
 	exportError  string
@@ -78,14 +78,14 @@ type Model struct {
 	selectedNoteFile string
 }
 
-func NewModel(db *sql.DB) (*Model, error) {
-	list, err := tui.LoadRecipes(db)
+func NewModel(store *chefops.SQLiteStore) (*Model, error) {
+	list, err := tui.LoadRecipes(store)
 	if err != nil {
 		return nil, err
 	}
 
 	return &Model{
-		db:            db,
+		store:         store,
 		recipes:       list,
 		currentScreen: screenList,
 		screen:        ScreenDashboard,
@@ -139,7 +139,7 @@ func (m *Model) importMetadata() {
 	}
 
 	// Load existing metadata
-	existingMeta, err := internal.LoadRecipeMetadata(m.selectedRecipeID)
+	existingMeta, err := m.store.RecipeMetadata(m.selectedRecipeID)
 	if err != nil {
 		// Handle error - maybe set a message in the model
 		return
@@ -159,7 +159,7 @@ func (m *Model) importMetadata() {
 	mergedMeta := internal.MergeMetadata(existingMeta, newMeta)
 
 	// Save to database
-	err = internal.SaveRecipeMetadata(m.selectedRecipeID, mergedMeta)
+	err = m.store.SetRecipeMetadata(m.selectedRecipeID, mergedMeta)
 	if err != nil {
 		// Handle error
 		return
@@ -190,10 +190,10 @@ func (m *Model) importNotes() {
 	}
 
 	// Try to find recipe by name (try both original and cleaned)
-	recipeID, err := internal.GetRecipeIDByName(cleanName)
+	recipe, err := m.store.RecipeByName(cleanName)
 	if err != nil {
 		// Try with original name if cleaned didn't work
-		recipeID, err = internal.GetRecipeIDByName(recipeName)
+		recipe, err = m.store.RecipeByName(recipeName)
 		if err != nil {
 			// Recipe not found, could add manual selection here
 			return
@@ -208,7 +208,7 @@ func (m *Model) importNotes() {
 	}
 
 	// Update database
-	err = internal.UpdateRecipeNotes(m.db, recipeID, notes)
+	err = m.store.SetRecipeNotes(recipe.ID, notes)
 	if err != nil {
 		// Handle error
 		return
@@ -284,8 +284,12 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			} else if m.currentScreen == screenList {
 				// enter opens detail view
 				id := m.recipes[m.cursor].ID
-				detail, _ := tui.LoadRecipeDetail(m.db, id)
+				detail, err := tui.LoadRecipeDetail(m.store, id)
 				m.activeRecipe = detail
+				m.detailError = ""
+				if err != nil {
+					m.detailError = err.Error()
+				}
 				m.currentScreen = screenDetail
 				m.detailCursor = 0
 			} else if m.currentScreen == screenDetail {
@@ -295,7 +299,11 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					if m.activeRecipe != nil {
 						// Generate export path
 						slug := tui.Slugify(m.activeRecipe.Name)
-						m.exportPath = fmt.Sprintf("exports/recipes/%s.csv", slug)
+						exportDir := internal.CurrentConfig().ExportDir
+						if exportDir == "" {
+							exportDir = "exports"
+						}
+						m.exportPath = filepath.Join(exportDir, "recipes", slug+".csv")
 						m.exportError = ""
 
 						// Export the recipe
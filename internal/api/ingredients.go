@@ -0,0 +1,163 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/ChefChristoph/chefops"
+	"github.com/ChefChristoph/chefops/internal"
+)
+
+type ingredientJSON struct {
+	ID          int     `json:"id"`
+	Name        string  `json:"name"`
+	Unit        string  `json:"unit"`
+	CostPerUnit float64 `json:"cost_per_unit"`
+}
+
+func toIngredientJSON(ing chefops.Ingredient) ingredientJSON {
+	return ingredientJSON{ID: ing.ID, Name: ing.Name, Unit: ing.Unit, CostPerUnit: ing.CostPerUnit}
+}
+
+// GET /api/ingredients[?q=TEXT]
+func (s *Server) listIngredients(w http.ResponseWriter, r *http.Request) {
+	list, err := s.store.Ingredients()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	q := strings.ToLower(r.URL.Query().Get("q"))
+	out := []ingredientJSON{}
+	for _, ing := range list {
+		if q == "" || strings.Contains(strings.ToLower(ing.Name), q) {
+			out = append(out, toIngredientJSON(ing))
+		}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) getIngredient(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	ing, err := s.store.Ingredient(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toIngredientJSON(ing))
+}
+
+// POST /api/ingredients {"name", "unit", "cost_per_unit"}
+func (s *Server) createIngredient(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name        string  `json:"name"`
+		Unit        string  `json:"unit"`
+		CostPerUnit float64 `json:"cost_per_unit"`
+	}
+	if err := decode(w, r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	if strings.TrimSpace(req.Name) == "" || req.Unit == "" || req.CostPerUnit <= 0 {
+		writeError(w, errorf(http.StatusBadRequest, "name, unit and positive cost_per_unit are required"))
+		return
+	}
+	if _, err := internal.NormalizeUnit(req.Unit); err != nil {
+		writeError(w, errorf(http.StatusBadRequest, "%v", err))
+		return
+	}
+
+	id, err := internal.CreateIngredient(s.db, req.Name, req.Unit, req.CostPerUnit, "api")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	ing, err := s.store.Ingredient(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, toIngredientJSON(ing))
+}
+
+// PATCH /api/ingredients/{id} {"name"?, "cost_per_unit"?}
+//
+// A new cost is recorded in the price history as of today. The unit cannot
+// change here: recipe lines, prices and stock are stored in it.
+func (s *Server) updateIngredient(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var req struct {
+		Name        *string  `json:"name"`
+		CostPerUnit *float64 `json:"cost_per_unit"`
+	}
+	if err := decode(w, r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	if req.CostPerUnit != nil && *req.CostPerUnit <= 0 {
+		writeError(w, errorf(http.StatusBadRequest, "cost_per_unit must be positive"))
+		return
+	}
+	if req.Name != nil && strings.TrimSpace(*req.Name) == "" {
+		writeError(w, errorf(http.StatusBadRequest, "name is empty"))
+		return
+	}
+	if _, err := s.store.Ingredient(id); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if req.Name != nil {
+		if err := internal.RenameIngredient(s.db, id, *req.Name); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+	if req.CostPerUnit != nil {
+		if err := internal.RecordPrice(s.db, id, *req.CostPerUnit, internal.Today(), "", "api"); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+
+	ing, err := s.store.Ingredient(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toIngredientJSON(ing))
+}
+
+// DELETE /api/ingredients/{id} refuses (409) while recipes use it.
+func (s *Server) deleteIngredient(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if _, err := s.store.Ingredient(id); err != nil {
+		writeError(w, err)
+		return
+	}
+	refs, err := internal.IngredientReferences(s.db, id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if len(refs) > 0 {
+		writeError(w, errorf(http.StatusConflict, "still used by %s", strings.Join(refs, ", ")))
+		return
+	}
+	if err := internal.DeleteIngredient(s.db, id); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/ChefChristoph/chefops"
+	"github.com/ChefChristoph/chefops/internal"
+)
+
+type usageJSON struct {
+	IngredientID int     `json:"ingredient_id"`
+	Name         string  `json:"name"`
+	Qty          float64 `json:"qty"`
+	Unit         string  `json:"unit"`
+	CostPerUnit  float64 `json:"cost_per_unit"`
+	Cost         float64 `json:"cost"`
+}
+
+func toUsageJSON(list []*chefops.IngredientUsage) []usageJSON {
+	out := make([]usageJSON, len(list))
+	for i, u := range list {
+		out[i] = usageJSON{
+			IngredientID: u.IngredientID,
+			Name:         u.Name,
+			Qty:          internal.CleanFloat(u.Qty),
+			Unit:         u.Unit,
+			CostPerUnit:  internal.CleanFloat(u.CostPerUnit),
+			Cost:         internal.RoundMoney(u.Cost()),
+		}
+	}
+	return out
+}
+
+// POST /api/forecast
+// {"dishes": [{"recipe_id": 12, "portions": 40}, {"recipe": "DISH Name", "portions": 25}]}
+func (s *Server) forecast(w http.ResponseWriter, r *http.Request) {
+	type dishJSON struct {
+		RecipeID int     `json:"recipe_id"`
+		Recipe   string  `json:"recipe"`
+		Portions float64 `json:"portions"`
+	}
+	var req struct {
+		Dishes []dishJSON `json:"dishes"`
+	}
+	if err := decode(w, r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	if len(req.Dishes) == 0 {
+		writeError(w, errorf(http.StatusBadRequest, "dishes is empty"))
+		return
+	}
+
+	dishes := make([]chefops.Dish, len(req.Dishes))
+	for i, d := range req.Dishes {
+		if d.Portions <= 0 {
+			writeError(w, errorf(http.StatusBadRequest, "dish %d: portions must be positive", i+1))
+			return
+		}
+		var rec chefops.Recipe
+		var err error
+		switch {
+		case d.RecipeID != 0:
+			rec, err = s.store.Recipe(d.RecipeID)
+		case d.Recipe != "":
+			rec, err = s.store.RecipeByName(d.Recipe)
+		default:
+			err = errorf(http.StatusBadRequest, "dish %d: recipe_id or recipe is required", i+1)
+		}
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		req.Dishes[i].RecipeID, req.Dishes[i].Recipe = rec.ID, rec.Name
+		dishes[i] = chefops.Dish{RecipeID: rec.ID, Portions: d.Portions}
+	}
+
+	f, err := s.kitchen.Forecast(dishes)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	type subrecipeJSON struct {
+		RecipeID int      `json:"recipe_id"`
+		Name     string   `json:"name"`
+		Qty      float64  `json:"qty"`
+		Unit     string   `json:"unit"`
+		Depth    int      `json:"depth"`
+		UsedIn   []string `json:"used_in"`
+	}
+	subs := make([]subrecipeJSON, len(f.Subrecipes))
+	for i, u := range f.Subrecipes {
+		subs[i] = subrecipeJSON{
+			RecipeID: u.RecipeID,
+			Name:     u.Name,
+			Qty:      internal.CleanFloat(u.Qty),
+			Unit:     u.Unit,
+			Depth:    u.Depth,
+			UsedIn:   u.UsedIn(),
+		}
+	}
+
+	writeJSON(w, http.StatusOK, struct {
+		Dishes      []dishJSON      `json:"dishes"`
+		Ingredients []usageJSON     `json:"ingredients"`
+		Subrecipes  []subrecipeJSON `json:"subrecipes"`
+		TotalCost   float64         `json:"total_cost"`
+	}{req.Dishes, toUsageJSON(f.Ingredients), subs, internal.RoundMoney(f.Cost())})
+}
+
+// GET /api/marketlist: one yield batch of every recipe that is not
+// archived, as `chefops marketlist` lists before pack rounding.
+func (s *Server) marketList(w http.ResponseWriter, r *http.Request) {
+	items, err := s.kitchen.MarketList()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toUsageJSON(items))
+}
@@ -0,0 +1,368 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ChefChristoph/chefops"
+	"github.com/ChefChristoph/chefops/internal"
+)
+
+type recipeJSON struct {
+	ID                 int        `json:"id"`
+	Name               string     `json:"name"`
+	YieldQty           float64    `json:"yield_qty"`
+	YieldUnit          string     `json:"yield_unit"`
+	SecondaryYieldQty  float64    `json:"secondary_yield_qty,omitempty"`
+	SecondaryYieldUnit string     `json:"secondary_yield_unit,omitempty"`
+	Archived           bool       `json:"archived"`
+	Notes              string     `json:"notes,omitempty"`
+	Lines              []lineJSON `json:"lines,omitempty"`
+}
+
+func toRecipeJSON(r chefops.Recipe) recipeJSON {
+	return recipeJSON{
+		ID:                 r.ID,
+		Name:               r.Name,
+		YieldQty:           r.YieldQty,
+		YieldUnit:          r.YieldUnit,
+		SecondaryYieldQty:  r.SecondaryYieldQty,
+		SecondaryYieldUnit: r.SecondaryYieldUnit,
+		Archived:           r.Archived,
+	}
+}
+
+type lineJSON struct {
+	ID           int     `json:"id"`
+	Type         string  `json:"type"`
+	IngredientID int     `json:"ingredient_id,omitempty"`
+	SubrecipeID  int     `json:"subrecipe_id,omitempty"`
+	Name         string  `json:"name"`
+	Qty          float64 `json:"qty"`
+	Unit         string  `json:"unit"`
+}
+
+func toLinesJSON(lines []chefops.Line) []lineJSON {
+	out := make([]lineJSON, len(lines))
+	for i, l := range lines {
+		out[i] = lineJSON{
+			ID:           l.ID,
+			Type:         l.Type,
+			IngredientID: l.IngredientID,
+			SubrecipeID:  l.SubrecipeID,
+			Name:         l.Name,
+			Qty:          l.Qty,
+			Unit:         l.Unit,
+		}
+	}
+	return out
+}
+
+type costLineJSON struct {
+	Type         string  `json:"type"`
+	IngredientID int     `json:"ingredient_id,omitempty"`
+	SubrecipeID  int     `json:"subrecipe_id,omitempty"`
+	Name         string  `json:"name"`
+	Qty          float64 `json:"qty"`
+	Unit         string  `json:"unit"`
+	BaseQty      float64 `json:"base_qty"`
+	BaseUnit     string  `json:"base_unit"`
+	CostPerUnit  float64 `json:"cost_per_unit"`
+	LineCost     float64 `json:"line_cost"`
+}
+
+func toCostLinesJSON(lines []chefops.CostLine) []costLineJSON {
+	out := make([]costLineJSON, len(lines))
+	for i, l := range lines {
+		out[i] = costLineJSON{
+			Type:         l.Type,
+			IngredientID: l.IngredientID,
+			SubrecipeID:  l.SubrecipeID,
+			Name:         l.Name,
+			Qty:          internal.CleanFloat(l.Qty),
+			Unit:         l.Unit,
+			BaseQty:      internal.CleanFloat(l.BaseQty),
+			BaseUnit:     l.BaseUnit,
+			CostPerUnit:  internal.CleanFloat(l.CostPerUnit),
+			LineCost:     internal.RoundMoney(l.LineCost),
+		}
+	}
+	return out
+}
+
+// GET /api/recipes[?q=TEXT]: recipes that are not archived.
+func (s *Server) listRecipes(w http.ResponseWriter, r *http.Request) {
+	list, err := s.store.Recipes()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	q := strings.ToLower(r.URL.Query().Get("q"))
+	out := []recipeJSON{}
+	for _, rec := range list {
+		if q == "" || strings.Contains(strings.ToLower(rec.Name), q) {
+			out = append(out, toRecipeJSON(rec))
+		}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// GET /api/recipes/{id}: the recipe with its notes and lines.
+func (s *Server) getRecipe(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	rec, err := s.store.Recipe(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	lines, err := s.store.Lines(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	out := toRecipeJSON(rec)
+	out.Notes = rec.Notes
+	out.Lines = toLinesJSON(lines)
+	writeJSON(w, http.StatusOK, out)
+}
+
+// GET /api/recipes/{id}/cost[?as_of=YYYY-MM-DD]
+func (s *Server) costRecipe(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	k := s.kitchen
+	if asOf := r.URL.Query().Get("as_of"); asOf != "" {
+		date, err := internal.ParseDate(asOf)
+		if err != nil {
+			writeError(w, errorf(http.StatusBadRequest, "as_of: %v", err))
+			return
+		}
+		k = k.AsOf(date)
+	}
+	rc, err := k.Cost(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	type costJSON struct {
+		RecipeID           int            `json:"recipe_id"`
+		Recipe             string         `json:"recipe"`
+		AsOf               string         `json:"as_of,omitempty"`
+		YieldQty           float64        `json:"yield_qty"`
+		YieldUnit          string         `json:"yield_unit"`
+		SecondaryYieldQty  float64        `json:"secondary_yield_qty,omitempty"`
+		SecondaryYieldUnit string         `json:"secondary_yield_unit,omitempty"`
+		TotalCost          float64        `json:"total_cost"`
+		CostPerYieldUnit   float64        `json:"cost_per_yield_unit"`
+		Lines              []costLineJSON `json:"lines"`
+	}
+	writeJSON(w, http.StatusOK, costJSON{
+		RecipeID:           rc.RecipeID,
+		Recipe:             rc.Name,
+		AsOf:               rc.AsOf,
+		YieldQty:           rc.YieldQty,
+		YieldUnit:          rc.YieldUnit,
+		SecondaryYieldQty:  rc.SecondaryYieldQty,
+		SecondaryYieldUnit: rc.SecondaryYieldUnit,
+		TotalCost:          internal.RoundMoney(rc.TotalCost),
+		CostPerYieldUnit:   internal.CleanFloat(rc.CostPerYieldUnit()),
+		Lines:              toCostLinesJSON(rc.Lines),
+	})
+}
+
+// GET /api/recipes/{id}/scale?qty=X&unit=UNIT
+func (s *Server) scaleRecipe(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	qty, err := strconv.ParseFloat(r.URL.Query().Get("qty"), 64)
+	unit := r.URL.Query().Get("unit")
+	if err != nil || qty <= 0 || unit == "" {
+		writeError(w, errorf(http.StatusBadRequest, "qty (positive number) and unit are required"))
+		return
+	}
+	sr, err := s.kitchen.Scale(id, qty, unit)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	type scaleJSON struct {
+		RecipeID   int            `json:"recipe_id"`
+		Recipe     string         `json:"recipe"`
+		YieldQty   float64        `json:"yield_qty"`
+		YieldUnit  string         `json:"yield_unit"`
+		TargetQty  float64        `json:"target_qty"`
+		TargetUnit string         `json:"target_unit"`
+		Factor     float64        `json:"factor"`
+		TotalCost  float64        `json:"total_cost"`
+		Lines      []costLineJSON `json:"lines"`
+	}
+	writeJSON(w, http.StatusOK, scaleJSON{
+		RecipeID:   sr.Recipe.RecipeID,
+		Recipe:     sr.Recipe.Name,
+		YieldQty:   sr.Recipe.YieldQty,
+		YieldUnit:  sr.Recipe.YieldUnit,
+		TargetQty:  sr.TargetQty,
+		TargetUnit: sr.TargetUnit,
+		Factor:     internal.CleanFloat(sr.Factor),
+		TotalCost:  internal.RoundMoney(sr.TotalCost),
+		Lines:      toCostLinesJSON(sr.Lines),
+	})
+}
+
+// GET /api/recipes/{id}/lines
+func (s *Server) listLines(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if _, err := s.store.Recipe(id); err != nil {
+		writeError(w, err)
+		return
+	}
+	lines, err := s.store.Lines(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toLinesJSON(lines))
+}
+
+// POST /api/recipes/{id}/lines
+// {"ingredient_id" or "subrecipe_id", "qty", "unit"?}
+//
+// Without a unit the line is in the ingredient's unit or the subrecipe's
+// yield unit.
+func (s *Server) addLine(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var req struct {
+		IngredientID int     `json:"ingredient_id"`
+		SubrecipeID  int     `json:"subrecipe_id"`
+		Qty          float64 `json:"qty"`
+		Unit         string  `json:"unit"`
+	}
+	if err := decode(w, r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	if (req.IngredientID == 0) == (req.SubrecipeID == 0) {
+		writeError(w, errorf(http.StatusBadRequest, "give exactly one of ingredient_id and subrecipe_id"))
+		return
+	}
+	if req.Qty <= 0 {
+		writeError(w, errorf(http.StatusBadRequest, "qty must be positive"))
+		return
+	}
+
+	lineType, lineID := internal.LineIngredient, 0
+	if req.SubrecipeID != 0 {
+		lineType = internal.LineSubrecipe
+		lineID, err = internal.AddSubrecipeLine(s.db, id, req.SubrecipeID, req.Qty, req.Unit)
+	} else {
+		lineID, err = internal.AddIngredientLine(s.db, id, req.IngredientID, req.Qty, req.Unit)
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	s.writeLine(w, http.StatusCreated, id, lineType, lineID)
+}
+
+// PATCH /api/recipes/{id}/lines/{type}/{line} {"qty", "unit"?}
+func (s *Server) updateLine(w http.ResponseWriter, r *http.Request) {
+	recipeID, lineType, lineID, err := s.lineFromPath(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var req struct {
+		Qty  float64 `json:"qty"`
+		Unit string  `json:"unit"`
+	}
+	if err := decode(w, r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	if req.Qty <= 0 {
+		writeError(w, errorf(http.StatusBadRequest, "qty must be positive"))
+		return
+	}
+	if err := internal.UpdateLine(s.db, lineType, lineID, req.Qty, req.Unit); err != nil {
+		writeError(w, err)
+		return
+	}
+	s.writeLine(w, http.StatusOK, recipeID, lineType, lineID)
+}
+
+// DELETE /api/recipes/{id}/lines/{type}/{line}
+func (s *Server) deleteLine(w http.ResponseWriter, r *http.Request) {
+	_, lineType, lineID, err := s.lineFromPath(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := internal.DeleteLine(s.db, lineType, lineID); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// lineFromPath reads {id}/lines/{type}/{line} and checks that the line
+// belongs to the recipe.
+func (s *Server) lineFromPath(r *http.Request) (int, string, int, error) {
+	recipeID, err := pathID(r, "id")
+	if err != nil {
+		return 0, "", 0, err
+	}
+	lineType := r.PathValue("type")
+	if lineType != internal.LineIngredient && lineType != internal.LineSubrecipe {
+		return 0, "", 0, errorf(http.StatusBadRequest, "line type must be %s or %s, not %q",
+			internal.LineIngredient, internal.LineSubrecipe, lineType)
+	}
+	lineID, err := pathID(r, "line")
+	if err != nil {
+		return 0, "", 0, err
+	}
+	owner, err := internal.LineRecipe(s.db, lineType, lineID)
+	if err != nil {
+		return 0, "", 0, err
+	}
+	if owner != recipeID {
+		return 0, "", 0, errorf(http.StatusNotFound, "%s line %d is not in recipe %d", lineType, lineID, recipeID)
+	}
+	return recipeID, lineType, lineID, nil
+}
+
+// writeLine answers with one line as the recipe now has it.
+func (s *Server) writeLine(w http.ResponseWriter, status, recipeID int, lineType string, lineID int) {
+	lines, err := s.store.Lines(recipeID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	for _, l := range toLinesJSON(lines) {
+		if l.Type == lineType && l.ID == lineID {
+			writeJSON(w, status, l)
+			return
+		}
+	}
+	writeError(w, errorf(http.StatusNotFound, "%s line %d not found", lineType, lineID))
+}
@@ -0,0 +1,161 @@
+// Package api is the ChefOps HTTP API served by `chefops serve`: JSON
+// endpoints for ingredients, recipes and their lines, costing, scaling,
+// forecasts and market lists over one chefops database.
+//
+// Server is an http.Handler, so it runs under httptest without a listener.
+package api
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/ChefChristoph/chefops"
+	"github.com/ChefChristoph/chefops/internal"
+)
+
+// Server routes API requests to handlers working on db.
+type Server struct {
+	db       *sql.DB
+	store    *chefops.SQLiteStore
+	kitchen  *chefops.Kitchen
+	token    string
+	readOnly bool
+	mux      *http.ServeMux
+}
+
+// NewServer returns the API over db. A non-empty token must be sent by
+// every request as "Authorization: Bearer TOKEN".
+func NewServer(db *sql.DB, token string) *Server {
+	return newServer(db, token, false)
+}
+
+// NewReadOnlyServer returns the API over db without the endpoints that
+// change it. Forecasts, which only read, stay available.
+func NewReadOnlyServer(db *sql.DB) *Server {
+	return newServer(db, "", true)
+}
+
+func newServer(db *sql.DB, token string, readOnly bool) *Server {
+	store := chefops.NewSQLiteStore(db)
+	s := &Server{
+		db:       db,
+		store:    store,
+		kitchen:  chefops.New(store),
+		token:    token,
+		readOnly: readOnly,
+		mux:      http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("GET /api/ingredients", s.listIngredients)
+	s.write("POST /api/ingredients", s.createIngredient)
+	s.mux.HandleFunc("GET /api/ingredients/{id}", s.getIngredient)
+	s.write("PATCH /api/ingredients/{id}", s.updateIngredient)
+	s.write("DELETE /api/ingredients/{id}", s.deleteIngredient)
+
+	s.mux.HandleFunc("GET /api/recipes", s.listRecipes)
+	s.mux.HandleFunc("GET /api/recipes/{id}", s.getRecipe)
+	s.mux.HandleFunc("GET /api/recipes/{id}/cost", s.costRecipe)
+	s.mux.HandleFunc("GET /api/recipes/{id}/scale", s.scaleRecipe)
+	s.mux.HandleFunc("GET /api/recipes/{id}/lines", s.listLines)
+	s.write("POST /api/recipes/{id}/lines", s.addLine)
+	s.write("PATCH /api/recipes/{id}/lines/{type}/{line}", s.updateLine)
+	s.write("DELETE /api/recipes/{id}/lines/{type}/{line}", s.deleteLine)
+
+	s.mux.HandleFunc("POST /api/forecast", s.forecast)
+	s.mux.HandleFunc("GET /api/marketlist", s.marketList)
+
+	s.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, errorf(http.StatusNotFound, "no such endpoint: %s %s", r.Method, r.URL.Path))
+	})
+	return s
+}
+
+// write registers a handler that changes the database. A read-only server
+// answers it with 405 instead.
+func (s *Server) write(pattern string, h http.HandlerFunc) {
+	if s.readOnly {
+		h = func(w http.ResponseWriter, r *http.Request) {
+			writeError(w, errorf(http.StatusMethodNotAllowed, "read-only server: %s %s is not allowed", r.Method, r.URL.Path))
+		}
+	}
+	s.mux.HandleFunc(pattern, h)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.token != "" {
+		want := "Bearer " + s.token
+		got := r.Header.Get("Authorization")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(want)) != 1 {
+			writeError(w, errorf(http.StatusUnauthorized, "missing or wrong API token"))
+			return
+		}
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// apiError is an error with the HTTP status to answer it with.
+type apiError struct {
+	status int
+	err    error
+}
+
+func (e *apiError) Error() string { return e.err.Error() }
+func (e *apiError) Unwrap() error { return e.err }
+
+func errorf(status int, format string, args ...any) error {
+	return &apiError{status: status, err: fmt.Errorf(format, args...)}
+}
+
+// statusOf picks the HTTP status for an error from the chefops packages.
+func statusOf(err error) int {
+	var ae *apiError
+	switch {
+	case errors.As(err, &ae):
+		return ae.status
+	case errors.Is(err, internal.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, internal.ErrIngredientExists),
+		errors.Is(err, internal.ErrRecipeExists),
+		errors.Is(err, internal.ErrSubrecipeCycle):
+		return http.StatusConflict
+	case errors.Is(err, internal.ErrNoConversion):
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	writeJSON(w, statusOf(err), map[string]string{"error": err.Error()})
+}
+
+// decode reads a JSON request body into v, refusing unknown fields.
+func decode(w http.ResponseWriter, r *http.Request, v any) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return errorf(http.StatusBadRequest, "invalid JSON body: %v", err)
+	}
+	return nil
+}
+
+// pathID returns the integer path value name.
+func pathID(r *http.Request, name string) (int, error) {
+	id, err := strconv.Atoi(r.PathValue(name))
+	if err != nil || id <= 0 {
+		return 0, errorf(http.StatusBadRequest, "%s must be a positive integer, not %q", name, r.PathValue(name))
+	}
+	return id, nil
+}
@@ -0,0 +1,291 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ChefChristoph/chefops/internal"
+)
+
+// fixture is a fresh database with
+//
+//	Flour (kg, 2.00) and Butter (kg, 10.00)
+//	SUB Dough (2 kg)  = 1 kg Flour + 0.5 kg Butter
+//	DISH Tart (1 portion) = 0.5 kg SUB Dough
+type fixture struct {
+	db                         *sql.DB
+	flour, butter, dough, tart int
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	db, err := internal.CreateDBAt(filepath.Join(t.TempDir(), "chefops.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	store := internal.NewSQLStore(db)
+	f := &fixture{db: db}
+	must := func(id int, err error) int {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+	f.flour = must(store.SaveIngredient("Flour", "kg", 2, "test"))
+	f.butter = must(store.SaveIngredient("Butter", "kg", 10, "test"))
+	f.dough = must(store.SaveRecipe(internal.Recipe{Name: "SUB Dough", YieldQty: 2, YieldUnit: "kg"}))
+	f.tart = must(store.SaveRecipe(internal.Recipe{Name: "DISH Tart", YieldQty: 1, YieldUnit: "portion"}))
+	must(store.AddIngredientLine(f.dough, f.flour, 1, "kg"))
+	must(store.AddIngredientLine(f.dough, f.butter, 0.5, "kg"))
+	must(store.AddSubrecipeLine(f.tart, f.dough, 0.5, "kg"))
+	return f
+}
+
+// do sends a request to h and decodes the JSON response into out, if given.
+func do(t *testing.T, h http.Handler, method, path, body string, out any) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("%s %s: Content-Type = %q, want application/json", method, path, ct)
+	}
+	if out != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: decoding %q: %v", method, path, rec.Body.String(), err)
+		}
+	}
+	return rec
+}
+
+func TestIngredients(t *testing.T) {
+	f := newFixture(t)
+	s := NewServer(f.db, "")
+
+	var created ingredientJSON
+	rec := do(t, s, "POST", "/api/ingredients", `{"name": "Sugar", "unit": "kg", "cost_per_unit": 4.5}`, &created)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: status %d, body %s", rec.Code, rec.Body)
+	}
+	if created.ID == 0 || created.Name != "Sugar" || created.Unit != "kg" || created.CostPerUnit != 4.5 {
+		t.Errorf("create returned %+v", created)
+	}
+
+	var got ingredientJSON
+	if rec := do(t, s, "GET", fmt.Sprintf("/api/ingredients/%d", created.ID), "", &got); rec.Code != http.StatusOK {
+		t.Fatalf("get: status %d", rec.Code)
+	}
+	if got != created {
+		t.Errorf("get returned %+v, want %+v", got, created)
+	}
+
+	var list []ingredientJSON
+	do(t, s, "GET", "/api/ingredients", "", &list)
+	var names []string
+	for _, ing := range list {
+		names = append(names, ing.Name)
+	}
+	if strings.Join(names, ",") != "Butter,Flour,Sugar" {
+		t.Errorf("list = %v, want Butter, Flour, Sugar", names)
+	}
+
+	do(t, s, "GET", "/api/ingredients?q=FLO", "", &list)
+	if len(list) != 1 || list[0].ID != f.flour {
+		t.Errorf("list ?q=FLO = %+v, want Flour only", list)
+	}
+}
+
+func TestRecipes(t *testing.T) {
+	f := newFixture(t)
+	s := NewServer(f.db, "")
+
+	var list []recipeJSON
+	if rec := do(t, s, "GET", "/api/recipes", "", &list); rec.Code != http.StatusOK {
+		t.Fatalf("list: status %d", rec.Code)
+	}
+	if len(list) != 2 || list[0].Name != "DISH Tart" || list[1].Name != "SUB Dough" {
+		t.Errorf("list = %+v", list)
+	}
+
+	var dough recipeJSON
+	do(t, s, "GET", fmt.Sprintf("/api/recipes/%d", f.dough), "", &dough)
+	if dough.YieldQty != 2 || dough.YieldUnit != "kg" || len(dough.Lines) != 2 {
+		t.Errorf("get SUB Dough = %+v", dough)
+	}
+
+	var cost struct {
+		TotalCost        float64 `json:"total_cost"`
+		CostPerYieldUnit float64 `json:"cost_per_yield_unit"`
+	}
+	do(t, s, "GET", fmt.Sprintf("/api/recipes/%d/cost", f.tart), "", &cost)
+	// Half of a 7.00 batch of 2 kg.
+	if cost.TotalCost != 1.75 || cost.CostPerYieldUnit != 1.75 {
+		t.Errorf("cost of DISH Tart = %+v, want 1.75", cost)
+	}
+
+	var line lineJSON
+	rec := do(t, s, "POST", fmt.Sprintf("/api/recipes/%d/lines", f.tart),
+		fmt.Sprintf(`{"ingredient_id": %d, "qty": 50, "unit": "g"}`, f.butter), &line)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("add line: status %d, body %s", rec.Code, rec.Body)
+	}
+	if line.ID == 0 || line.Type != internal.LineIngredient || line.Name != "Butter" || line.Unit != "g" {
+		t.Errorf("add line returned %+v", line)
+	}
+}
+
+func TestAddLineRejectsCycle(t *testing.T) {
+	f := newFixture(t)
+	s := NewServer(f.db, "")
+
+	var body map[string]string
+	rec := do(t, s, "POST", fmt.Sprintf("/api/recipes/%d/lines", f.dough),
+		fmt.Sprintf(`{"subrecipe_id": %d, "qty": 1, "unit": "portion"}`, f.tart), &body)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("status %d, want 409; body %s", rec.Code, rec.Body)
+	}
+	if !strings.Contains(body["error"], "SUB Dough → DISH Tart → SUB Dough") {
+		t.Errorf("error %q does not show the cycle", body["error"])
+	}
+
+	lines, err := internal.NewSQLStore(f.db).Lines(f.dough)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 2 {
+		t.Errorf("SUB Dough has %d lines after the refused add, want 2", len(lines))
+	}
+}
+
+func TestToken(t *testing.T) {
+	f := newFixture(t)
+	s := NewServer(f.db, "s3cret")
+
+	tests := []struct {
+		name   string
+		header string
+		status int
+	}{
+		{"no token", "", http.StatusUnauthorized},
+		{"wrong token", "Bearer nope", http.StatusUnauthorized},
+		{"token without Bearer", "s3cret", http.StatusUnauthorized},
+		{"right token", "Bearer s3cret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/recipes", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Errorf("status %d, want %d", rec.Code, tt.status)
+			}
+			if tt.status == http.StatusUnauthorized && !strings.Contains(rec.Body.String(), `"error"`) {
+				t.Errorf("401 body %s has no error", rec.Body)
+			}
+		})
+	}
+}
+
+func TestErrorShapes(t *testing.T) {
+	f := newFixture(t)
+	s := NewServer(f.db, "")
+
+	tests := []struct {
+		name         string
+		method, path string
+		body         string
+		status       int
+		message      string
+	}{
+		{"unknown recipe", "GET", "/api/recipes/999", "", http.StatusNotFound, "not found"},
+		{"bad id", "GET", "/api/recipes/abc", "", http.StatusBadRequest, "id must be a positive integer"},
+		{"unknown endpoint", "GET", "/api/nothing", "", http.StatusNotFound, "no such endpoint"},
+		{"invalid JSON", "POST", "/api/ingredients", `{"name": `, http.StatusBadRequest, "invalid JSON body"},
+		{"unknown field", "POST", "/api/ingredients", `{"name": "Salt", "colour": "white"}`, http.StatusBadRequest, "invalid JSON body"},
+		{"missing fields", "POST", "/api/ingredients", `{"name": "Salt"}`, http.StatusBadRequest, "required"},
+		{"duplicate ingredient", "POST", "/api/ingredients", `{"name": "flour", "unit": "kg", "cost_per_unit": 1}`, http.StatusConflict, "flour"},
+		{"unit without conversion", "POST", fmt.Sprintf("/api/recipes/%d/lines", f.tart),
+			fmt.Sprintf(`{"ingredient_id": %d, "qty": 2, "unit": "piece"}`, f.flour), http.StatusUnprocessableEntity, "piece"},
+		{"both line kinds", "POST", fmt.Sprintf("/api/recipes/%d/lines", f.tart),
+			fmt.Sprintf(`{"ingredient_id": %d, "subrecipe_id": %d, "qty": 1}`, f.flour, f.dough), http.StatusBadRequest, "exactly one"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body map[string]any
+			rec := do(t, s, tt.method, tt.path, tt.body, &body)
+			if rec.Code != tt.status {
+				t.Errorf("status %d, want %d", rec.Code, tt.status)
+			}
+			msg, ok := body["error"].(string)
+			if len(body) != 1 || !ok {
+				t.Fatalf("body %s, want {\"error\": \"...\"}", rec.Body)
+			}
+			if !strings.Contains(strings.ToLower(msg), strings.ToLower(tt.message)) {
+				t.Errorf("error %q does not mention %q", msg, tt.message)
+			}
+		})
+	}
+}
+
+func TestReadOnlyServerRefusesWrites(t *testing.T) {
+	f := newFixture(t)
+	s := NewReadOnlyServer(f.db)
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("POST", "/api/ingredients",
+		strings.NewReader(`{"name": "Sugar", "unit": "kg", "cost_per_unit": 4.5}`)))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST on a read-only server: status %d, want 405", rec.Code)
+	}
+	list, err := internal.NewSQLStore(f.db).IngredientsNamed("Sugar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 0 {
+		t.Error("read-only server created an ingredient")
+	}
+}
+
+func TestCostsHaveNoFloatNoise(t *testing.T) {
+	f := newFixture(t)
+	store := internal.NewSQLStore(f.db)
+	salt, err := store.SaveIngredient("Salt", "kg", 0.1, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	brine, err := store.SaveRecipe(internal.Recipe{Name: "SUB Brine", YieldQty: 1, YieldUnit: "kg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.AddIngredientLine(brine, salt, 3, "kg"); err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer(f.db, "")
+
+	// 3 × 0.1 is 0.30000000000000004 in float64.
+	for _, path := range []string{
+		fmt.Sprintf("/api/recipes/%d/cost", brine),
+		fmt.Sprintf("/api/recipes/%d/scale?qty=1&unit=kg", brine),
+	} {
+		rec := do(t, s, "GET", path, "", nil)
+		if strings.Contains(rec.Body.String(), "0.30000000000000004") {
+			t.Errorf("GET %s: float noise in %s", path, rec.Body)
+		}
+	}
+	rec := do(t, s, "POST", "/api/forecast", fmt.Sprintf(`{"dishes": [{"recipe_id": %d, "portions": 1}]}`, brine), nil)
+	if strings.Contains(rec.Body.String(), "0.30000000000000004") {
+		t.Errorf("forecast: float noise in %s", rec.Body)
+	}
+}
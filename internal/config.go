@@ -0,0 +1,139 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+// DefaultDBPath is used when neither --db, CHEFOPS_DB nor the config file
+// name a database.
+const DefaultDBPath = "db/chefops.db"
+
+// Config holds the settings shared by the CLI and the TUI.
+//
+// It is read from $XDG_CONFIG_HOME/chefops/config.json (or
+// ~/.config/chefops/config.json), e.g.
+//
+//	{
+//	  "db": "~/kitchens/yas/chefops.db",
+//	  "currency": "AED",
+//	  "export_dir": "~/Documents/chefops"
+//	}
+type Config struct {
+	DBPath    string `json:"db,omitempty"`
+	Currency  string `json:"currency,omitempty"`
+	ExportDir string `json:"export_dir,omitempty"`
+
+	// Source records where DBPath came from (flag, env, config, default).
+	Source string `json:"-"`
+	// File is the config file that was read, if any.
+	File string `json:"-"`
+}
+
+var activeConfig = &Config{DBPath: DefaultDBPath, Source: "default"}
+
+// CurrentConfig returns the settings resolved by LoadConfig.
+func CurrentConfig() *Config {
+	return activeConfig
+}
+
+// ConfigPath returns the location of the config file.
+func ConfigPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "chefops", "config.json")
+}
+
+// LoadConfig resolves the settings for this run and makes them current.
+// The database path comes from, in order: dbFlag, the CHEFOPS_DB
+// environment variable, the config file, DefaultDBPath.
+func LoadConfig(dbFlag string) (*Config, error) {
+	cfg := &Config{}
+
+	path := ConfigPath()
+	if path != "" {
+		data, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			if err := json.Unmarshal(data, cfg); err != nil {
+				return nil, fmt.Errorf("parsing %s: %w", path, err)
+			}
+			cfg.File = path
+			if cfg.DBPath != "" {
+				cfg.Source = "config"
+			}
+		case !errors.Is(err, fs.ErrNotExist):
+			return nil, fmt.Errorf("reading %s: %w", path, err)
+		}
+	}
+
+	if env := os.Getenv("CHEFOPS_DB"); env != "" {
+		cfg.DBPath = env
+		cfg.Source = "env"
+	}
+	if dbFlag != "" {
+		cfg.DBPath = dbFlag
+		cfg.Source = "flag"
+	}
+	if cfg.DBPath == "" {
+		cfg.DBPath = DefaultDBPath
+		cfg.Source = "default"
+	}
+
+	cfg.DBPath = expandHome(cfg.DBPath)
+	cfg.ExportDir = expandHome(cfg.ExportDir)
+
+	activeConfig = cfg
+	return cfg, nil
+}
+
+// ExportPath places a relative output path under the configured export
+// directory. Absolute paths, and all paths when no export_dir is set, are
+// returned unchanged.
+func (c *Config) ExportPath(path string) string {
+	if c.ExportDir == "" || path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(c.ExportDir, path)
+}
+
+// Money formats an amount with the configured currency, if any.
+func (c *Config) Money(v float64) string {
+	if c.Currency == "" {
+		return fmt.Sprintf("%.2f", v)
+	}
+	return fmt.Sprintf("%.2f %s", v, c.Currency)
+}
+
+// RoundMoney rounds an amount to cents, for JSON and CSV output.
+func RoundMoney(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// CleanFloat drops float noise from v (6.4512, not 6.451199999999999) but
+// keeps real precision such as a unit cost of 0.0045 per gram.
+func CleanFloat(v float64) float64 {
+	return math.Round(v*1e9) / 1e9
+}
+
+func expandHome(path string) string {
+	if len(path) < 2 || path[:2] != "~/" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[2:])
+}
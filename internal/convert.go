@@ -0,0 +1,136 @@
+package internal
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ConversionStep is one row of ingredient_conversions, read in either
+// direction: FromQty FromUnit is equivalent to ToQty ToUnit.
+type ConversionStep struct {
+	FromQty  float64
+	FromUnit string
+	ToQty    float64
+	ToUnit   string
+}
+
+// ErrNoConversion is returned (wrapped) when a quantity cannot be expressed
+// in the requested unit.
+var ErrNoConversion = errors.New("no unit conversion")
+
+// ConvertIngredientQty converts qty of an ingredient between two units.
+// Standard units of one dimension (g ↔ kg, ml ↔ liter) convert directly;
+// across dimensions it uses the ingredient's ingredient_conversions rows,
+// which are usable in both directions and may be chained with standard
+// factors (e.g. piece → g → kg).
+func ConvertIngredientQty(db *sql.DB, ingredientID int, qty float64, fromUnit, toUnit string) (float64, error) {
+	return ConvertQty(NewSQLStore(db), ingredientID, qty, fromUnit, toUnit)
+}
+
+// ConvertQty is ConvertIngredientQty reading conversions from a Store.
+func ConvertQty(s Store, ingredientID int, qty float64, fromUnit, toUnit string) (float64, error) {
+	return convertQty(s.Conversions, ingredientID, qty, fromUnit, toUnit)
+}
+
+// convertQty is ConvertQty with the ingredient's conversions read by load.
+func convertQty(load func(int) ([]Conversion, error), ingredientID int, qty float64, fromUnit, toUnit string) (float64, error) {
+	if converted, ok := ConvertStandard(qty, fromUnit, toUnit); ok {
+		return converted, nil
+	}
+
+	conversions, err := load(ingredientID)
+	if err != nil {
+		return 0, err
+	}
+
+	converted, err := resolveConversionChain(conversionSteps(conversions), qty, fromUnit, toUnit, map[string]bool{})
+	if err != nil {
+		return 0, fmt.Errorf("%w: cannot convert %s → %s", ErrNoConversion, fromUnit, toUnit)
+	}
+	return converted, nil
+}
+
+// conversionSteps turns conversions into steps for resolveConversionChain,
+// each one in both directions.
+func conversionSteps(conversions []Conversion) []ConversionStep {
+	var steps []ConversionStep
+	for _, c := range conversions {
+		if c.FromQty <= 0 || c.ToQty <= 0 {
+			continue
+		}
+		s := ConversionStep{
+			FromQty:  c.FromQty,
+			FromUnit: CanonicalUnit(c.FromUnit),
+			ToQty:    c.ToQty,
+			ToUnit:   CanonicalUnit(c.ToUnit),
+		}
+		steps = append(steps, s)
+		// Every conversion also holds in reverse.
+		steps = append(steps, ConversionStep{
+			FromQty:  s.ToQty,
+			FromUnit: s.ToUnit,
+			ToQty:    s.FromQty,
+			ToUnit:   s.FromUnit,
+		})
+	}
+	return steps
+}
+
+// resolveConversionChain walks the conversion graph depth-first from
+// fromUnit until it reaches targetUnit. A step applies when its from unit
+// is fromUnit or a standard unit of the same dimension.
+func resolveConversionChain(steps []ConversionStep, qty float64, fromUnit, targetUnit string, visited map[string]bool) (float64, error) {
+	fromUnit = CanonicalUnit(fromUnit)
+	if converted, ok := ConvertStandard(qty, fromUnit, targetUnit); ok {
+		return converted, nil
+	}
+
+	// Loop protection
+	if visited[fromUnit] {
+		return 0, errors.New("conversion loop detected")
+	}
+	visited[fromUnit] = true
+
+	for _, s := range steps {
+		stepQty, ok := ConvertStandard(qty, fromUnit, s.FromUnit)
+		if !ok || visited[s.ToUnit] {
+			continue
+		}
+
+		next := stepQty * s.ToQty / s.FromQty
+		if converted, err := resolveConversionChain(steps, next, s.ToUnit, targetUnit, visited); err == nil {
+			return converted, nil
+		}
+	}
+
+	return 0, fmt.Errorf("cannot convert '%s' → '%s'", fromUnit, targetUnit)
+}
+
+// ConvertYieldQty converts qty of a recipe from unit into the recipe's
+// primary yield unit. The secondary yield (e.g. 2 kg = 40 piece) acts as the
+// recipe's own conversion.
+func ConvertYieldQty(rc *RecipeCost, qty float64, unit string) (float64, error) {
+	if unit == "" {
+		return qty, nil
+	}
+	if converted, ok := ConvertStandard(qty, unit, rc.YieldUnit); ok {
+		return converted, nil
+	}
+
+	if rc.SecondaryYieldUnit != "" && rc.SecondaryYieldQty > 0 {
+		if secQty, ok := ConvertStandard(qty, unit, rc.SecondaryYieldUnit); ok {
+			return secQty * rc.YieldQty / rc.SecondaryYieldQty, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: %s is measured in %s, cannot use %s",
+		ErrNoConversion, rc.Name, yieldUnits(rc), unit)
+}
+
+func yieldUnits(rc *RecipeCost) string {
+	if rc.SecondaryYieldUnit != "" && rc.SecondaryYieldQty > 0 {
+		return rc.YieldUnit + " or " + rc.SecondaryYieldUnit
+	}
+	return rc.YieldUnit
+}
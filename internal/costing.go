@@ -0,0 +1,222 @@
+package internal
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// CostLine is one priced line of a recipe: either an ingredient or a
+// subrecipe, whose cost per unit is derived from its own full costing.
+//
+// Qty and Unit are as entered on the line; BaseQty and BaseUnit are the same
+// amount expressed in the ingredient's unit or the subrecipe's yield unit.
+// CostPerUnit is per BaseUnit.
+type CostLine struct {
+	Type         string // "ingredient" or "subrecipe"
+	Name         string
+	Qty          float64
+	Unit         string
+	BaseQty      float64
+	BaseUnit     string
+	CostPerUnit  float64
+	LineCost     float64
+	IngredientID int
+	SubrecipeID  int
+}
+
+// RecipeCost is the fully expanded costing of a recipe.
+type RecipeCost struct {
+	RecipeID           int
+	Name               string
+	YieldQty           float64
+	YieldUnit          string
+	SecondaryYieldQty  float64
+	SecondaryYieldUnit string
+	Lines              []CostLine
+	TotalCost          float64
+	AsOf               string // price date; empty means current prices
+}
+
+// CostPerYieldUnit returns the cost of one primary yield unit.
+func (rc *RecipeCost) CostPerYieldUnit() float64 {
+	if rc.YieldQty <= 0 {
+		return 0
+	}
+	return rc.TotalCost / rc.YieldQty
+}
+
+// CostPerSecondaryUnit returns the cost of one secondary yield unit, if the
+// recipe has a secondary yield.
+func (rc *RecipeCost) CostPerSecondaryUnit() (float64, bool) {
+	if rc.SecondaryYieldQty <= 0 || rc.SecondaryYieldUnit == "" {
+		return 0, false
+	}
+	return rc.TotalCost / rc.SecondaryYieldQty, true
+}
+
+// Coster walks the recipe_subrecipes graph to any depth and prices every
+// level against its own yield. Results are memoised, so one Coster can be
+// reused to cost many recipes that share subrecipes.
+//
+// By default ingredients are priced from ingredient_prices as of today; a
+// Coster from NewCosterAsOf prices them as of another date.
+type Coster struct {
+	store       Store
+	asOf        string
+	cache       map[int]*RecipeCost
+	ingredients map[int]Ingredient
+	visiting    map[int]bool
+	path        []string
+}
+
+// NewCoster returns a Coster reading from db.
+func NewCoster(db *sql.DB) *Coster {
+	return NewStoreCoster(NewSQLStore(db))
+}
+
+// NewCosterAsOf returns a Coster that prices ingredients as they were on
+// date (YYYY-MM-DD).
+func NewCosterAsOf(db *sql.DB, date string) *Coster {
+	c := NewCoster(db)
+	c.asOf = date
+	return c
+}
+
+// NewStoreCoster returns a Coster reading from any Store.
+func NewStoreCoster(s Store) *Coster {
+	return &Coster{
+		store:       s,
+		cache:       make(map[int]*RecipeCost),
+		ingredients: make(map[int]Ingredient),
+		visiting:    make(map[int]bool),
+	}
+}
+
+// AsOf makes c price ingredients as they were on date (YYYY-MM-DD); an
+// empty date means current prices. Call it before costing anything.
+func (c *Coster) AsOf(date string) *Coster {
+	c.asOf = date
+	return c
+}
+
+// CostRecipe is a convenience wrapper for costing a single recipe.
+func CostRecipe(db *sql.DB, recipeID int) (*RecipeCost, error) {
+	return NewCoster(db).Cost(recipeID)
+}
+
+// Cost returns the full costing for recipeID.
+func (c *Coster) Cost(recipeID int) (*RecipeCost, error) {
+	if rc, ok := c.cache[recipeID]; ok {
+		return rc, nil
+	}
+
+	r, err := c.store.Recipe(recipeID)
+	if err != nil {
+		return nil, err
+	}
+	rc := &RecipeCost{
+		RecipeID:           r.ID,
+		Name:               r.Name,
+		YieldQty:           r.YieldQty,
+		YieldUnit:          r.YieldUnit,
+		SecondaryYieldQty:  r.SecondaryYieldQty,
+		SecondaryYieldUnit: r.SecondaryYieldUnit,
+		AsOf:               c.asOf,
+	}
+
+	if c.visiting[recipeID] {
+		return nil, fmt.Errorf("subrecipe cycle detected: %s → %s",
+			strings.Join(c.path, " → "), rc.Name)
+	}
+	c.visiting[recipeID] = true
+	c.path = append(c.path, rc.Name)
+	defer func() {
+		delete(c.visiting, recipeID)
+		c.path = c.path[:len(c.path)-1]
+	}()
+
+	lines, err := c.store.Lines(recipeID)
+	if err != nil {
+		return nil, fmt.Errorf("loading lines for %s: %w", rc.Name, err)
+	}
+	for _, line := range lines {
+		var l CostLine
+		if line.Type == "subrecipe" {
+			l, err = c.subrecipeLine(rc, line)
+		} else {
+			l, err = c.ingredientLine(rc, line)
+		}
+		if err != nil {
+			return nil, err
+		}
+		rc.Lines = append(rc.Lines, l)
+		rc.TotalCost += l.LineCost
+	}
+
+	c.cache[recipeID] = rc
+	return rc, nil
+}
+
+func (c *Coster) ingredientLine(rc *RecipeCost, line Line) (CostLine, error) {
+	l := CostLine{
+		Type:         "ingredient",
+		Name:         line.Name,
+		Qty:          line.Qty,
+		Unit:         line.Unit,
+		IngredientID: line.IngredientID,
+	}
+
+	ing, ok := c.ingredients[line.IngredientID]
+	if !ok {
+		var err error
+		if ing, err = c.store.Ingredient(line.IngredientID); err != nil {
+			return l, fmt.Errorf("%s: %w", rc.Name, err)
+		}
+		c.ingredients[line.IngredientID] = ing
+	}
+	l.BaseUnit = ing.Unit
+
+	date := c.asOf
+	if date == "" {
+		date = Today()
+	}
+	var err error
+	l.CostPerUnit, err = c.store.PriceAsOf(l.IngredientID, date)
+	if err != nil {
+		return l, fmt.Errorf("%s: ingredient %s: %w", rc.Name, l.Name, err)
+	}
+	l.BaseQty, err = ConvertQty(c.store, l.IngredientID, l.Qty, l.Unit, l.BaseUnit)
+	if err != nil {
+		return l, fmt.Errorf("%s: ingredient %s: %w", rc.Name, l.Name, err)
+	}
+	l.LineCost = l.BaseQty * l.CostPerUnit
+	return l, nil
+}
+
+func (c *Coster) subrecipeLine(rc *RecipeCost, line Line) (CostLine, error) {
+	l := CostLine{
+		Type:        "subrecipe",
+		Name:        line.Name,
+		Qty:         line.Qty,
+		Unit:        line.Unit,
+		SubrecipeID: line.SubrecipeID,
+	}
+
+	sub, err := c.Cost(l.SubrecipeID)
+	if err != nil {
+		return l, err
+	}
+	if sub.YieldQty <= 0 {
+		return l, fmt.Errorf("subrecipe %s has no positive yield (used in %s)", sub.Name, rc.Name)
+	}
+
+	l.BaseQty, err = ConvertYieldQty(sub, l.Qty, l.Unit)
+	if err != nil {
+		return l, fmt.Errorf("%s: subrecipe line: %w", rc.Name, err)
+	}
+	l.BaseUnit = sub.YieldUnit
+	l.CostPerUnit = sub.CostPerYieldUnit()
+	l.LineCost = l.BaseQty * l.CostPerUnit
+	return l, nil
+}
@@ -0,0 +1,305 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+// memStore is a Store held in maps, so the engine can be tested without
+// SQLite. lineCalls counts Lines calls per recipe.
+type memStore struct {
+	recipes     map[int]Recipe
+	lines       map[int][]Line
+	ingredients map[int]Ingredient
+	conversions map[int][]Conversion
+	lineCalls   map[int]int
+}
+
+func (s *memStore) Recipes() ([]Recipe, error) {
+	var list []Recipe
+	for _, r := range s.recipes {
+		list = append(list, r)
+	}
+	return list, nil
+}
+
+func (s *memStore) Recipe(id int) (Recipe, error) {
+	r, ok := s.recipes[id]
+	if !ok {
+		return r, fmt.Errorf("recipe with ID %d %w", id, ErrNotFound)
+	}
+	return r, nil
+}
+
+func (s *memStore) RecipeByName(name string) (Recipe, error) {
+	for _, r := range s.recipes {
+		if strings.EqualFold(r.Name, name) {
+			return r, nil
+		}
+	}
+	return Recipe{}, fmt.Errorf("recipe %s %w", name, ErrNotFound)
+}
+
+func (s *memStore) Lines(recipeID int) ([]Line, error) {
+	s.lineCalls[recipeID]++
+	return s.lines[recipeID], nil
+}
+
+func (s *memStore) Ingredients() ([]Ingredient, error) {
+	var list []Ingredient
+	for _, ing := range s.ingredients {
+		list = append(list, ing)
+	}
+	return list, nil
+}
+
+func (s *memStore) Ingredient(id int) (Ingredient, error) {
+	ing, ok := s.ingredients[id]
+	if !ok {
+		return ing, fmt.Errorf("ingredient with ID %d %w", id, ErrNotFound)
+	}
+	return ing, nil
+}
+
+func (s *memStore) Conversions(ingredientID int) ([]Conversion, error) {
+	return s.conversions[ingredientID], nil
+}
+
+func (s *memStore) PriceAsOf(ingredientID int, date string) (float64, error) {
+	ing, err := s.Ingredient(ingredientID)
+	return ing.CostPerUnit, err
+}
+
+func ingLine(id int, name string, qty float64, unit string) Line {
+	return Line{Type: LineIngredient, IngredientID: id, Name: name, Qty: qty, Unit: unit}
+}
+
+func subLine(id int, name string, qty float64, unit string) Line {
+	return Line{Type: LineSubrecipe, SubrecipeID: id, Name: name, Qty: qty, Unit: unit}
+}
+
+// testKitchen is a small pastry section:
+//
+//	SUB Dough (2 kg)           = 1 kg flour + 500 g butter       = 7.00
+//	BULK Tart Shells (10 piece) = 1 kg SUB Dough + 120 g egg     = 4.50
+//	DISH Tart (1 portion)      = 2 piece shells + 0.1 kg butter  = 1.90
+//	SUB Cookies (1 kg = 40 piece) = 1 kg flour                   = 2.00
+//	DISH Cookie Plate (1 portion) = 4 piece SUB Cookies          = 0.20
+func testKitchen() *memStore {
+	return &memStore{
+		ingredients: map[int]Ingredient{
+			1: {ID: 1, Name: "Flour", Unit: "kg", CostPerUnit: 2},
+			2: {ID: 2, Name: "Butter", Unit: "kg", CostPerUnit: 10},
+			3: {ID: 3, Name: "Egg", Unit: "piece", CostPerUnit: 0.5},
+		},
+		conversions: map[int][]Conversion{
+			3: {{IngredientID: 3, FromQty: 1, FromUnit: "piece", ToQty: 60, ToUnit: "g"}},
+		},
+		recipes: map[int]Recipe{
+			10: {ID: 10, Name: "SUB Dough", YieldQty: 2, YieldUnit: "kg"},
+			11: {ID: 11, Name: "BULK Tart Shells", YieldQty: 10, YieldUnit: "piece"},
+			12: {ID: 12, Name: "DISH Tart", YieldQty: 1, YieldUnit: "portion"},
+			13: {ID: 13, Name: "SUB Cookies", YieldQty: 1, YieldUnit: "kg", SecondaryYieldQty: 40, SecondaryYieldUnit: "piece"},
+			14: {ID: 14, Name: "DISH Cookie Plate", YieldQty: 1, YieldUnit: "portion"},
+		},
+		lines: map[int][]Line{
+			10: {ingLine(1, "Flour", 1, "kg"), ingLine(2, "Butter", 500, "g")},
+			11: {ingLine(3, "Egg", 120, "g"), subLine(10, "SUB Dough", 1, "kg")},
+			12: {ingLine(2, "Butter", 0.1, "kg"), subLine(11, "BULK Tart Shells", 2, "piece")},
+			13: {ingLine(1, "Flour", 1, "kg")},
+			14: {subLine(13, "SUB Cookies", 4, "piece")},
+		},
+		lineCalls: make(map[int]int),
+	}
+}
+
+func near(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+func TestCosterCost(t *testing.T) {
+	tests := []struct {
+		name         string
+		recipeID     int
+		total        float64
+		perYieldUnit float64
+	}{
+		{"ingredients in other units", 10, 7, 3.5},
+		{"subrecipe divided by its yield", 11, 4.5, 0.45},
+		{"two levels of subrecipes", 12, 1.9, 1.9},
+		{"subrecipe in its secondary unit", 14, 0.2, 0.2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc, err := NewStoreCoster(testKitchen()).Cost(tt.recipeID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !near(rc.TotalCost, tt.total) {
+				t.Errorf("total = %v, want %v", rc.TotalCost, tt.total)
+			}
+			if !near(rc.CostPerYieldUnit(), tt.perYieldUnit) {
+				t.Errorf("cost per %s = %v, want %v", rc.YieldUnit, rc.CostPerYieldUnit(), tt.perYieldUnit)
+			}
+		})
+	}
+}
+
+func TestCosterLines(t *testing.T) {
+	rc, err := NewStoreCoster(testKitchen()).Cost(11)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []CostLine{
+		{Type: LineIngredient, Name: "Egg", Qty: 120, Unit: "g", BaseQty: 2, BaseUnit: "piece", CostPerUnit: 0.5, LineCost: 1, IngredientID: 3},
+		{Type: LineSubrecipe, Name: "SUB Dough", Qty: 1, Unit: "kg", BaseQty: 1, BaseUnit: "kg", CostPerUnit: 3.5, LineCost: 3.5, SubrecipeID: 10},
+	}
+	if len(rc.Lines) != len(want) {
+		t.Fatalf("got %d lines, want %d", len(rc.Lines), len(want))
+	}
+	for i, w := range want {
+		g := rc.Lines[i]
+		if g.Type != w.Type || g.Name != w.Name || g.BaseUnit != w.BaseUnit ||
+			!near(g.BaseQty, w.BaseQty) || !near(g.CostPerUnit, w.CostPerUnit) || !near(g.LineCost, w.LineCost) ||
+			g.IngredientID != w.IngredientID || g.SubrecipeID != w.SubrecipeID {
+			t.Errorf("line %d = %+v, want %+v", i, g, w)
+		}
+	}
+}
+
+func TestCosterMemoises(t *testing.T) {
+	s := testKitchen()
+	c := NewStoreCoster(s)
+
+	first, err := c.Cost(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []int{12, 11, 10} {
+		if _, err := c.Cost(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	again, _ := c.Cost(10)
+
+	if first != again {
+		t.Error("second Cost of SUB Dough returned a new RecipeCost")
+	}
+	for id, n := range s.lineCalls {
+		if n != 1 {
+			t.Errorf("Lines(%d) called %d times, want 1", id, n)
+		}
+	}
+}
+
+func TestCosterErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		recipes []Recipe
+		lines   map[int][]Line
+		is      error  // errors.Is target, if any
+		message string // substring of the error
+	}{
+		{
+			name: "cycle",
+			recipes: []Recipe{
+				{ID: 20, Name: "SUB A", YieldQty: 1, YieldUnit: "kg"},
+				{ID: 21, Name: "SUB B", YieldQty: 1, YieldUnit: "kg"},
+			},
+			lines:   map[int][]Line{20: {subLine(21, "SUB B", 1, "kg")}, 21: {subLine(20, "SUB A", 1, "kg")}},
+			message: "subrecipe cycle detected: SUB A → SUB B → SUB A",
+		},
+		{
+			name:    "recipe using itself",
+			recipes: []Recipe{{ID: 20, Name: "SUB A", YieldQty: 1, YieldUnit: "kg"}},
+			lines:   map[int][]Line{20: {subLine(20, "SUB A", 1, "kg")}},
+			message: "subrecipe cycle detected: SUB A → SUB A",
+		},
+		{
+			name:    "ingredient unit without a conversion",
+			recipes: []Recipe{{ID: 20, Name: "SUB A", YieldQty: 1, YieldUnit: "kg"}},
+			lines:   map[int][]Line{20: {ingLine(1, "Flour", 1, "piece")}},
+			is:      ErrNoConversion,
+			message: "SUB A: ingredient Flour",
+		},
+		{
+			name:    "ingredient line without a unit",
+			recipes: []Recipe{{ID: 20, Name: "SUB A", YieldQty: 1, YieldUnit: "kg"}},
+			lines:   map[int][]Line{20: {ingLine(1, "Flour", 1, "")}},
+			is:      ErrNoConversion,
+			message: "SUB A: ingredient Flour",
+		},
+		{
+			name:    "subrecipe unit outside its yields",
+			recipes: []Recipe{{ID: 20, Name: "DISH A", YieldQty: 1, YieldUnit: "portion"}},
+			lines:   map[int][]Line{20: {subLine(10, "SUB Dough", 1, "portion")}},
+			is:      ErrNoConversion,
+			message: "SUB Dough is measured in kg, cannot use portion",
+		},
+		{
+			name: "subrecipe without a yield",
+			recipes: []Recipe{
+				{ID: 20, Name: "DISH A", YieldQty: 1, YieldUnit: "portion"},
+				{ID: 21, Name: "SUB Empty", YieldQty: 0, YieldUnit: "kg"},
+			},
+			lines:   map[int][]Line{20: {subLine(21, "SUB Empty", 1, "kg")}},
+			message: "subrecipe SUB Empty has no positive yield",
+		},
+		{
+			name:    "missing ingredient",
+			recipes: []Recipe{{ID: 20, Name: "SUB A", YieldQty: 1, YieldUnit: "kg"}},
+			lines:   map[int][]Line{20: {ingLine(99, "Ghost", 1, "kg")}},
+			is:      ErrNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := testKitchen()
+			for _, r := range tt.recipes {
+				s.recipes[r.ID] = r
+			}
+			for id, l := range tt.lines {
+				s.lines[id] = l
+			}
+
+			_, err := NewStoreCoster(s).Cost(tt.recipes[0].ID)
+			if err == nil {
+				t.Fatal("no error")
+			}
+			if tt.is != nil && !errors.Is(err, tt.is) {
+				t.Errorf("error %q is not %v", err, tt.is)
+			}
+			if !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("error %q does not mention %q", err, tt.message)
+			}
+		})
+	}
+}
+
+func TestCosterRecoversAfterError(t *testing.T) {
+	s := testKitchen()
+	s.recipes[20] = Recipe{ID: 20, Name: "SUB Broken", YieldQty: 1, YieldUnit: "kg"}
+	s.lines[20] = []Line{ingLine(1, "Flour", 1, "piece")}
+	c := NewStoreCoster(s)
+
+	if _, err := c.Cost(20); err == nil {
+		t.Fatal("costing SUB Broken: no error")
+	}
+	// A failed recipe must not leave the cycle check thinking it is still
+	// being costed.
+	if _, err := c.Cost(12); err != nil {
+		t.Errorf("costing DISH Tart after a failure: %v", err)
+	}
+}
+
+func TestExpandRefusesRecipeWithoutYield(t *testing.T) {
+	s := testKitchen()
+	s.recipes[20] = Recipe{ID: 20, Name: "DISH Empty", YieldQty: 0, YieldUnit: "portion"}
+	s.lines[20] = []Line{ingLine(1, "Flour", 1, "kg")}
+
+	_, err := NewStoreCoster(s).Forecast([]Dish{{RecipeID: 20, Portions: 10}})
+	if err == nil || !strings.Contains(err.Error(), "DISH Empty has no positive yield") {
+		t.Errorf("forecast error = %v, want DISH Empty has no positive yield", err)
+	}
+}
@@ -0,0 +1,188 @@
+package internal
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrSubrecipeCycle is returned (wrapped) when a subrecipe line would make
+// a recipe depend on itself.
+var ErrSubrecipeCycle = errors.New("subrecipe cycle")
+
+// subrecipeGraph is recipe_subrecipes as adjacency lists, parent → children,
+// with recipe names for reporting.
+type subrecipeGraph struct {
+	children map[int][]int
+	names    map[int]string
+}
+
+func loadSubrecipeGraph(db *sql.DB) (*subrecipeGraph, error) {
+	g := &subrecipeGraph{children: make(map[int][]int), names: make(map[int]string)}
+
+	rows, err := db.Query(`SELECT id, name FROM recipes`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var id int
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		g.names[id] = name
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = db.Query(`SELECT DISTINCT recipe_id, subrecipe_id FROM recipe_subrecipes ORDER BY recipe_id, subrecipe_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var parent, child int
+		if err := rows.Scan(&parent, &child); err != nil {
+			return nil, err
+		}
+		g.children[parent] = append(g.children[parent], child)
+	}
+	return g, rows.Err()
+}
+
+// path returns the recipe IDs from "from" down to "to", or nil when "to" is
+// not reachable. Breadth-first, so the path is a shortest one.
+func (g *subrecipeGraph) path(from, to int) []int {
+	prev := map[int]int{from: from}
+	queue := []int{from}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if id == to {
+			var p []int
+			for ; id != from; id = prev[id] {
+				p = append(p, id)
+			}
+			p = append(p, from)
+			for i, j := 0, len(p)-1; i < j; i, j = i+1, j-1 {
+				p[i], p[j] = p[j], p[i]
+			}
+			return p
+		}
+		for _, c := range g.children[id] {
+			if _, ok := prev[c]; !ok {
+				prev[c] = id
+				queue = append(queue, c)
+			}
+		}
+	}
+	return nil
+}
+
+func (g *subrecipeGraph) pathString(ids []int) string {
+	names := make([]string, len(ids))
+	for i, id := range ids {
+		names[i] = g.names[id]
+	}
+	return strings.Join(names, " → ")
+}
+
+// CheckSubrecipe reports whether recipeID can use subID as a subrecipe. It
+// returns an ErrSubrecipeCycle error showing the loop when subID already
+// depends, at any depth, on recipeID (or is recipeID).
+func CheckSubrecipe(db *sql.DB, recipeID, subID int) error {
+	g, err := loadSubrecipeGraph(db)
+	if err != nil {
+		return err
+	}
+	p := g.path(subID, recipeID)
+	if p == nil {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrSubrecipeCycle, g.pathString(append([]int{recipeID}, p...)))
+}
+
+// FindSubrecipeCycles returns the loops in recipe_subrecipes, each as the
+// recipe names along it with the first repeated at the end (A → B → A),
+// starting from its alphabetically first recipe. Any database with a loop
+// yields at least one; where loops share recipes, breaking the reported
+// ones and checking again finds the rest.
+func FindSubrecipeCycles(db *sql.DB) ([][]string, error) {
+	g, err := loadSubrecipeGraph(db)
+	if err != nil {
+		return nil, err
+	}
+
+	const (
+		unvisited = iota
+		onStack
+		finished
+	)
+	state := make(map[int]int)
+	var stack []int
+	seen := make(map[string]bool)
+	var cycles [][]string
+
+	var visit func(id int)
+	visit = func(id int) {
+		state[id] = onStack
+		stack = append(stack, id)
+		for _, c := range g.children[id] {
+			switch state[c] {
+			case unvisited:
+				visit(c)
+			case onStack:
+				// Back edge: the stack from c to here is a loop.
+				start := len(stack) - 1
+				for stack[start] != c {
+					start--
+				}
+				cycle := g.canonicalCycle(stack[start:])
+				key := strings.Join(cycle, "\x00")
+				if !seen[key] {
+					seen[key] = true
+					cycles = append(cycles, cycle)
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[id] = finished
+	}
+
+	ids := make([]int, 0, len(g.names))
+	for id := range g.names {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		if state[id] == unvisited {
+			visit(id)
+		}
+	}
+
+	sort.Slice(cycles, func(i, j int) bool {
+		return strings.Join(cycles[i], " ") < strings.Join(cycles[j], " ")
+	})
+	return cycles, nil
+}
+
+// canonicalCycle rotates a loop to start at its alphabetically first recipe
+// and closes it by repeating that recipe at the end.
+func (g *subrecipeGraph) canonicalCycle(ids []int) []string {
+	first := 0
+	for i, id := range ids {
+		if g.names[id] < g.names[ids[first]] {
+			first = i
+		}
+	}
+	names := make([]string, 0, len(ids)+1)
+	for i := range ids {
+		names = append(names, g.names[ids[(first+i)%len(ids)]])
+	}
+	return append(names, names[0])
+}
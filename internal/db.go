@@ -2,84 +2,92 @@ package internal
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
 
 	_ "modernc.org/sqlite" // SQLite driver
 )
 
-const DBPath = "db/chefops.db"
+// ErrNoDatabase is returned (wrapped) when opening a database file that
+// does not exist. Only the Create functions make new databases, so a
+// mistyped --db path fails instead of starting an empty one.
+var ErrNoDatabase = errors.New("database does not exist")
 
-// OpenDB opens the SQLite database with foreign keys enabled.
+// OpenDB opens the SQLite database with foreign keys enabled, applies any
+// pending schema migrations.
 func OpenDB() (*sql.DB, error) {
-	dsn := fmt.Sprintf("file:%s?_pragma=foreign_keys(ON)", DBPath)
-	return sql.Open("sqlite", dsn)
+	return OpenDBAt(CurrentConfig().DBPath)
 }
 
-// LoadRecipeMetadata loads metadata for a recipe by ID
-func LoadRecipeMetadata(recipeID int) (*RecipeMetadata, error) {
-	db, err := OpenDB()
+// OpenDBAt is OpenDB for the database at path rather than the configured
+// one.
+func OpenDBAt(path string) (*sql.DB, error) {
+	db, err := OpenRawDBAt(path)
 	if err != nil {
 		return nil, err
 	}
-	defer db.Close()
+	return prepareDB(db, path)
+}
 
-	var metadataStr *string
-	query := "SELECT metadata FROM recipes WHERE id = ?"
-	err = db.QueryRow(query, recipeID).Scan(&metadataStr)
+// CreateDBAt is OpenDBAt, creating the database (and its directory) when
+// it does not exist yet.
+func CreateDBAt(path string) (*sql.DB, error) {
+	db, err := CreateRawDBAt(path)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("recipe with ID %d not found", recipeID)
-		}
 		return nil, err
 	}
+	return prepareDB(db, path)
+}
 
-	var metadataStrVal string
-	if metadataStr != nil {
-		metadataStrVal = *metadataStr
+// prepareDB migrates a freshly opened database, closing it on failure.
+func prepareDB(db *sql.DB, path string) (*sql.DB, error) {
+	if _, err := Migrate(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrating %s: %w", path, err)
 	}
-
-	return LoadMetadata(metadataStrVal)
+	return db, nil
 }
 
-// SaveRecipeMetadata saves metadata for a recipe by ID
-func SaveRecipeMetadata(recipeID int, meta *RecipeMetadata) error {
-	db, err := OpenDB()
-	if err != nil {
-		return err
-	}
-	defer db.Close()
+// OpenRawDB opens the SQLite database at the configured path without
+// touching its schema. It is used by `chefops db` to inspect or migrate
+// explicitly.
+func OpenRawDB() (*sql.DB, error) {
+	return OpenRawDBAt(CurrentConfig().DBPath)
+}
 
-	metadataStr, err := SaveMetadataToJSON(meta)
-	if err != nil {
-		return err
+// OpenRawDBAt is OpenRawDB for the database at path. The file must exist.
+func OpenRawDBAt(path string) (*sql.DB, error) {
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%s: %w", path, ErrNoDatabase)
+	} else if err != nil {
+		return nil, err
 	}
-
-	query := "UPDATE recipes SET metadata = ? WHERE id = ?"
-	_, err = db.Exec(query, metadataStr, recipeID)
-	if err != nil {
-		return err
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory, not a database", path)
 	}
-
-	return nil
+	return openSQLite(path)
 }
 
-// GetRecipeIDByName gets recipe ID by name
-func GetRecipeIDByName(name string) (int, error) {
-	db, err := OpenDB()
-	if err != nil {
-		return 0, err
-	}
-	defer db.Close()
+// CreateRawDB is OpenRawDB, creating an empty database file (and its
+// directory) when there is none. `chefops db migrate` uses it to set up a
+// new database.
+func CreateRawDB() (*sql.DB, error) {
+	return CreateRawDBAt(CurrentConfig().DBPath)
+}
 
-	var id int
-	query := "SELECT id FROM recipes WHERE name = ?"
-	err = db.QueryRow(query, name).Scan(&id)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return 0, fmt.Errorf("recipe '%s' not found", name)
-		}
-		return 0, err
+// CreateRawDBAt is CreateRawDB for the database at path.
+func CreateRawDBAt(path string) (*sql.DB, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("creating database directory: %w", err)
 	}
+	return openSQLite(path)
+}
 
-	return id, nil
+func openSQLite(path string) (*sql.DB, error) {
+	dsn := fmt.Sprintf("file:%s?_pragma=foreign_keys(ON)", path)
+	return sql.Open("sqlite", dsn)
 }
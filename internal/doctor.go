@@ -0,0 +1,770 @@
+package internal
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Finding is one problem reported by Diagnose.
+type Finding struct {
+	Check    string `json:"check"`
+	Severity string `json:"severity"` // "error" or "warning"
+	Subject  string `json:"subject"`
+	Message  string `json:"message"`
+	Fix      string `json:"fix,omitempty"` // what --fix does; empty when it needs a person
+
+	repair func(db *sql.DB) error
+}
+
+// Fixable reports whether Repair can resolve the finding safely.
+func (f *Finding) Fixable() bool {
+	return f.repair != nil
+}
+
+// Severities of a Finding.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Diagnose scans the database for integrity and sanity problems. It does
+// not change anything; see Repair.
+//
+// The database should be opened with OpenRawDB, so schema drift and
+// pending migrations are seen as they are.
+func Diagnose(db *sql.DB) ([]*Finding, error) {
+	pending, err := PendingMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+	if len(pending) > 0 {
+		// Everything below assumes the current schema.
+		return []*Finding{pendingMigrationsFinding(pending)}, nil
+	}
+
+	checks := []func(*sql.DB) ([]*Finding, error){
+		checkSchemaDrift,
+		checkYields,
+		checkLineUnits,
+		checkIngredientCosts,
+		checkOrphanIngredients,
+		checkDuplicateNames,
+		checkEmptyRecipes,
+		checkConversions,
+		checkSubrecipeCycles,
+	}
+	var findings []*Finding
+	for _, check := range checks {
+		fs, err := check(db)
+		if err != nil {
+			return nil, err
+		}
+		findings = append(findings, fs...)
+	}
+	return findings, nil
+}
+
+// Repair applies the safe fix of every fixable finding and returns the ones
+// it fixed. It stops at the first fix that fails.
+func Repair(db *sql.DB, findings []*Finding) ([]*Finding, error) {
+	var fixed []*Finding
+	for _, f := range findings {
+		if f.repair == nil {
+			continue
+		}
+		if err := f.repair(db); err != nil {
+			return fixed, fmt.Errorf("%s: %s: %w", f.Check, f.Subject, err)
+		}
+		fixed = append(fixed, f)
+	}
+	return fixed, nil
+}
+
+// ------------------------------------------------------------
+// schema
+// ------------------------------------------------------------
+
+func pendingMigrationsFinding(pending []Migration) *Finding {
+	names := make([]string, len(pending))
+	for i, m := range pending {
+		names[i] = fmt.Sprintf("%04d_%s", m.Version, m.Name)
+	}
+	return &Finding{
+		Check:    "schema",
+		Severity: SeverityError,
+		Subject:  "schema_migrations",
+		Message:  fmt.Sprintf("%d pending migration(s): %s; other checks skipped", len(pending), strings.Join(names, ", ")),
+		Fix:      "apply pending migrations",
+		repair: func(db *sql.DB) error {
+			_, err := Migrate(db)
+			return err
+		},
+	}
+}
+
+var (
+	createTableRe = regexp.MustCompile(`(?is)CREATE\s+TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?(\w+)\s*\((.*?)\);`)
+	alterColumnRe = regexp.MustCompile(`(?i)ALTER\s+TABLE\s+(\w+)\s+ADD\s+COLUMN\s+(\w+)`)
+	createViewRe  = regexp.MustCompile(`(?is)CREATE\s+VIEW\s+(?:IF\s+NOT\s+EXISTS\s+)?(\w+)\s+AS\s.*?;`)
+	dropViewRe    = regexp.MustCompile(`(?i)DROP\s+VIEW\s+(?:IF\s+EXISTS\s+)?(\w+)\s*;`)
+	sqlCommentRe  = regexp.MustCompile(`--[^\n]*`)
+	leadingWordRe = regexp.MustCompile(`^\w+`)
+)
+
+// expectedSchema reads the tables, columns and views the embedded
+// migrations create. Later view definitions replace earlier ones, and a
+// view dropped without being created again is not expected.
+func expectedSchema() (map[string][]string, map[string]string, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tables := make(map[string][]string)
+	views := make(map[string]string)
+	for _, m := range migrations {
+		text := sqlCommentRe.ReplaceAllString(m.SQL, "")
+		for _, match := range createTableRe.FindAllStringSubmatch(text, -1) {
+			tables[match[1]] = append(tables[match[1]], tableColumnNames(match[2])...)
+		}
+		for _, match := range alterColumnRe.FindAllStringSubmatch(text, -1) {
+			tables[match[1]] = append(tables[match[1]], match[2])
+		}
+		// Drops and creates in file order: "DROP VIEW x; CREATE VIEW x" keeps x.
+		creates := createViewRe.FindAllStringSubmatchIndex(text, -1)
+		drops := dropViewRe.FindAllStringSubmatchIndex(text, -1)
+		for len(creates) > 0 || len(drops) > 0 {
+			if len(drops) > 0 && (len(creates) == 0 || drops[0][0] < creates[0][0]) {
+				delete(views, text[drops[0][2]:drops[0][3]])
+				drops = drops[1:]
+				continue
+			}
+			m := creates[0]
+			views[text[m[2]:m[3]]] = strings.TrimSuffix(text[m[0]:m[1]], ";")
+			creates = creates[1:]
+		}
+	}
+	return tables, views, nil
+}
+
+// tableColumnNames picks the column names out of a CREATE TABLE body,
+// skipping table constraints.
+func tableColumnNames(body string) []string {
+	var cols []string
+	depth, start := 0, 0
+	parts := []string{}
+	for i, r := range body {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, body[start:i])
+				start = i + 1
+			}
+		}
+	}
+	parts = append(parts, body[start:])
+
+	for _, p := range parts {
+		name := leadingWordRe.FindString(strings.TrimSpace(p))
+		switch strings.ToUpper(name) {
+		case "", "FOREIGN", "UNIQUE", "PRIMARY", "CHECK", "CONSTRAINT":
+			continue
+		}
+		cols = append(cols, name)
+	}
+	return cols
+}
+
+func normalizeSQL(s string) string {
+	s = sqlCommentRe.ReplaceAllString(s, "")
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+func checkSchemaDrift(db *sql.DB) ([]*Finding, error) {
+	var findings []*Finding
+
+	all, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	version, err := SchemaVersion(db)
+	if err != nil {
+		return nil, err
+	}
+	if latest := all[len(all)-1].Version; version > latest {
+		findings = append(findings, &Finding{
+			Check:    "schema",
+			Severity: SeverityWarning,
+			Subject:  "schema_migrations",
+			Message:  fmt.Sprintf("database is at schema version %d, newer than this chefops (%d)", version, latest),
+		})
+	}
+
+	tables, views, err := expectedSchema()
+	if err != nil {
+		return nil, err
+	}
+
+	tableNames := make([]string, 0, len(tables))
+	for t := range tables {
+		tableNames = append(tableNames, t)
+	}
+	sort.Strings(tableNames)
+	for _, t := range tableNames {
+		exists, err := tableExists(db, t)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			findings = append(findings, &Finding{
+				Check: "schema", Severity: SeverityError, Subject: t,
+				Message: "table is missing",
+			})
+			continue
+		}
+		have, err := tableColumns(db, t)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range tables[t] {
+			if !have[c] {
+				findings = append(findings, &Finding{
+					Check: "schema", Severity: SeverityError, Subject: t + "." + c,
+					Message: "column is missing",
+				})
+			}
+		}
+	}
+
+	viewNames := make([]string, 0, len(views))
+	for v := range views {
+		viewNames = append(viewNames, v)
+	}
+	sort.Strings(viewNames)
+	for _, v := range viewNames {
+		want := views[v]
+		var have sql.NullString
+		err := db.QueryRow(`SELECT sql FROM sqlite_master WHERE type = 'view' AND name = ?`, v).Scan(&have)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+
+		msg := ""
+		switch {
+		case err == sql.ErrNoRows:
+			msg = "view is missing"
+		case normalizeSQL(have.String) != normalizeSQL(want):
+			msg = "view definition differs from the migrations"
+		default:
+			continue
+		}
+		name, stmt := v, want
+		findings = append(findings, &Finding{
+			Check:    "schema",
+			Severity: SeverityError,
+			Subject:  v,
+			Message:  msg,
+			Fix:      "recreate the view",
+			repair: func(db *sql.DB) error {
+				_, err := db.Exec(fmt.Sprintf("DROP VIEW IF EXISTS %s;\n%s;", name, stmt))
+				return err
+			},
+		})
+	}
+	return findings, nil
+}
+
+// ------------------------------------------------------------
+// recipes
+// ------------------------------------------------------------
+
+func checkYields(db *sql.DB) ([]*Finding, error) {
+	rows, err := db.Query(`SELECT name, yield_qty, yield_unit FROM recipes WHERE yield_qty IS NULL OR yield_qty <= 0 ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var findings []*Finding
+	for rows.Next() {
+		var name string
+		var qty sql.NullFloat64
+		var unit sql.NullString
+		if err := rows.Scan(&name, &qty, &unit); err != nil {
+			return nil, err
+		}
+		findings = append(findings, &Finding{
+			Check:    "yield",
+			Severity: SeverityError,
+			Subject:  name,
+			Message:  fmt.Sprintf("yield is %g %s; it must be positive to cost or scale the recipe", qty.Float64, unit.String),
+		})
+	}
+	return findings, rows.Err()
+}
+
+// subrecipeLine is one row of recipe_subrecipes.
+type subrecipeLine struct {
+	id, recipeID, subID int
+	qty                 float64
+	unit                string
+}
+
+// loadYieldRecipes returns every recipe's name and yields by ID.
+func loadYieldRecipes(q queryer) (map[int]*RecipeCost, error) {
+	rows, err := q.Query(`SELECT id, name, yield_qty, yield_unit, secondary_yield_qty, secondary_yield_unit FROM recipes`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	recipes := make(map[int]*RecipeCost)
+	for rows.Next() {
+		rc := &RecipeCost{}
+		var secQty sql.NullFloat64
+		var secUnit sql.NullString
+		if err := rows.Scan(&rc.RecipeID, &rc.Name, &rc.YieldQty, &rc.YieldUnit, &secQty, &secUnit); err != nil {
+			return nil, err
+		}
+		rc.SecondaryYieldQty = secQty.Float64
+		rc.SecondaryYieldUnit = secUnit.String
+		recipes[rc.RecipeID] = rc
+	}
+	return recipes, rows.Err()
+}
+
+func loadSubrecipeLines(q queryer) ([]subrecipeLine, error) {
+	rows, err := q.Query(`SELECT id, recipe_id, subrecipe_id, qty, COALESCE(unit, '') FROM recipe_subrecipes ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []subrecipeLine
+	for rows.Next() {
+		var l subrecipeLine
+		if err := rows.Scan(&l.id, &l.recipeID, &l.subID, &l.qty, &l.unit); err != nil {
+			return nil, err
+		}
+		lines = append(lines, l)
+	}
+	return lines, rows.Err()
+}
+
+// legacySubrecipeUnit returns the unit a subrecipe line saved by old
+// versions of `recipe add-subrecipe` should have. Those defaulted the unit
+// to the parent's yield unit (a "portion" of a sauce made by the kg) while
+// the quantity was always read in the subrecipe's yield unit. It reports
+// false for lines that convert as they are or carry some other unit.
+func legacySubrecipeUnit(parent, sub *RecipeCost, l subrecipeLine) (string, bool) {
+	if l.unit == "" || !SameUnit(l.unit, parent.YieldUnit) {
+		return "", false
+	}
+	if _, err := ConvertYieldQty(sub, l.qty, l.unit); err == nil {
+		return "", false
+	}
+	return sub.YieldUnit, true
+}
+
+func setSubrecipeUnit(lineID int, unit string) func(db *sql.DB) error {
+	return func(db *sql.DB) error {
+		_, err := db.Exec(`UPDATE recipe_subrecipes SET unit = ? WHERE id = ?`, unit, lineID)
+		return err
+	}
+}
+
+func checkLineUnits(db *sql.DB) ([]*Finding, error) {
+	var findings []*Finding
+
+	// Subrecipe lines
+	recipes, err := loadYieldRecipes(db)
+	if err != nil {
+		return nil, err
+	}
+	subLines, err := loadSubrecipeLines(db)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, l := range subLines {
+		parent, sub := recipes[l.recipeID], recipes[l.subID]
+		if parent == nil || sub == nil {
+			findings = append(findings, &Finding{
+				Check:    "line-units",
+				Severity: SeverityError,
+				Subject:  fmt.Sprintf("recipe_subrecipes #%d", l.id),
+				Message:  "refers to a recipe that does not exist",
+				Fix:      "delete the line",
+				repair:   deleteRow("recipe_subrecipes", l.id),
+			})
+			continue
+		}
+		subject := parent.Name + " → " + sub.Name
+		if l.unit == "" {
+			findings = append(findings, &Finding{
+				Check:    "line-units",
+				Severity: SeverityError,
+				Subject:  subject,
+				Message:  "subrecipe line has no unit",
+				Fix:      "set the unit to the subrecipe's yield unit (" + sub.YieldUnit + ")",
+				repair:   setSubrecipeUnit(l.id, sub.YieldUnit),
+			})
+			continue
+		}
+		if _, err := ConvertYieldQty(sub, l.qty, l.unit); err != nil {
+			f := &Finding{
+				Check:    "line-units",
+				Severity: SeverityError,
+				Subject:  subject,
+				Message:  err.Error(),
+			}
+			if unit, ok := legacySubrecipeUnit(parent, sub, l); ok {
+				f.Fix = "set the unit to the subrecipe's yield unit (" + unit + "); " +
+					parent.YieldUnit + " was the old default, the parent's yield unit"
+				f.repair = setSubrecipeUnit(l.id, unit)
+			}
+			findings = append(findings, f)
+		}
+	}
+
+	// Ingredient lines
+	type itemLine struct {
+		recipe, ingredient   string
+		ingredientID         int
+		qty                  float64
+		unit, ingredientUnit string
+	}
+	var items []itemLine
+	rows, err := db.Query(`
+		SELECT r.name, ing.name, ing.id, ri.qty, COALESCE(NULLIF(ri.unit, ''), ing.unit), ing.unit
+		FROM recipe_items ri
+		JOIN recipes r ON r.id = ri.recipe_id
+		JOIN ingredients ing ON ing.id = ri.ingredient_id
+		ORDER BY r.name, ing.name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var l itemLine
+		if err := rows.Scan(&l.recipe, &l.ingredient, &l.ingredientID, &l.qty, &l.unit, &l.ingredientUnit); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		items = append(items, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, l := range items {
+		if _, err := ConvertIngredientQty(db, l.ingredientID, l.qty, l.unit, l.ingredientUnit); err != nil {
+			findings = append(findings, &Finding{
+				Check:    "line-units",
+				Severity: SeverityError,
+				Subject:  l.recipe + " → " + l.ingredient,
+				Message:  err.Error(),
+			})
+		}
+	}
+	return findings, nil
+}
+
+func checkEmptyRecipes(db *sql.DB) ([]*Finding, error) {
+	rows, err := db.Query(`
+		SELECT r.name
+		FROM recipes r
+		WHERE NOT EXISTS (SELECT 1 FROM recipe_items ri WHERE ri.recipe_id = r.id)
+		  AND NOT EXISTS (SELECT 1 FROM recipe_subrecipes rs WHERE rs.recipe_id = r.id)
+		ORDER BY r.name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var findings []*Finding
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		findings = append(findings, &Finding{
+			Check:    "empty-recipes",
+			Severity: SeverityWarning,
+			Subject:  name,
+			Message:  "recipe has no ingredients or subrecipes",
+		})
+	}
+	return findings, rows.Err()
+}
+
+func checkSubrecipeCycles(db *sql.DB) ([]*Finding, error) {
+	cycles, err := FindSubrecipeCycles(db)
+	if err != nil {
+		return nil, err
+	}
+	var findings []*Finding
+	for _, c := range cycles {
+		findings = append(findings, &Finding{
+			Check:    "subrecipe-cycles",
+			Severity: SeverityError,
+			Subject:  c[0],
+			Message:  "subrecipe loop: " + strings.Join(c, " → "),
+		})
+	}
+	return findings, nil
+}
+
+// ------------------------------------------------------------
+// ingredients
+// ------------------------------------------------------------
+
+func checkIngredientCosts(db *sql.DB) ([]*Finding, error) {
+	rows, err := db.Query(`
+		SELECT ing.id, ing.name,
+		       (SELECT p.cost_per_unit FROM ingredient_prices p
+		        WHERE p.ingredient_id = ing.id AND p.cost_per_unit > 0
+		          AND p.effective_date <= ?
+		        ORDER BY p.effective_date DESC, p.id DESC LIMIT 1)
+		FROM ingredients ing
+		WHERE ing.cost_per_unit IS NULL OR ing.cost_per_unit <= 0
+		ORDER BY ing.name
+	`, Today())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var findings []*Finding
+	for rows.Next() {
+		var id int
+		var name string
+		var recorded sql.NullFloat64
+		if err := rows.Scan(&id, &name, &recorded); err != nil {
+			return nil, err
+		}
+		f := &Finding{
+			Check:    "ingredient-cost",
+			Severity: SeverityWarning,
+			Subject:  name,
+			Message:  "ingredient has no cost; recipes using it are undercosted",
+		}
+		if recorded.Valid {
+			ingID, cost := id, recorded.Float64
+			f.Fix = fmt.Sprintf("use the price in effect today (%.4f)", cost)
+			f.repair = func(db *sql.DB) error {
+				_, err := db.Exec(`UPDATE ingredients SET cost_per_unit = ? WHERE id = ?`, cost, ingID)
+				return err
+			}
+		}
+		findings = append(findings, f)
+	}
+	return findings, rows.Err()
+}
+
+func checkOrphanIngredients(db *sql.DB) ([]*Finding, error) {
+	rows, err := db.Query(`
+		SELECT ing.name
+		FROM ingredients ing
+		WHERE NOT EXISTS (SELECT 1 FROM recipe_items ri WHERE ri.ingredient_id = ing.id)
+		ORDER BY ing.name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var findings []*Finding
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		findings = append(findings, &Finding{
+			Check:    "orphan-ingredients",
+			Severity: SeverityWarning,
+			Subject:  name,
+			Message:  "ingredient is not used in any recipe",
+		})
+	}
+	return findings, rows.Err()
+}
+
+// nameKey reduces a name to letters and digits, lowercased, with a plural
+// "s" dropped from each word, so "Lobster Meat", "lobster-meat" and
+// "Lobster  Meats" compare equal.
+func nameKey(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, w := range words {
+		if len(w) > 3 && strings.HasSuffix(w, "s") && !strings.HasSuffix(w, "ss") {
+			words[i] = strings.TrimSuffix(w, "s")
+		}
+	}
+	return strings.Join(words, "")
+}
+
+func checkDuplicateNames(db *sql.DB) ([]*Finding, error) {
+	var findings []*Finding
+	for _, table := range []string{"ingredients", "recipes"} {
+		rows, err := db.Query(fmt.Sprintf(`SELECT name FROM %s ORDER BY name`, table))
+		if err != nil {
+			return nil, err
+		}
+		groups := make(map[string][]string)
+		var keys []string
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			k := nameKey(name)
+			if _, ok := groups[k]; !ok {
+				keys = append(keys, k)
+			}
+			groups[k] = append(groups[k], name)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+
+		for _, k := range keys {
+			names := groups[k]
+			if len(names) < 2 {
+				continue
+			}
+			findings = append(findings, &Finding{
+				Check:    "duplicate-names",
+				Severity: SeverityWarning,
+				Subject:  names[0],
+				Message:  fmt.Sprintf("near-identical %s: %s", table, strings.Join(quoteAll(names), ", ")),
+			})
+		}
+	}
+	return findings, nil
+}
+
+func quoteAll(names []string) []string {
+	q := make([]string, len(names))
+	for i, n := range names {
+		q[i] = fmt.Sprintf("%q", n)
+	}
+	return q
+}
+
+// ------------------------------------------------------------
+// conversions
+// ------------------------------------------------------------
+
+// checkConversions reports unusable ingredient_conversions rows and rows
+// that disagree with the standard units or the ingredient's other rows,
+// i.e. loops in the conversion graph that do not come back to the same
+// quantity.
+func checkConversions(db *sql.DB) ([]*Finding, error) {
+	type convRow struct {
+		id           int
+		ingredientID int
+		ingredient   string
+		step         ConversionStep
+	}
+	rows, err := db.Query(`
+		SELECT c.id, c.ingredient_id, ing.name, c.from_qty, c.from_unit, c.to_qty, c.to_unit
+		FROM ingredient_conversions c
+		JOIN ingredients ing ON ing.id = c.ingredient_id
+		ORDER BY ing.name, c.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	var all []convRow
+	for rows.Next() {
+		var r convRow
+		if err := rows.Scan(&r.id, &r.ingredientID, &r.ingredient,
+			&r.step.FromQty, &r.step.FromUnit, &r.step.ToQty, &r.step.ToUnit); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		r.step.FromUnit = CanonicalUnit(r.step.FromUnit)
+		r.step.ToUnit = CanonicalUnit(r.step.ToUnit)
+		all = append(all, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var findings []*Finding
+	byIngredient := make(map[int][]convRow)
+	var order []int
+	for _, r := range all {
+		if r.step.FromQty <= 0 || r.step.ToQty <= 0 {
+			findings = append(findings, &Finding{
+				Check:    "conversions",
+				Severity: SeverityWarning,
+				Subject:  r.ingredient,
+				Message:  fmt.Sprintf("conversion %g %s = %g %s is ignored: quantities must be positive", r.step.FromQty, r.step.FromUnit, r.step.ToQty, r.step.ToUnit),
+				Fix:      "delete the conversion",
+				repair:   deleteRow("ingredient_conversions", r.id),
+			})
+			continue
+		}
+		if _, ok := byIngredient[r.ingredientID]; !ok {
+			order = append(order, r.ingredientID)
+		}
+		byIngredient[r.ingredientID] = append(byIngredient[r.ingredientID], r)
+	}
+
+	for _, id := range order {
+		convs := byIngredient[id]
+		for i, r := range convs {
+			// Steps from every other row, both directions.
+			var others []ConversionStep
+			for j, o := range convs {
+				if j == i {
+					continue
+				}
+				others = append(others, o.step, ConversionStep{
+					FromQty: o.step.ToQty, FromUnit: o.step.ToUnit,
+					ToQty: o.step.FromQty, ToUnit: o.step.FromUnit,
+				})
+			}
+			got, err := resolveConversionChain(others, r.step.FromQty, r.step.FromUnit, r.step.ToUnit, map[string]bool{})
+			if err != nil {
+				continue // no other route: nothing to disagree with
+			}
+			if math.Abs(got-r.step.ToQty) <= 0.01*r.step.ToQty {
+				continue
+			}
+			findings = append(findings, &Finding{
+				Check:    "conversions",
+				Severity: SeverityError,
+				Subject:  r.ingredient,
+				Message: fmt.Sprintf("conversion loop disagrees: %g %s = %g %s, but other conversions give %.4g %s",
+					r.step.FromQty, r.step.FromUnit, r.step.ToQty, r.step.ToUnit, got, r.step.ToUnit),
+			})
+			break // one report per ingredient
+		}
+	}
+	return findings, nil
+}
+
+func deleteRow(table string, id int) func(db *sql.DB) error {
+	return func(db *sql.DB) error {
+		_, err := db.Exec(fmt.Sprintf(`DELETE FROM %s WHERE id = ?`, table), id)
+		return err
+	}
+}
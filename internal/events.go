@@ -0,0 +1,201 @@
+package internal
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Event is a multi-day service with expected covers per day and a menu.
+type Event struct {
+	ID        int
+	Name      string
+	StartDate string
+	EndDate   string
+	Notes     string
+	Days      []EventDay
+	Dishes    []EventDish
+}
+
+// EventDay is the expected number of covers on one day of an event.
+type EventDay struct {
+	Day    string
+	Covers float64
+}
+
+// EventDish is a menu item with the share of covers expected to order it.
+type EventDish struct {
+	RecipeID int
+	Name     string
+	MixPct   float64
+}
+
+// EventDays lists the dates from start to end inclusive.
+func EventDays(start, end string) ([]string, error) {
+	s, err := time.Parse(DateLayout, start)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start date %q", start)
+	}
+	e, err := time.Parse(DateLayout, end)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end date %q", end)
+	}
+	if e.Before(s) {
+		return nil, fmt.Errorf("end date %s is before start date %s", end, start)
+	}
+
+	var days []string
+	for d := s; !d.After(e); d = d.AddDate(0, 0, 1) {
+		days = append(days, d.Format(DateLayout))
+	}
+	return days, nil
+}
+
+// CreateEvent inserts an event and one event_days row per date, each with
+// the given covers.
+func CreateEvent(db *sql.DB, name, start, end string, covers float64, notes string) (int, error) {
+	days, err := EventDays(start, end)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`
+		INSERT INTO events (name, start_date, end_date, notes)
+		VALUES (?, ?, ?, NULLIF(?, ''))
+	`, name, start, end, notes)
+	if err != nil {
+		return 0, fmt.Errorf("creating event: %w", err)
+	}
+	id64, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, d := range days {
+		if _, err := tx.Exec(`INSERT INTO event_days (event_id, day, covers) VALUES (?, ?, ?)`, id64, d, covers); err != nil {
+			return 0, fmt.Errorf("creating event day %s: %w", d, err)
+		}
+	}
+	return int(id64), tx.Commit()
+}
+
+// LoadEvent reads an event with its days and menu.
+func LoadEvent(db *sql.DB, name string) (*Event, error) {
+	ev := &Event{}
+	var notes sql.NullString
+	err := db.QueryRow(`
+		SELECT id, name, start_date, end_date, notes
+		FROM events
+		WHERE name = ?
+	`, name).Scan(&ev.ID, &ev.Name, &ev.StartDate, &ev.EndDate, &notes)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("event not found: %s", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	ev.Notes = notes.String
+
+	rows, err := db.Query(`SELECT day, covers FROM event_days WHERE event_id = ? ORDER BY day`, ev.ID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var d EventDay
+		if err := rows.Scan(&d.Day, &d.Covers); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ev.Days = append(ev.Days, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = db.Query(`
+		SELECT ed.recipe_id, r.name, ed.mix_pct
+		FROM event_dishes ed
+		JOIN recipes r ON r.id = ed.recipe_id
+		WHERE ed.event_id = ?
+		ORDER BY ed.mix_pct DESC, r.name
+	`, ev.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var d EventDish
+		if err := rows.Scan(&d.RecipeID, &d.Name, &d.MixPct); err != nil {
+			return nil, err
+		}
+		ev.Dishes = append(ev.Dishes, d)
+	}
+	return ev, rows.Err()
+}
+
+// RemoveEventDish takes a recipe off an event's menu. It returns
+// ErrNotFound (wrapped) when the recipe is not on the menu.
+func RemoveEventDish(db *sql.DB, eventID, recipeID int) error {
+	res, err := db.Exec(`DELETE FROM event_dishes WHERE event_id = ? AND recipe_id = ?`, eventID, recipeID)
+	if err != nil {
+		return fmt.Errorf("removing dish: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("dish %w on the menu", ErrNotFound)
+	}
+	return nil
+}
+
+// TotalCovers sums the covers over all days.
+func (e *Event) TotalCovers() float64 {
+	var total float64
+	for _, d := range e.Days {
+		total += d.Covers
+	}
+	return total
+}
+
+// MixEpsilon absorbs float noise when checking that a menu mix is at most
+// 100%.
+const MixEpsilon = 1e-9
+
+// TotalMix sums the menu's mix percentages, at most 100. It need not reach
+// 100: some covers may not order from the menu.
+func (e *Event) TotalMix() float64 {
+	var total float64
+	for _, d := range e.Dishes {
+		total += d.MixPct
+	}
+	return total
+}
+
+// Plan expands covers into portions per dish per day.
+func (e *Event) Plan() *Plan {
+	p := &Plan{Name: e.Name}
+	for _, day := range e.Days {
+		for _, dish := range e.Dishes {
+			portions := day.Covers * dish.MixPct / 100
+			if portions <= 0 {
+				continue
+			}
+			p.Entries = append(p.Entries, PlanEntry{
+				Dish:     dish.Name,
+				RecipeID: dish.RecipeID,
+				Portions: portions,
+				Day:      day.Day,
+			})
+		}
+	}
+	return p
+}
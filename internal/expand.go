@@ -0,0 +1,218 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+)
+
+// IngredientUsage is the total amount of one ingredient needed, expressed in
+// the ingredient's own unit.
+type IngredientUsage struct {
+	IngredientID int
+	Name         string
+	Unit         string
+	CostPerUnit  float64
+	Qty          float64
+}
+
+// Cost returns the cost of the aggregated quantity.
+func (u *IngredientUsage) Cost() float64 {
+	return u.Qty * u.CostPerUnit
+}
+
+// ExpandIngredients adds everything needed to produce qty yield units of
+// recipeID to usage, keyed by ingredient ID. Subrecipes are expanded to any
+// depth and scaled by each level's yield, with line units already resolved
+// by the Coster.
+func (c *Coster) ExpandIngredients(recipeID int, qty float64, usage map[int]*IngredientUsage) error {
+	rc, err := c.Cost(recipeID)
+	if err != nil {
+		return err
+	}
+	if rc.YieldQty <= 0 {
+		return fmt.Errorf("recipe %s has no positive yield", rc.Name)
+	}
+	scale := qty / rc.YieldQty
+
+	for _, l := range rc.Lines {
+		switch l.Type {
+		case "ingredient":
+			u, ok := usage[l.IngredientID]
+			if !ok {
+				u = &IngredientUsage{
+					IngredientID: l.IngredientID,
+					Name:         l.Name,
+					Unit:         l.BaseUnit,
+					CostPerUnit:  l.CostPerUnit,
+				}
+				usage[l.IngredientID] = u
+			}
+			u.Qty += l.BaseQty * scale
+
+		case "subrecipe":
+			if err := c.ExpandIngredients(l.SubrecipeID, l.BaseQty*scale, usage); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SubrecipeUsage is the total amount of one subrecipe needed, expressed in
+// its primary yield unit.
+type SubrecipeUsage struct {
+	RecipeID int
+	Name     string
+	Unit     string
+	Qty      float64
+	Depth    int // 1 inside a dish, 2 inside one of those, ...; deepest if several
+	usedIn   map[string]bool
+}
+
+// UsedIn returns the recipes that use the subrecipe, sorted.
+func (u *SubrecipeUsage) UsedIn() []string {
+	names := make([]string, 0, len(u.usedIn))
+	for n := range u.usedIn {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ExpandSubrecipes adds every subrecipe needed to produce qty yield units
+// of recipeID to usage, keyed by recipe ID, at every nesting level. Each
+// level is scaled by its parent's yield.
+func (c *Coster) ExpandSubrecipes(recipeID int, qty float64, usage map[int]*SubrecipeUsage) error {
+	return c.expandSubrecipes(recipeID, qty, 1, usage)
+}
+
+func (c *Coster) expandSubrecipes(recipeID int, qty float64, depth int, usage map[int]*SubrecipeUsage) error {
+	rc, err := c.Cost(recipeID)
+	if err != nil {
+		return err
+	}
+	if rc.YieldQty <= 0 {
+		return fmt.Errorf("recipe %s has no positive yield", rc.Name)
+	}
+	scale := qty / rc.YieldQty
+
+	for _, l := range rc.Lines {
+		if l.Type != "subrecipe" {
+			continue
+		}
+		u, ok := usage[l.SubrecipeID]
+		if !ok {
+			u = &SubrecipeUsage{
+				RecipeID: l.SubrecipeID,
+				Name:     l.Name,
+				Unit:     l.BaseUnit,
+				usedIn:   make(map[string]bool),
+			}
+			usage[l.SubrecipeID] = u
+		}
+		u.Qty += l.BaseQty * scale
+		u.usedIn[rc.Name] = true
+		if depth > u.Depth {
+			u.Depth = depth
+		}
+
+		if err := c.expandSubrecipes(l.SubrecipeID, l.BaseQty*scale, depth+1, usage); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SortedSubrecipes returns the usage map as a slice in production order:
+// deepest first, so every subrecipe comes before the ones it goes into,
+// then by name.
+func SortedSubrecipes(usage map[int]*SubrecipeUsage) []*SubrecipeUsage {
+	list := make([]*SubrecipeUsage, 0, len(usage))
+	for _, u := range usage {
+		list = append(list, u)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Depth != list[j].Depth {
+			return list[i].Depth > list[j].Depth
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// SortedUsage returns the usage map as a slice ordered by ingredient name.
+func SortedUsage(usage map[int]*IngredientUsage) []*IngredientUsage {
+	list := make([]*IngredientUsage, 0, len(usage))
+	for _, u := range usage {
+		list = append(list, u)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// MarketList is the market list of every recipe in the Coster's Store that
+// is not archived.
+func (c *Coster) MarketList() ([]*IngredientUsage, error) {
+	recipes, err := c.store.Recipes()
+	if err != nil {
+		return nil, err
+	}
+
+	usage := make(map[int]*IngredientUsage)
+	for _, r := range recipes {
+		if err := c.ExpandIngredients(r.ID, r.YieldQty, usage); err != nil {
+			return nil, err
+		}
+	}
+
+	return SortedUsage(usage), nil
+}
+
+// Dish is an amount of a recipe to produce, in its yield unit (portions for
+// a dish).
+type Dish struct {
+	RecipeID int
+	Portions float64
+}
+
+// Forecast is everything needed to produce a set of dishes.
+type Forecast struct {
+	Ingredients []*IngredientUsage // by name
+	Subrecipes  []*SubrecipeUsage  // in production order
+}
+
+// Cost returns the ingredient cost of the forecast.
+func (f *Forecast) Cost() float64 {
+	var total float64
+	for _, u := range f.Ingredients {
+		total += u.Cost()
+	}
+	return total
+}
+
+// Forecast expands dishes into the ingredients and the subrecipes, at every
+// level, needed to produce them.
+func (c *Coster) Forecast(dishes []Dish) (*Forecast, error) {
+	ingredients := make(map[int]*IngredientUsage)
+	subrecipes := make(map[int]*SubrecipeUsage)
+
+	for _, d := range dishes {
+		rc, err := c.Cost(d.RecipeID)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.ExpandIngredients(d.RecipeID, d.Portions, ingredients); err != nil {
+			return nil, fmt.Errorf("expanding ingredients for %s: %w", rc.Name, err)
+		}
+		if err := c.ExpandSubrecipes(d.RecipeID, d.Portions, subrecipes); err != nil {
+			return nil, fmt.Errorf("expanding subrecipes for %s: %w", rc.Name, err)
+		}
+	}
+
+	return &Forecast{
+		Ingredients: SortedUsage(ingredients),
+		Subrecipes:  SortedSubrecipes(subrecipes),
+	}, nil
+}
@@ -0,0 +1,829 @@
+package internal
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Recipe import modes: what happens to lines of an existing recipe that
+// the file does not list.
+const (
+	// ImportUpsert keeps them; listed lines are added or updated.
+	ImportUpsert = "upsert"
+	// ImportReplace deletes them, so the file's lines become the recipe.
+	ImportReplace = "replace"
+)
+
+// Import change actions, as in ImportChange.Action.
+const (
+	ImportAdd       = "add"
+	ImportUpdate    = "update"
+	ImportUnchanged = "unchanged"
+)
+
+// ImportChange is one ingredient or recipe of an import file, compared with
+// the database.
+type ImportChange struct {
+	Action  string // ImportAdd, ImportUpdate or ImportUnchanged
+	Name    string
+	Details []string // e.g. "cost 7 → 7.5", "+ Butter 0.08 kg"
+}
+
+// ImportRowError is a problem with one row of an import file. Line is the
+// line number in the file, counting the header as line 1.
+type ImportRowError struct {
+	Line int
+	Err  error
+}
+
+func (e ImportRowError) Error() string { return fmt.Sprintf("line %d: %v", e.Line, e.Err) }
+
+// ImportErrors lists every row of an import file that failed validation.
+type ImportErrors []ImportRowError
+
+func (e ImportErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	return fmt.Sprintf("%d rows have errors, first %v", len(e), e[0])
+}
+
+// Import is a validated import file: what it would change, ready to Apply.
+type Import struct {
+	Changes     []ImportChange
+	ingredients []*importIngredient
+	recipes     []*importRecipe
+}
+
+// Count returns the number of changes with action.
+func (imp *Import) Count(action string) int {
+	n := 0
+	for _, c := range imp.Changes {
+		if c.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+// Apply writes the import in one transaction, so either all of it lands or
+// none of it does.
+func (imp *Import) Apply(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, ing := range imp.ingredients {
+		if err := ing.save(tx); err != nil {
+			return fmt.Errorf("saving %s: %w", ing.name, err)
+		}
+	}
+	// Recipes first, so lines can refer to subrecipes new in this file.
+	for _, r := range imp.recipes {
+		if err := r.save(tx); err != nil {
+			return fmt.Errorf("saving %s: %w", r.name, err)
+		}
+	}
+	for _, r := range imp.recipes {
+		if err := r.saveLines(tx); err != nil {
+			return fmt.Errorf("saving lines of %s: %w", r.name, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// importIngredient is a new or changed ingredient of an import.
+type importIngredient struct {
+	id        int // 0 for a new ingredient
+	name      string
+	unit      string
+	cost      float64
+	costMoved bool // cost differs, so it goes into the price history
+}
+
+func (ing *importIngredient) save(tx *sql.Tx) error {
+	if ing.id == 0 {
+		r, err := tx.Exec(`INSERT INTO ingredients (name, unit, cost_per_unit) VALUES (?, ?, ?)`,
+			ing.name, ing.unit, ing.cost)
+		if err != nil {
+			return err
+		}
+		id, err := r.LastInsertId()
+		if err != nil {
+			return err
+		}
+		ing.id = int(id)
+	} else if _, err := tx.Exec(`UPDATE ingredients SET unit = ?, cost_per_unit = ? WHERE id = ?`,
+		ing.unit, ing.cost, ing.id); err != nil {
+		return err
+	}
+	if !ing.costMoved {
+		return nil
+	}
+	return recordPrice(tx, ing.id, ing.cost, Today(), "", "import")
+}
+
+// PlanIngredientImport reads an ingredient CSV file and compares it with
+// the database. Columns: name, unit, cost. A name the database has
+// (compared case-insensitively) updates that ingredient's unit and cost;
+// cost changes go into the price history as with `ingredient add`.
+//
+//	name,unit,cost
+//	Butter Unsalted,kg,9.50
+//
+// Rows that fail validation come back together as ImportErrors.
+func PlanIngredientImport(db *sql.DB, r io.Reader) (*Import, error) {
+	rows, err := readImportCSV(r, []string{"name", "unit", "cost"}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	s := NewSQLStore(db)
+	existing, err := s.Ingredients()
+	if err != nil {
+		return nil, err
+	}
+	names := newNameIndex()
+	byID := make(map[int]Ingredient)
+	for _, ing := range existing {
+		names.add(ing.ID, ing.Name)
+		byID[ing.ID] = ing
+	}
+
+	imp := &Import{}
+	var errs ImportErrors
+	seen := make(map[string]int) // lower-case name → line
+	for _, row := range rows {
+		fail := func(err error) { errs = append(errs, ImportRowError{Line: row.line, Err: err}) }
+
+		name := row.fields["name"]
+		if name == "" {
+			fail(fmt.Errorf("name is empty"))
+			continue
+		}
+		if prev, ok := seen[strings.ToLower(name)]; ok {
+			fail(fmt.Errorf("%s is already on line %d", name, prev))
+			continue
+		}
+		seen[strings.ToLower(name)] = row.line
+
+		unit, err := NormalizeUnit(row.fields["unit"])
+		if err != nil {
+			fail(err)
+			continue
+		}
+		cost, err := parseImportQty(row.fields["cost"], "cost")
+		if err != nil {
+			fail(err)
+			continue
+		}
+		id, err := names.find(name)
+		if err != nil {
+			fail(err)
+			continue
+		}
+
+		ing := &importIngredient{id: id, name: name, unit: unit, cost: cost, costMoved: true}
+		change := ImportChange{Action: ImportAdd, Name: name}
+		if id == 0 {
+			change.Details = []string{fmt.Sprintf("%s @ %s", unit, importNum(cost))}
+		} else {
+			cur := byID[id]
+			ing.name, change.Name = cur.Name, cur.Name
+			change.Action = ImportUpdate
+			if cur.Unit != unit {
+				change.Details = append(change.Details, fmt.Sprintf("unit %s → %s", cur.Unit, unit))
+			}
+			ing.costMoved = cur.CostPerUnit != cost
+			if ing.costMoved {
+				change.Details = append(change.Details,
+					fmt.Sprintf("cost %s → %s", importNum(cur.CostPerUnit), importNum(cost)))
+			}
+			if len(change.Details) == 0 {
+				change.Action = ImportUnchanged
+			}
+		}
+		imp.Changes = append(imp.Changes, change)
+		if change.Action != ImportUnchanged {
+			imp.ingredients = append(imp.ingredients, ing)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return imp, nil
+}
+
+// importYield is the yield of a recipe; zero fields are not set.
+type importYield struct {
+	qty, secQty   float64
+	unit, secUnit string
+}
+
+// importRecipe is one recipe of a recipe import, gathered from all its rows.
+type importRecipe struct {
+	line     int // first row
+	id       int // 0 until a new recipe is saved
+	name     string
+	file     importYield // as given in the file
+	yield    Recipe      // the yield it ends up with
+	newYield bool        // yield must be written
+	rows     []importLineRow
+	lines    []*importLine
+	removed  []Line // existing lines ImportReplace deletes
+}
+
+// importLineRow is a line as written in the file, before names resolve.
+type importLineRow struct {
+	line     int
+	lineType string // LineIngredient or LineSubrecipe
+	item     string
+	qty      float64
+	unit     string
+}
+
+// importLine is a resolved, validated recipe line.
+type importLine struct {
+	line         int
+	lineType     string
+	ingredientID int
+	sub          *importRecipe // a subrecipe from the same file
+	subID        int           // or one only in the database
+	name         string
+	qty          float64
+	unit         string
+	existing     *Line // the recipe's line of the same item
+}
+
+func (l *importLine) changed() bool {
+	return l.existing == nil || l.existing.Qty != l.qty || !SameUnit(l.existing.Unit, l.unit)
+}
+
+func (r *importRecipe) save(tx *sql.Tx) error {
+	if r.id == 0 {
+		res, err := tx.Exec(`
+			INSERT INTO recipes (name, yield_qty, yield_unit, secondary_yield_qty, secondary_yield_unit)
+			VALUES (?, ?, ?, NULLIF(?, 0), NULLIF(?, ''))
+		`, r.name, r.yield.YieldQty, r.yield.YieldUnit, r.yield.SecondaryYieldQty, r.yield.SecondaryYieldUnit)
+		if err != nil {
+			return err
+		}
+		id, err := res.LastInsertId()
+		r.id = int(id)
+		return err
+	}
+	if !r.newYield {
+		return nil
+	}
+	_, err := tx.Exec(`
+		UPDATE recipes
+		SET yield_qty = ?, yield_unit = ?,
+		    secondary_yield_qty = NULLIF(?, 0), secondary_yield_unit = NULLIF(?, '')
+		WHERE id = ?
+	`, r.yield.YieldQty, r.yield.YieldUnit, r.yield.SecondaryYieldQty, r.yield.SecondaryYieldUnit, r.id)
+	return err
+}
+
+func (r *importRecipe) saveLines(tx *sql.Tx) error {
+	for _, l := range r.removed {
+		table, err := lineTable(l.Type)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM `+table+` WHERE id = ?`, l.ID); err != nil {
+			return err
+		}
+	}
+	for _, l := range r.lines {
+		if !l.changed() {
+			continue
+		}
+		table, err := lineTable(l.lineType)
+		if err != nil {
+			return err
+		}
+		if l.existing != nil {
+			_, err = tx.Exec(`UPDATE `+table+` SET qty = ?, unit = ? WHERE id = ?`, l.qty, l.unit, l.existing.ID)
+		} else if l.lineType == LineIngredient {
+			_, err = tx.Exec(`INSERT INTO recipe_items (recipe_id, ingredient_id, qty, unit) VALUES (?, ?, ?, ?)`,
+				r.id, l.ingredientID, l.qty, l.unit)
+		} else {
+			subID := l.subID
+			if l.sub != nil {
+				subID = l.sub.id
+			}
+			_, err = tx.Exec(`INSERT INTO recipe_subrecipes (recipe_id, subrecipe_id, qty, unit) VALUES (?, ?, ?, ?)`,
+				r.id, subID, l.qty, l.unit)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PlanRecipeImport reads a recipe CSV file and compares it with the
+// database. Each row is one line of a recipe: an ingredient or a
+// subrecipe with its qty and, optionally, unit (default: the ingredient's
+// unit or the subrecipe's yield unit). The yield goes on any row of the
+// recipe, and a row without a line only sets the yield.
+//
+//	recipe,yield,yield_unit,secondary_yield,secondary_unit,ingredient,subrecipe,qty,unit
+//	SUB Mac Base,1,kg,,,Macaroni,,0.4,kg
+//	SUB Mac Base,,,,,Milk,,300,ml
+//	BULK Lobster Mac,1,kg,,,,SUB Mac Base,0.666,
+//
+// Subrecipes may be recipes of the same file, in any order. Recipes that
+// exist get the file's yield (where given) and lines: mode ImportUpsert
+// adds or updates the listed lines and keeps the rest, ImportReplace also
+// deletes the lines the file does not list.
+//
+// Rows that fail validation come back together as ImportErrors.
+func PlanRecipeImport(db *sql.DB, r io.Reader, mode string) (*Import, error) {
+	if mode != ImportUpsert && mode != ImportReplace {
+		return nil, fmt.Errorf("import mode must be %s or %s, not %q", ImportUpsert, ImportReplace, mode)
+	}
+	rows, err := readImportCSV(r, []string{"recipe"},
+		[]string{"yield", "yield_unit", "secondary_yield", "secondary_unit", "ingredient", "subrecipe", "qty", "unit"})
+	if err != nil {
+		return nil, err
+	}
+
+	s := NewSQLStore(db)
+	ingredientNames := newNameIndex()
+	ingredients, err := s.Ingredients()
+	if err != nil {
+		return nil, err
+	}
+	for _, ing := range ingredients {
+		ingredientNames.add(ing.ID, ing.Name)
+	}
+	recipeNames, err := loadRecipeNames(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs ImportErrors
+	fail := func(line int, err error) { errs = append(errs, ImportRowError{Line: line, Err: err}) }
+
+	// Gather each recipe's rows.
+	var recipes []*importRecipe
+	byName := make(map[string]*importRecipe) // lower-case name
+	for _, row := range rows {
+		name := row.fields["recipe"]
+		if name == "" {
+			fail(row.line, fmt.Errorf("recipe is empty"))
+			continue
+		}
+		rec, ok := byName[strings.ToLower(name)]
+		if !ok {
+			rec = &importRecipe{line: row.line, name: name}
+			byName[strings.ToLower(name)] = rec
+			recipes = append(recipes, rec)
+		}
+		if err := rec.file.merge(row.fields); err != nil {
+			fail(row.line, err)
+			continue
+		}
+		l, ok, err := parseImportLine(row)
+		if err != nil {
+			fail(row.line, err)
+		} else if ok {
+			rec.rows = append(rec.rows, l)
+		}
+	}
+
+	// Match recipes with the database and settle their yields.
+	for _, rec := range recipes {
+		id, err := recipeNames.find(rec.name)
+		if err != nil {
+			fail(rec.line, err)
+			continue
+		}
+		if id != 0 {
+			if rec.yield, err = s.Recipe(id); err != nil {
+				return nil, err
+			}
+			rec.id, rec.name = id, rec.yield.Name
+		} else {
+			rec.yield.Name = rec.name
+		}
+		if err := rec.settleYield(); err != nil {
+			fail(rec.line, err)
+		}
+	}
+
+	// Resolve and check the lines, now that every yield is known.
+	for _, rec := range recipes {
+		var existing []Line
+		if rec.id != 0 {
+			if existing, err = s.Lines(rec.id); err != nil {
+				return nil, err
+			}
+		}
+		used := make([]bool, len(existing))
+		seen := make(map[string]int) // line type and item → line
+		for _, row := range rec.rows {
+			l := &importLine{line: row.line, lineType: row.lineType, qty: row.qty}
+			var key string
+			if row.lineType == LineIngredient {
+				id, err := ingredientNames.find(row.item)
+				if err == nil && id == 0 {
+					err = fmt.Errorf("ingredient %s %w", row.item, ErrNotFound)
+				}
+				if err != nil {
+					fail(row.line, err)
+					continue
+				}
+				l.ingredientID, l.name = id, ingredientNames.names[id]
+				if l.unit, err = checkIngredientLine(s, id, row.qty, row.unit); err != nil {
+					fail(row.line, err)
+					continue
+				}
+				key = fmt.Sprintf("i%d", id)
+			} else {
+				var sub Recipe
+				if l.sub = byName[strings.ToLower(row.item)]; l.sub != nil {
+					sub, l.subID = l.sub.yield, l.sub.id
+				} else {
+					id, err := recipeNames.find(row.item)
+					if err == nil && id == 0 {
+						err = fmt.Errorf("recipe %s %w (nor in this file)", row.item, ErrNotFound)
+					}
+					if err != nil {
+						fail(row.line, err)
+						continue
+					}
+					if sub, err = s.Recipe(id); err != nil {
+						return nil, err
+					}
+					l.subID = id
+				}
+				l.name = sub.Name
+				if sub.YieldUnit == "" {
+					continue // the subrecipe's own row already failed
+				}
+				if l.unit, err = checkYieldUnit(sub, row.qty, row.unit); err != nil {
+					fail(row.line, err)
+					continue
+				}
+				key = "s" + strings.ToLower(sub.Name)
+			}
+			if prev, ok := seen[key]; ok {
+				fail(row.line, fmt.Errorf("%s is already in %s on line %d", l.name, rec.name, prev))
+				continue
+			}
+			seen[key] = row.line
+
+			for i, ex := range existing {
+				if used[i] || ex.Type != l.lineType {
+					continue
+				}
+				if (l.lineType == LineIngredient && ex.IngredientID == l.ingredientID) ||
+					(l.lineType == LineSubrecipe && l.subID != 0 && ex.SubrecipeID == l.subID) {
+					l.existing = &existing[i]
+					used[i] = true
+					break
+				}
+			}
+			rec.lines = append(rec.lines, l)
+		}
+		if mode == ImportReplace {
+			for i, ex := range existing {
+				if !used[i] {
+					rec.removed = append(rec.removed, ex)
+				}
+			}
+		}
+	}
+
+	if err := checkImportCycles(db, recipes, mode, fail); err != nil {
+		return nil, err
+	}
+	if len(errs) > 0 {
+		sort.SliceStable(errs, func(i, j int) bool { return errs[i].Line < errs[j].Line })
+		return nil, errs
+	}
+
+	imp := &Import{}
+	for _, rec := range recipes {
+		change := rec.change()
+		imp.Changes = append(imp.Changes, change)
+		if change.Action != ImportUnchanged {
+			imp.recipes = append(imp.recipes, rec)
+		}
+	}
+	return imp, nil
+}
+
+// merge takes the yield columns of one row, which must agree with the
+// recipe's other rows.
+func (y *importYield) merge(f map[string]string) error {
+	for _, col := range []struct {
+		name string
+		qty  *float64
+	}{{"yield", &y.qty}, {"secondary_yield", &y.secQty}} {
+		if f[col.name] == "" {
+			continue
+		}
+		q, err := parseImportQty(f[col.name], col.name)
+		if err != nil {
+			return err
+		}
+		if *col.qty != 0 && *col.qty != q {
+			return fmt.Errorf("%s %s differs from %s on an earlier row", col.name, importNum(q), importNum(*col.qty))
+		}
+		*col.qty = q
+	}
+	for _, col := range []struct {
+		name string
+		unit *string
+	}{{"yield_unit", &y.unit}, {"secondary_unit", &y.secUnit}} {
+		u := CanonicalUnit(f[col.name])
+		if u == "" {
+			continue
+		}
+		if *col.unit != "" && !SameUnit(*col.unit, u) {
+			return fmt.Errorf("%s %s differs from %s on an earlier row", col.name, u, *col.unit)
+		}
+		*col.unit = u
+	}
+	return nil
+}
+
+// settleYield applies the file's yield to the recipe's current one.
+func (r *importRecipe) settleYield() error {
+	f := r.file
+	if r.id == 0 && (f.qty == 0 || f.unit == "") {
+		return fmt.Errorf("new recipe %s needs yield and yield_unit", r.name)
+	}
+	if (f.secQty == 0) != (f.secUnit == "") {
+		return fmt.Errorf("%s: secondary_yield and secondary_unit go together", r.name)
+	}
+	old := r.yield
+	if f.qty != 0 {
+		r.yield.YieldQty = f.qty
+	}
+	if f.unit != "" {
+		r.yield.YieldUnit = f.unit
+	}
+	if f.secQty != 0 {
+		r.yield.SecondaryYieldQty, r.yield.SecondaryYieldUnit = f.secQty, f.secUnit
+	}
+	r.newYield = r.yield.YieldQty != old.YieldQty || r.yield.YieldUnit != old.YieldUnit ||
+		r.yield.SecondaryYieldQty != old.SecondaryYieldQty || r.yield.SecondaryYieldUnit != old.SecondaryYieldUnit
+	return nil
+}
+
+// change describes the recipe as a diff against the database.
+func (r *importRecipe) change() ImportChange {
+	c := ImportChange{Action: ImportUpdate, Name: r.name}
+	if r.id == 0 {
+		c.Action = ImportAdd
+		c.Details = append(c.Details, "yield "+yieldString(r.yield))
+	} else if r.newYield {
+		c.Details = append(c.Details, "yield → "+yieldString(r.yield))
+	}
+	for _, l := range r.lines {
+		switch {
+		case l.existing == nil:
+			c.Details = append(c.Details, fmt.Sprintf("+ %s %s %s", l.name, importNum(l.qty), l.unit))
+		case l.changed():
+			c.Details = append(c.Details, fmt.Sprintf("~ %s %s %s → %s %s", l.name,
+				importNum(l.existing.Qty), l.existing.Unit, importNum(l.qty), l.unit))
+		}
+	}
+	for _, l := range r.removed {
+		c.Details = append(c.Details, fmt.Sprintf("- %s %s %s", l.Name, importNum(l.Qty), l.Unit))
+	}
+	if len(c.Details) == 0 {
+		c.Action = ImportUnchanged
+	}
+	return c
+}
+
+func yieldString(r Recipe) string {
+	s := importNum(r.YieldQty) + " " + r.YieldUnit
+	if r.SecondaryYieldQty > 0 && r.SecondaryYieldUnit != "" {
+		s += " (" + importNum(r.SecondaryYieldQty) + " " + r.SecondaryYieldUnit + ")"
+	}
+	return s
+}
+
+// checkImportCycles reports, through fail, subrecipe lines that would
+// make a recipe depend on itself once the import is applied.
+func checkImportCycles(db *sql.DB, recipes []*importRecipe, mode string, fail func(int, error)) error {
+	g, err := loadSubrecipeGraph(db)
+	if err != nil {
+		return err
+	}
+
+	// New recipes get negative IDs in the graph.
+	ids := make(map[*importRecipe]int)
+	for i, rec := range recipes {
+		id := rec.id
+		if id == 0 {
+			id = -1 - i
+		}
+		ids[rec] = id
+		g.names[id] = rec.name
+		if mode == ImportReplace {
+			delete(g.children, id)
+		}
+	}
+	subID := func(l *importLine) int {
+		if l.sub != nil {
+			return ids[l.sub]
+		}
+		return l.subID
+	}
+	for _, rec := range recipes {
+		for _, l := range rec.lines {
+			if l.lineType == LineSubrecipe {
+				g.children[ids[rec]] = append(g.children[ids[rec]], subID(l))
+			}
+		}
+	}
+
+	for _, rec := range recipes {
+		for _, l := range rec.lines {
+			if l.lineType != LineSubrecipe {
+				continue
+			}
+			if p := g.path(subID(l), ids[rec]); p != nil {
+				fail(l.line, fmt.Errorf("%w: %s",
+					ErrSubrecipeCycle, g.pathString(append([]int{ids[rec]}, p...))))
+			}
+		}
+	}
+	return nil
+}
+
+// importRow is one row of an import file, by lower-case column name.
+type importRow struct {
+	line   int
+	fields map[string]string
+}
+
+// readImportCSV reads an import file with a header row. Blank rows and
+// # comments are skipped; columns other than required and optional are
+// refused, so a misspelt header does not drop its column silently.
+func readImportCSV(r io.Reader, required, optional []string) ([]importRow, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	cr.Comment = '#'
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	known := make(map[string]bool)
+	for _, c := range append(required, optional...) {
+		known[c] = true
+	}
+	cols := make([]string, len(header))
+	have := make(map[string]bool)
+	for i, h := range header {
+		cols[i] = strings.ToLower(strings.TrimSpace(h))
+		if !known[cols[i]] {
+			return nil, fmt.Errorf("unknown column %q (columns: %s)", h, strings.Join(append(required, optional...), ", "))
+		}
+		have[cols[i]] = true
+	}
+	for _, c := range required {
+		if !have[c] {
+			return nil, fmt.Errorf("missing %q column", c)
+		}
+	}
+
+	var rows []importRow
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+
+		row := importRow{line: line, fields: make(map[string]string)}
+		blank := true
+		for i, v := range rec {
+			if i < len(cols) {
+				row.fields[cols[i]] = strings.TrimSpace(v)
+				blank = blank && row.fields[cols[i]] == ""
+			}
+		}
+		if !blank {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+// parseImportLine reads the line columns of a recipe row. It reports false
+// for a row without a line, which only sets the yield.
+func parseImportLine(row importRow) (importLineRow, bool, error) {
+	f := row.fields
+	l := importLineRow{line: row.line, unit: f["unit"]}
+	switch {
+	case f["ingredient"] != "" && f["subrecipe"] != "":
+		return l, false, fmt.Errorf("give an ingredient or a subrecipe, not both")
+	case f["ingredient"] != "":
+		l.lineType, l.item = LineIngredient, f["ingredient"]
+	case f["subrecipe"] != "":
+		l.lineType, l.item = LineSubrecipe, f["subrecipe"]
+	case f["qty"] != "" || f["unit"] != "":
+		return l, false, fmt.Errorf("qty without an ingredient or subrecipe")
+	default:
+		return l, false, nil
+	}
+	qty, err := parseImportQty(f["qty"], "qty")
+	if err != nil {
+		return l, false, fmt.Errorf("%s: %w", l.item, err)
+	}
+	l.qty = qty
+	return l, true, nil
+}
+
+func parseImportQty(s, column string) (float64, error) {
+	if s == "" {
+		return 0, fmt.Errorf("%s is empty", column)
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v <= 0 {
+		return 0, fmt.Errorf("%s must be a positive number, not %q", column, s)
+	}
+	return v, nil
+}
+
+// importNum formats a quantity as short as it reads back exactly.
+func importNum(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// nameIndex finds IDs by name: an exact match, or else a case-insensitive
+// one when that picks out a single row.
+type nameIndex struct {
+	exact  map[string]int
+	folded map[string][]int
+	names  map[int]string
+}
+
+func newNameIndex() *nameIndex {
+	return &nameIndex{exact: make(map[string]int), folded: make(map[string][]int), names: make(map[int]string)}
+}
+
+func (x *nameIndex) add(id int, name string) {
+	x.exact[name] = id
+	x.folded[strings.ToLower(name)] = append(x.folded[strings.ToLower(name)], id)
+	x.names[id] = name
+}
+
+// find returns the ID for name, or 0 when nothing matches.
+func (x *nameIndex) find(name string) (int, error) {
+	if id, ok := x.exact[name]; ok {
+		return id, nil
+	}
+	ids := x.folded[strings.ToLower(name)]
+	switch len(ids) {
+	case 0:
+		return 0, nil
+	case 1:
+		return ids[0], nil
+	}
+	names := make([]string, len(ids))
+	for i, id := range ids {
+		names[i] = x.names[id]
+	}
+	return 0, fmt.Errorf("%s is ambiguous: %s", name, strings.Join(names, ", "))
+}
+
+// loadRecipeNames indexes every recipe, archived ones too.
+func loadRecipeNames(db *sql.DB) (*nameIndex, error) {
+	rows, err := db.Query(`SELECT id, name FROM recipes`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	x := newNameIndex()
+	for rows.Next() {
+		var id int
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+		x.add(id, name)
+	}
+	return x, rows.Err()
+}
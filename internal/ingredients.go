@@ -0,0 +1,533 @@
+package internal
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ErrIngredientExists is returned (wrapped) when a new ingredient name is
+// taken.
+var ErrIngredientExists = errors.New("ingredient already exists")
+
+// MergeResult describes what MergeIngredients changed.
+type MergeResult struct {
+	Recipes       []string         // recipes whose lines now use the kept ingredient
+	Summed        []string         // recipes where the two lines were added together
+	Duplicates    []MergeDuplicate // recipes left with two lines that could not be added
+	Conflicts     []string         // merged conversions dropped for disagreeing with the kept ones
+	Converted     []string         // recipes whose lines were rewritten in the kept unit because of a conflict
+	Conversions   int              // conversion rows moved
+	Prices        int              // price history rows moved
+	StockCounts   int              // stock counts moved
+	SupplierLinks int              // supplier links moved
+}
+
+// MergeDuplicate is a recipe left listing the kept ingredient twice, with
+// the reason the two lines could not be added together.
+type MergeDuplicate struct {
+	Recipe string
+	Err    error
+}
+
+// ingredientRef is the name and unit of an ingredient.
+type ingredientRef struct {
+	id   int
+	name string
+	unit string
+}
+
+func loadIngredientRef(q queryer, id int) (ingredientRef, error) {
+	ref := ingredientRef{id: id}
+	err := q.QueryRow(`SELECT name, unit FROM ingredients WHERE id = ?`, id).Scan(&ref.name, &ref.unit)
+	if err == sql.ErrNoRows {
+		return ref, fmt.Errorf("ingredient with ID %d not found", id)
+	}
+	return ref, err
+}
+
+// SaveIngredient adds an ingredient or, when the name exists, updates its
+// unit and cost. The cost goes into the price history too, unless it is
+// already the price in effect today. It returns the ingredient's ID.
+func SaveIngredient(db *sql.DB, name, unit string, cost float64, source string) (int, error) {
+	_, err := db.Exec(`
+		INSERT INTO ingredients (name, unit, cost_per_unit)
+		VALUES (?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET
+		    unit = excluded.unit,
+		    cost_per_unit = excluded.cost_per_unit;
+	`, name, unit, cost)
+	if err != nil {
+		return 0, fmt.Errorf("saving ingredient: %w", err)
+	}
+
+	// Keep the price history in step, without a new row on every re-import.
+	var ingID int
+	if err := db.QueryRow(`SELECT id FROM ingredients WHERE name = ?`, name).Scan(&ingID); err != nil {
+		return 0, fmt.Errorf("reading ingredient: %w", err)
+	}
+	var recorded float64
+	err = db.QueryRow(`
+		SELECT cost_per_unit FROM ingredient_prices
+		WHERE ingredient_id = ? AND effective_date <= ?
+		ORDER BY effective_date DESC, id DESC
+		LIMIT 1
+	`, ingID, Today()).Scan(&recorded)
+	if err == sql.ErrNoRows || (err == nil && recorded != cost) {
+		err = RecordPrice(db, ingID, cost, Today(), "", source)
+	}
+	return ingID, err
+}
+
+// CreateIngredient adds a new ingredient, refusing names that are taken
+// (case-insensitively). The unit must be a standard unit.
+func CreateIngredient(db *sql.DB, name, unit string, cost float64, source string) (int, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return 0, fmt.Errorf("name is empty")
+	}
+	if cost <= 0 {
+		return 0, fmt.Errorf("cost must be positive")
+	}
+	unit, err := NormalizeUnit(unit)
+	if err != nil {
+		return 0, err
+	}
+	if err := ingredientNameFree(db, name, 0); err != nil {
+		return 0, err
+	}
+	return SaveIngredient(db, name, unit, cost, source)
+}
+
+// RenameIngredient changes an ingredient's name. Recipe lines, prices and
+// stock refer to it by ID and follow automatically.
+func RenameIngredient(db *sql.DB, ingredientID int, newName string) error {
+	newName = strings.TrimSpace(newName)
+	if newName == "" {
+		return fmt.Errorf("new name is empty")
+	}
+	if err := ingredientNameFree(db, newName, ingredientID); err != nil {
+		return err
+	}
+	_, err := db.Exec(`UPDATE ingredients SET name = ? WHERE id = ?`, newName, ingredientID)
+	return err
+}
+
+// IngredientReferences lists the recipes that use an ingredient directly.
+func IngredientReferences(db *sql.DB, ingredientID int) ([]string, error) {
+	rows, err := db.Query(`
+		SELECT DISTINCT r.name
+		FROM recipe_items ri
+		JOIN recipes r ON r.id = ri.recipe_id
+		WHERE ri.ingredient_id = ?
+		ORDER BY r.name
+	`, ingredientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// DeleteIngredient removes an ingredient with its prices, conversions,
+// supplier links and stock counts. It refuses while a recipe uses it.
+func DeleteIngredient(db *sql.DB, ingredientID int) error {
+	refs, err := IngredientReferences(db, ingredientID)
+	if err != nil {
+		return err
+	}
+	if len(refs) > 0 {
+		return fmt.Errorf("still used by %s", strings.Join(refs, ", "))
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, q := range []string{
+		`DELETE FROM ingredient_prices WHERE ingredient_id = ?`,
+		`DELETE FROM ingredient_conversions WHERE ingredient_id = ?`,
+		`DELETE FROM ingredient_suppliers WHERE ingredient_id = ?`,
+		`DELETE FROM stock_counts WHERE ingredient_id = ?`,
+		`DELETE FROM ingredients WHERE id = ?`,
+	} {
+		if _, err := tx.Exec(q, ingredientID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// mergeLine is one recipe_items row of the ingredient being merged away.
+type mergeLine struct {
+	id       int
+	recipeID int
+	recipe   string
+	qty      float64
+	unit     string // never empty: NULL lines get the ingredient's unit
+}
+
+// MergeIngredients folds fromID into intoID and deletes fromID. Recipe
+// lines, conversions, price history, stock counts and supplier links move
+// across, converted to the kept ingredient's unit where they are stored in
+// it. A recipe that already uses both gets one line with the quantities
+// added when they convert; otherwise both lines are kept and the recipe is
+// listed in Duplicates. Conversions, prices (per date) and supplier links
+// the kept ingredient already has win over the merged ones. A merged
+// conversion that disagrees with a kept one is listed in Conflicts, and
+// lines in its units are rewritten in the kept ingredient's unit using the
+// merged conversion, so their quantities do not change.
+func MergeIngredients(db *sql.DB, fromID, intoID int) (*MergeResult, error) {
+	if fromID == intoID {
+		return nil, fmt.Errorf("cannot merge an ingredient into itself")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	from, err := loadIngredientRef(tx, fromID)
+	if err != nil {
+		return nil, err
+	}
+	into, err := loadIngredientRef(tx, intoID)
+	if err != nil {
+		return nil, err
+	}
+
+	// factor is the number of into units in one from unit.
+	factor, err := mergeFactor(tx, from, into)
+	if err != nil {
+		return nil, err
+	}
+
+	lines, err := loadMergeLines(tx, from)
+	if err != nil {
+		return nil, err
+	}
+
+	moves, conflicts, err := planConversionMerge(tx, from, into)
+	if err != nil {
+		return nil, err
+	}
+	// Units whose meaning changes once the merged conversion is dropped.
+	conflictUnits := make(map[string]bool)
+	for _, c := range conflicts {
+		conflictUnits[CanonicalUnit(c.merged.FromUnit)] = true
+		conflictUnits[CanonicalUnit(c.merged.ToUnit)] = true
+	}
+
+	// The kept ingredient's first line in each recipe, to add to.
+	type target struct {
+		id   int
+		unit string
+		qty  float64
+	}
+	targets := make(map[int]*target)
+	rows, err := tx.Query(`
+		SELECT recipe_id, id, qty, COALESCE(NULLIF(unit, ''), ?)
+		FROM recipe_items
+		WHERE ingredient_id = ?
+		ORDER BY id
+	`, into.unit, into.id)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var recipeID int
+		t := &target{}
+		if err := rows.Scan(&recipeID, &t.id, &t.qty, &t.unit); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if _, ok := targets[recipeID]; !ok {
+			targets[recipeID] = t
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	res := &MergeResult{}
+	recipes := make(map[string]bool)
+
+	for _, l := range lines {
+		recipes[l.recipe] = true
+
+		if t, ok := targets[l.recipeID]; ok {
+			qty, err := mergeLineQty(tx, from, into, factor, l, t.unit)
+			if err == nil {
+				t.qty += qty
+				if _, err := tx.Exec(`UPDATE recipe_items SET qty = ? WHERE id = ?`, t.qty, t.id); err != nil {
+					return nil, err
+				}
+				if _, err := tx.Exec(`DELETE FROM recipe_items WHERE id = ?`, l.id); err != nil {
+					return nil, err
+				}
+				res.Summed = append(res.Summed, l.recipe)
+				continue
+			}
+			res.Duplicates = append(res.Duplicates, MergeDuplicate{Recipe: l.recipe, Err: err})
+		}
+
+		// Keep the line's own unit, spelled out so it is not read in the
+		// kept ingredient's unit, unless the kept ingredient converts that
+		// unit differently.
+		qty, unit := l.qty, l.unit
+		if conflictUnits[CanonicalUnit(unit)] {
+			if qty, err = mergeLineQty(tx, from, into, factor, l, into.unit); err != nil {
+				return nil, fmt.Errorf("%s: %w", l.recipe, err)
+			}
+			unit = into.unit
+			res.Converted = append(res.Converted, l.recipe)
+		}
+		if SameUnit(unit, into.unit) {
+			unit = ""
+		}
+		if _, err := tx.Exec(`
+			UPDATE recipe_items SET ingredient_id = ?, qty = ?, unit = NULLIF(?, '') WHERE id = ?
+		`, into.id, qty, unit, l.id); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, id := range moves {
+		if _, err := tx.Exec(`UPDATE ingredient_conversions SET ingredient_id = ? WHERE id = ?`, into.id, id); err != nil {
+			return nil, err
+		}
+	}
+	res.Conversions = len(moves)
+	for _, c := range conflicts {
+		res.Conflicts = append(res.Conflicts, fmt.Sprintf("%s: %s dropped; %s keeps %s",
+			from.name, c.merged, into.name, c.kept))
+	}
+
+	if res.Prices, err = execCount(tx, `
+		INSERT INTO ingredient_prices (ingredient_id, cost_per_unit, effective_date, supplier, source, created_at)
+		SELECT ?, p.cost_per_unit / ?, p.effective_date, p.supplier, p.source, p.created_at
+		FROM ingredient_prices p
+		WHERE p.ingredient_id = ? AND NOT EXISTS (
+			SELECT 1 FROM ingredient_prices q
+			WHERE q.ingredient_id = ? AND q.effective_date = p.effective_date
+		)
+	`, into.id, factor, from.id, into.id); err != nil {
+		return nil, err
+	}
+
+	if res.StockCounts, err = execCount(tx, `
+		UPDATE stock_counts SET ingredient_id = ?, qty = qty * ? WHERE ingredient_id = ?
+	`, into.id, factor, from.id); err != nil {
+		return nil, err
+	}
+
+	if res.SupplierLinks, err = execCount(tx, `
+		UPDATE ingredient_suppliers
+		SET ingredient_id = ?,
+		    preferred = CASE WHEN EXISTS (
+		        SELECT 1 FROM ingredient_suppliers WHERE ingredient_id = ? AND preferred = 1
+		    ) THEN 0 ELSE preferred END
+		WHERE ingredient_id = ? AND supplier_id NOT IN (
+			SELECT supplier_id FROM ingredient_suppliers WHERE ingredient_id = ?
+		)
+	`, into.id, into.id, from.id, into.id); err != nil {
+		return nil, err
+	}
+
+	for _, q := range []string{
+		`DELETE FROM ingredient_prices WHERE ingredient_id = ?`,
+		`DELETE FROM ingredient_conversions WHERE ingredient_id = ?`,
+		`DELETE FROM ingredient_suppliers WHERE ingredient_id = ?`,
+		`DELETE FROM ingredients WHERE id = ?`,
+	} {
+		if _, err := tx.Exec(q, from.id); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	for name := range recipes {
+		res.Recipes = append(res.Recipes, name)
+	}
+	sort.Strings(res.Recipes)
+	// A recipe with several lines of the merged ingredient appears once.
+	res.Summed = slices.Compact(res.Summed)
+	res.Duplicates = slices.CompactFunc(res.Duplicates, func(a, b MergeDuplicate) bool { return a.Recipe == b.Recipe })
+	res.Converted = slices.Compact(res.Converted)
+	return res, nil
+}
+
+// mergeFactor returns how many of into's units make one of from's, using
+// the standard units or either ingredient's conversions. The merged
+// ingredient's own conversions come first: its prices and stock were
+// recorded by them.
+func mergeFactor(tx *sql.Tx, from, into ingredientRef) (float64, error) {
+	if f, err := convertInTx(tx, from.id, 1, from.unit, into.unit); err == nil {
+		return f, nil
+	}
+	if f, err := convertInTx(tx, into.id, 1, from.unit, into.unit); err == nil {
+		return f, nil
+	}
+	return 0, fmt.Errorf("%w: %s is in %s and %s in %s; add a conversion first",
+		ErrNoConversion, from.name, from.unit, into.name, into.unit)
+}
+
+// mergeLineQty converts a line of the merged ingredient into unit of the
+// kept one: line unit → from's unit → into's unit → unit.
+func mergeLineQty(tx *sql.Tx, from, into ingredientRef, factor float64, l mergeLine, unit string) (float64, error) {
+	base, err := convertInTx(tx, from.id, l.qty, l.unit, from.unit)
+	if err != nil {
+		return 0, err
+	}
+	return convertInTx(tx, into.id, base*factor, into.unit, unit)
+}
+
+// convertInTx is ConvertIngredientQty reading conversions through tx.
+func convertInTx(tx *sql.Tx, ingredientID int, qty float64, fromUnit, toUnit string) (float64, error) {
+	load := func(id int) ([]Conversion, error) { return loadConversions(tx, id) }
+	return convertQty(load, ingredientID, qty, fromUnit, toUnit)
+}
+
+// conversionConflict is a merged conversion between the same two units as
+// a kept one, giving a different quantity.
+type conversionConflict struct {
+	merged, kept conversionText
+}
+
+// conversionText prints a conversion as "1 piece = 0.2 kg".
+type conversionText Conversion
+
+func (c conversionText) String() string {
+	return fmt.Sprintf("%g %s = %g %s", c.FromQty, c.FromUnit, c.ToQty, c.ToUnit)
+}
+
+// planConversionMerge decides which of from's conversion rows move to into:
+// those between units into has no conversion for. The rest are dropped;
+// the ones that disagree with into's are returned as conflicts.
+func planConversionMerge(tx *sql.Tx, from, into ingredientRef) (moves []int, conflicts []conversionConflict, err error) {
+	kept, err := loadConversions(tx, into.id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := tx.Query(`
+		SELECT id, from_qty, from_unit, to_qty, to_unit
+		FROM ingredient_conversions
+		WHERE ingredient_id = ?
+		ORDER BY id
+	`, from.id)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var c Conversion
+		if err := rows.Scan(&id, &c.FromQty, &c.FromUnit, &c.ToQty, &c.ToUnit); err != nil {
+			return nil, nil, err
+		}
+		match := -1
+		for i, k := range kept {
+			if sameConversionUnits(c, k) {
+				match = i
+				break
+			}
+		}
+		switch {
+		case match < 0:
+			moves = append(moves, id)
+		case !sameConversionRate(c, kept[match]):
+			conflicts = append(conflicts, conversionConflict{merged: conversionText(c), kept: conversionText(kept[match])})
+		}
+	}
+	return moves, conflicts, rows.Err()
+}
+
+// sameConversionUnits reports whether a and b convert between the same two
+// units, in either direction.
+func sameConversionUnits(a, b Conversion) bool {
+	af, at := CanonicalUnit(a.FromUnit), CanonicalUnit(a.ToUnit)
+	bf, bt := CanonicalUnit(b.FromUnit), CanonicalUnit(b.ToUnit)
+	return af == bf && at == bt || af == bt && at == bf
+}
+
+// sameConversionRate reports whether two conversions between the same
+// units agree.
+func sameConversionRate(a, b Conversion) bool {
+	if a.FromQty <= 0 || a.ToQty <= 0 || b.FromQty <= 0 || b.ToQty <= 0 {
+		return false
+	}
+	ra := a.ToQty / a.FromQty
+	rb := b.ToQty / b.FromQty
+	if CanonicalUnit(a.FromUnit) != CanonicalUnit(b.FromUnit) {
+		rb = b.FromQty / b.ToQty
+	}
+	return math.Abs(ra-rb) <= 1e-9*math.Max(ra, rb)
+}
+
+func loadMergeLines(q queryer, from ingredientRef) ([]mergeLine, error) {
+	rows, err := q.Query(`
+		SELECT ri.id, ri.recipe_id, r.name, ri.qty, COALESCE(NULLIF(ri.unit, ''), ?)
+		FROM recipe_items ri
+		JOIN recipes r ON r.id = ri.recipe_id
+		WHERE ri.ingredient_id = ?
+		ORDER BY r.name, ri.id
+	`, from.unit, from.id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []mergeLine
+	for rows.Next() {
+		var l mergeLine
+		if err := rows.Scan(&l.id, &l.recipeID, &l.recipe, &l.qty, &l.unit); err != nil {
+			return nil, err
+		}
+		lines = append(lines, l)
+	}
+	return lines, rows.Err()
+}
+
+// execCount runs a statement and returns the number of rows it changed.
+func execCount(tx *sql.Tx, query string, args ...any) (int, error) {
+	r, err := tx.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := r.RowsAffected()
+	return int(n), err
+}
+
+// ingredientNameFree returns ErrIngredientExists when another ingredient
+// (not exceptID) already has name, compared case-insensitively.
+func ingredientNameFree(q queryer, name string, exceptID int) error {
+	var n int
+	if err := q.QueryRow(`SELECT COUNT(*) FROM ingredients WHERE LOWER(name) = LOWER(?) AND id <> ?`, name, exceptID).Scan(&n); err != nil {
+		return err
+	}
+	if n > 0 {
+		return fmt.Errorf("%w: %s", ErrIngredientExists, name)
+	}
+	return nil
+}
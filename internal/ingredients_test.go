@@ -0,0 +1,145 @@
+package internal
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestMergeKeepsQuantitiesWhenConversionsDisagree(t *testing.T) {
+	db, err := CreateDBAt(filepath.Join(t.TempDir(), "chefops.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	// With one connection, a read outside the merge transaction would
+	// block forever instead of passing unnoticed.
+	db.SetMaxOpenConns(1)
+
+	store := NewSQLStore(db)
+	lemons, err := store.SaveIngredient("Lemons", "kg", 4, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lemon, err := store.SaveIngredient("Lemon", "kg", 4, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.AddConversion(Conversion{IngredientID: lemons, FromQty: 1, FromUnit: "piece", ToQty: 0.2, ToUnit: "kg"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.AddConversion(Conversion{IngredientID: lemon, FromQty: 1, FromUnit: "piece", ToQty: 0.25, ToUnit: "kg"}); err != nil {
+		t.Fatal(err)
+	}
+	juice, err := store.SaveRecipe(Recipe{Name: "SUB Juice", YieldQty: 1, YieldUnit: "liter"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.AddIngredientLine(juice, lemons, 5, "piece"); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := MergeIngredients(db, lemons, lemon)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Conflicts) != 1 || res.Conversions != 0 {
+		t.Errorf("conflicts %q, %d conversion(s) moved; want 1 conflict, none moved", res.Conflicts, res.Conversions)
+	}
+	if len(res.Converted) != 1 || res.Converted[0] != "SUB Juice" {
+		t.Errorf("converted = %q, want SUB Juice", res.Converted)
+	}
+
+	conversions, err := store.Conversions(lemon)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conversions) != 1 || conversions[0].ToQty != 0.25 {
+		t.Errorf("Lemon conversions = %+v, want only its own 1 piece = 0.25 kg", conversions)
+	}
+	// 5 pieces at 0.2 kg, not re-read at Lemon's 0.25 kg.
+	rc, err := NewCoster(db).Cost(juice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !near(rc.TotalCost, 4) {
+		t.Errorf("SUB Juice costs %v after the merge, want 4", rc.TotalCost)
+	}
+}
+
+func TestMergeRescalesPricesToKeptUnit(t *testing.T) {
+	db, err := CreateDBAt(filepath.Join(t.TempDir(), "chefops.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	store := NewSQLStore(db)
+	sugar, err := store.SaveIngredient("Sugar", "g", 0.002, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	caster, err := store.SaveIngredient("Caster Sugar", "kg", 2.5, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordPrice(db, sugar, 0.0015, "2026-01-01", "", "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := MergeIngredients(db, sugar, caster)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Prices != 1 {
+		t.Errorf("%d price(s) moved, want only the 2026-01-01 one", res.Prices)
+	}
+	// 0.0015 per g is 1.50 per kg.
+	price, err := PriceAsOf(db, caster, "2026-01-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !near(price, 1.5) {
+		t.Errorf("Caster Sugar on 2026-01-01 = %v per kg, want 1.5", price)
+	}
+}
+
+func TestMergeExplainsDuplicateLines(t *testing.T) {
+	db, err := CreateDBAt(filepath.Join(t.TempDir(), "chefops.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	store := NewSQLStore(db)
+	lemon, err := store.SaveIngredient("Lemon", "kg", 4, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lemons, err := store.SaveIngredient("Lemons", "kg", 4, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	juice, err := store.SaveRecipe(Recipe{Name: "SUB Juice", YieldQty: 1, YieldUnit: "liter"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.AddIngredientLine(juice, lemons, 1, "kg"); err != nil {
+		t.Fatal(err)
+	}
+	// A legacy line in a unit Lemon has no conversion for.
+	if _, err := db.Exec(`INSERT INTO recipe_items (recipe_id, ingredient_id, qty, unit) VALUES (?, ?, 2, 'bunch')`, juice, lemon); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := MergeIngredients(db, lemon, lemons)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Duplicates) != 1 || res.Duplicates[0].Recipe != "SUB Juice" {
+		t.Fatalf("duplicates = %+v, want SUB Juice", res.Duplicates)
+	}
+	if !errors.Is(res.Duplicates[0].Err, ErrNoConversion) {
+		t.Errorf("duplicate reason = %v, want %v", res.Duplicates[0].Err, ErrNoConversion)
+	}
+}
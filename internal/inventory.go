@@ -0,0 +1,125 @@
+package internal
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// DefaultLocation is used for stock counts without a location.
+const DefaultLocation = "main"
+
+// TimestampLayout is the format of counted_at and similar timestamps.
+const TimestampLayout = "2006-01-02 15:04:05"
+
+// StockLevel is the latest count of one ingredient at one location.
+type StockLevel struct {
+	IngredientID int
+	Name         string
+	Location     string
+	Qty          float64
+	Unit         string
+	CountedAt    string
+}
+
+// ParseTimestamp accepts "YYYY-MM-DD HH:MM[:SS]" or "YYYY-MM-DD" (start of
+// day). An empty string means now.
+func ParseTimestamp(s string) (string, error) {
+	if s == "" {
+		return time.Now().Format(TimestampLayout), nil
+	}
+	for _, layout := range []string{TimestampLayout, "2006-01-02 15:04", "2006-01-02T15:04:05", DateLayout} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t.Format(TimestampLayout), nil
+		}
+	}
+	return "", fmt.Errorf("invalid time %q (expected YYYY-MM-DD or YYYY-MM-DD HH:MM)", s)
+}
+
+// RecordStockCount stores a count of qty, given in unit, for an ingredient
+// at location. The quantity is converted to the ingredient's unit; an empty
+// unit means the ingredient's unit.
+func RecordStockCount(db *sql.DB, ingredientID int, qty float64, unit, location, countedAt, note string) (float64, error) {
+	var name, baseUnit string
+	if err := db.QueryRow(`SELECT name, unit FROM ingredients WHERE id = ?`, ingredientID).Scan(&name, &baseUnit); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, fmt.Errorf("ingredient with ID %d not found", ingredientID)
+		}
+		return 0, err
+	}
+
+	if unit == "" {
+		unit = baseUnit
+	}
+	baseQty, err := ConvertIngredientQty(db, ingredientID, qty, unit, baseUnit)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", name, err)
+	}
+	if location == "" {
+		location = DefaultLocation
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO stock_counts (ingredient_id, location, qty, counted_at, note)
+		VALUES (?, ?, ?, ?, NULLIF(?, ''))
+	`, ingredientID, location, baseQty, countedAt, note)
+	if err != nil {
+		return 0, fmt.Errorf("recording stock count: %w", err)
+	}
+	return baseQty, nil
+}
+
+// StockLevels returns the latest count per ingredient and location,
+// optionally for one location only.
+func StockLevels(db *sql.DB, location string) ([]StockLevel, error) {
+	rows, err := db.Query(`
+		SELECT ingredient_id, ingredient_name, location, qty, unit, counted_at
+		FROM stock_on_hand
+		WHERE ? = '' OR location = ?
+		ORDER BY ingredient_name, location
+	`, location, location)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []StockLevel
+	for rows.Next() {
+		var s StockLevel
+		if err := rows.Scan(&s.IngredientID, &s.Name, &s.Location, &s.Qty, &s.Unit, &s.CountedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, s)
+	}
+	return list, rows.Err()
+}
+
+// OnHand returns the stock on hand per ingredient ID, summed over all
+// locations, in the ingredient's unit.
+func OnHand(db *sql.DB) (map[int]float64, error) {
+	levels, err := StockLevels(db, "")
+	if err != nil {
+		return nil, err
+	}
+	onHand := make(map[int]float64)
+	for _, s := range levels {
+		onHand[s.IngredientID] += s.Qty
+	}
+	return onHand, nil
+}
+
+// NetOfStock returns copies of items with on-hand stock subtracted.
+// Ingredients that are fully covered are left out.
+func NetOfStock(items []*IngredientUsage, onHand map[int]float64) []*IngredientUsage {
+	var net []*IngredientUsage
+	for _, it := range items {
+		need := it.Qty - onHand[it.IngredientID]
+		if need <= packEpsilon {
+			continue
+		}
+		n := *it
+		n.Qty = need
+		net = append(net, &n)
+	}
+	return net
+}
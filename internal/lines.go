@@ -0,0 +1,189 @@
+package internal
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Recipe line types, as in Line.Type and CostLine.Type.
+const (
+	LineIngredient = "ingredient"
+	LineSubrecipe  = "subrecipe"
+)
+
+// LineRecipe returns the recipe a line belongs to.
+func LineRecipe(db *sql.DB, lineType string, lineID int) (int, error) {
+	table, err := lineTable(lineType)
+	if err != nil {
+		return 0, err
+	}
+	var recipeID int
+	err = db.QueryRow(`SELECT recipe_id FROM `+table+` WHERE id = ?`, lineID).Scan(&recipeID)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("%s line %d %w", lineType, lineID, ErrNotFound)
+	}
+	return recipeID, err
+}
+
+// AddIngredientLine adds qty of an ingredient to a recipe and returns the
+// new line's ID. An empty unit means the ingredient's own unit; any other
+// unit must convert to it.
+func AddIngredientLine(db *sql.DB, recipeID, ingredientID int, qty float64, unit string) (int, error) {
+	s := NewSQLStore(db)
+	if _, err := s.Recipe(recipeID); err != nil {
+		return 0, err
+	}
+	unit, err := checkIngredientLine(s, ingredientID, qty, unit)
+	if err != nil {
+		return 0, err
+	}
+	return insertID(db, `
+		INSERT INTO recipe_items (recipe_id, ingredient_id, qty, unit)
+		VALUES (?, ?, ?, ?)
+	`, recipeID, ingredientID, qty, unit)
+}
+
+// AddSubrecipeLine adds qty of a subrecipe to a recipe and returns the new
+// line's ID. An empty unit means the subrecipe's yield unit. It refuses
+// lines that would make a subrecipe cycle.
+func AddSubrecipeLine(db *sql.DB, recipeID, subID int, qty float64, unit string) (int, error) {
+	s := NewSQLStore(db)
+	if _, err := s.Recipe(recipeID); err != nil {
+		return 0, err
+	}
+	if err := CheckSubrecipe(db, recipeID, subID); err != nil {
+		return 0, err
+	}
+	unit, err := checkSubrecipeLine(s, subID, qty, unit)
+	if err != nil {
+		return 0, err
+	}
+	return insertID(db, `
+		INSERT INTO recipe_subrecipes (recipe_id, subrecipe_id, qty, unit)
+		VALUES (?, ?, ?, ?)
+	`, recipeID, subID, qty, unit)
+}
+
+// UpdateLine sets the quantity and unit of a recipe line. An empty unit
+// keeps the line's current unit.
+func UpdateLine(db *sql.DB, lineType string, lineID int, qty float64, unit string) error {
+	table, err := lineTable(lineType)
+	if err != nil {
+		return err
+	}
+	column := "ingredient_id"
+	if lineType == LineSubrecipe {
+		column = "subrecipe_id"
+	}
+
+	var itemID int
+	var current sql.NullString
+	err = db.QueryRow(`SELECT `+column+`, unit FROM `+table+` WHERE id = ?`, lineID).Scan(&itemID, &current)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("%s line %d %w", lineType, lineID, ErrNotFound)
+	} else if err != nil {
+		return err
+	}
+	if unit == "" {
+		unit = current.String
+	}
+
+	s := NewSQLStore(db)
+	if lineType == LineSubrecipe {
+		unit, err = checkSubrecipeLine(s, itemID, qty, unit)
+	} else {
+		unit, err = checkIngredientLine(s, itemID, qty, unit)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`UPDATE `+table+` SET qty = ?, unit = ? WHERE id = ?`, qty, unit, lineID)
+	return err
+}
+
+// DeleteLine removes a recipe line.
+func DeleteLine(db *sql.DB, lineType string, lineID int) error {
+	table, err := lineTable(lineType)
+	if err != nil {
+		return err
+	}
+	n, err := db.Exec(`DELETE FROM `+table+` WHERE id = ?`, lineID)
+	if err != nil {
+		return err
+	}
+	if rows, _ := n.RowsAffected(); rows == 0 {
+		return fmt.Errorf("%s line %d %w", lineType, lineID, ErrNotFound)
+	}
+	return nil
+}
+
+func lineTable(lineType string) (string, error) {
+	switch lineType {
+	case LineIngredient:
+		return "recipe_items", nil
+	case LineSubrecipe:
+		return "recipe_subrecipes", nil
+	}
+	return "", fmt.Errorf("line type must be %s or %s, not %q", LineIngredient, LineSubrecipe, lineType)
+}
+
+// checkIngredientLine validates a line of an ingredient and returns the
+// unit to store.
+func checkIngredientLine(s Store, ingredientID int, qty float64, unit string) (string, error) {
+	if qty <= 0 {
+		return "", fmt.Errorf("quantity must be positive")
+	}
+	ing, err := s.Ingredient(ingredientID)
+	if err != nil {
+		return "", err
+	}
+	if unit == "" {
+		return ing.Unit, nil
+	}
+	if _, err := ConvertQty(s, ingredientID, qty, unit, ing.Unit); err != nil {
+		return "", fmt.Errorf("%s is costed per %s: %w", ing.Name, ing.Unit, err)
+	}
+	return unit, nil
+}
+
+// checkSubrecipeLine validates a line of a subrecipe and returns the unit
+// to store.
+func checkSubrecipeLine(s Store, subID int, qty float64, unit string) (string, error) {
+	if qty <= 0 {
+		return "", fmt.Errorf("quantity must be positive")
+	}
+	sub, err := s.Recipe(subID)
+	if err != nil {
+		return "", err
+	}
+	return checkYieldUnit(sub, qty, unit)
+}
+
+// checkYieldUnit returns the unit to store for qty of sub: unit itself
+// when it converts to one of sub's yields, or the yield unit when empty.
+func checkYieldUnit(sub Recipe, qty float64, unit string) (string, error) {
+	if unit == "" {
+		return sub.YieldUnit, nil
+	}
+	rc := &RecipeCost{
+		Name:               sub.Name,
+		YieldQty:           sub.YieldQty,
+		YieldUnit:          sub.YieldUnit,
+		SecondaryYieldQty:  sub.SecondaryYieldQty,
+		SecondaryYieldUnit: sub.SecondaryYieldUnit,
+	}
+	if _, err := ConvertYieldQty(rc, qty, unit); err != nil {
+		return "", err
+	}
+	return unit, nil
+}
+
+// insertID runs an INSERT and returns the new row's ID.
+func insertID(db *sql.DB, query string, args ...any) (int, error) {
+	r, err := db.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	id, err := r.LastInsertId()
+	return int(id), err
+}
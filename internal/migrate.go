@@ -0,0 +1,328 @@
+package internal
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migration is one numbered schema change embedded in the binary, loaded
+// from internal/migrations/NNNN_name.sql.
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// AppliedMigration is a row of schema_migrations.
+type AppliedMigration struct {
+	Version   int
+	Name      string
+	AppliedAt string
+}
+
+// Migrations returns the embedded migrations in version order.
+func Migrations() ([]Migration, error) {
+	entries, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	var list []Migration
+	for _, e := range entries {
+		file := e.Name()
+		base := strings.TrimSuffix(file, ".sql")
+		num, name, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %s: expected NNNN_name.sql", file)
+		}
+		version, err := strconv.Atoi(num)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: bad version: %w", file, err)
+		}
+
+		data, err := migrationFiles.ReadFile(path.Join("migrations", file))
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, Migration{Version: version, Name: name, SQL: string(data)})
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	for i := 1; i < len(list); i++ {
+		if list[i].Version == list[i-1].Version {
+			return nil, fmt.Errorf("duplicate migration version %d", list[i].Version)
+		}
+	}
+	return list, nil
+}
+
+// SchemaVersion returns the highest applied migration, or 0 for a database
+// that has never been migrated.
+func SchemaVersion(db *sql.DB) (int, error) {
+	if err := ensureMigrationTable(db); err != nil {
+		return 0, err
+	}
+	var v sql.NullInt64
+	if err := db.QueryRow(`SELECT MAX(version) FROM schema_migrations`).Scan(&v); err != nil {
+		return 0, err
+	}
+	return int(v.Int64), nil
+}
+
+// AppliedMigrations lists the migrations recorded in the database.
+func AppliedMigrations(db *sql.DB) ([]AppliedMigration, error) {
+	if err := ensureMigrationTable(db); err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(`SELECT version, name, applied_at FROM schema_migrations ORDER BY version`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []AppliedMigration
+	for rows.Next() {
+		var m AppliedMigration
+		if err := rows.Scan(&m.Version, &m.Name, &m.AppliedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, m)
+	}
+	return list, rows.Err()
+}
+
+// PendingMigrations returns the embedded migrations newer than the database.
+func PendingMigrations(db *sql.DB) ([]Migration, error) {
+	current, err := SchemaVersion(db)
+	if err != nil {
+		return nil, err
+	}
+	all, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, m := range all {
+		if m.Version > current {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// Migrate applies every pending migration, each in its own transaction, and
+// returns the ones it applied. Databases created before versioning (tables
+// present, no schema_migrations rows) are first brought in line with the
+// base schema.
+func Migrate(db *sql.DB) ([]Migration, error) {
+	pending, err := PendingMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+	if len(pending) == 0 {
+		return nil, nil
+	}
+
+	current, err := SchemaVersion(db)
+	if err != nil {
+		return nil, err
+	}
+	if current == 0 {
+		if err := adoptLegacySchema(db); err != nil {
+			return nil, fmt.Errorf("upgrading unversioned database: %w", err)
+		}
+	}
+
+	var applied []Migration
+	for _, m := range pending {
+		if err := applyMigration(db, m); err != nil {
+			return applied, fmt.Errorf("migration %04d_%s: %w", m.Version, m.Name, err)
+		}
+		applied = append(applied, m)
+	}
+	return applied, nil
+}
+
+// migrationFuncs are data fixes that need more than SQL. Each runs after
+// the SQL of the migration with the same version, in its transaction.
+var migrationFuncs = map[int]func(tx *sql.Tx) error{
+	11: fixLegacySubrecipeUnits,
+}
+
+func applyMigration(db *sql.DB, m Migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(m.SQL); err != nil {
+		return err
+	}
+	if fn := migrationFuncs[m.Version]; fn != nil {
+		if err := fn(tx); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO schema_migrations (version, name, applied_at)
+		VALUES (?, ?, ?)
+	`, m.Version, m.Name, time.Now().Format("2006-01-02 15:04:05")); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func ensureMigrationTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+		    version INTEGER PRIMARY KEY,
+		    name TEXT NOT NULL,
+		    applied_at TEXT NOT NULL
+		)
+	`)
+	return err
+}
+
+// adoptLegacySchema patches databases built from the old schema.sql so the
+// base migration's CREATE TABLE IF NOT EXISTS statements line up with them:
+// recipes.metadata and recipe_items.unit are added, and the old single
+// factor column of ingredient_conversions becomes from_qty/to_qty.
+func adoptLegacySchema(db *sql.DB) error {
+	exists, err := tableExists(db, "recipes")
+	if err != nil || !exists {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := addColumnIfMissing(tx, "recipes", "metadata", "TEXT"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(tx, "recipe_items", "unit", "TEXT"); err != nil {
+		return err
+	}
+
+	convExists, err := tableExists(tx, "ingredient_conversions")
+	if err != nil {
+		return err
+	}
+	if convExists {
+		cols, err := tableColumns(tx, "ingredient_conversions")
+		if err != nil {
+			return err
+		}
+		if cols["factor"] && !cols["from_qty"] {
+			if _, err := tx.Exec(`
+				CREATE TABLE ingredient_conversions_new (
+				    id INTEGER PRIMARY KEY AUTOINCREMENT,
+				    ingredient_id INTEGER NOT NULL,
+				    from_qty REAL NOT NULL DEFAULT 1,
+				    from_unit TEXT NOT NULL,
+				    to_qty REAL NOT NULL,
+				    to_unit TEXT NOT NULL,
+				    FOREIGN KEY(ingredient_id) REFERENCES ingredients(id) ON DELETE CASCADE
+				);
+				INSERT INTO ingredient_conversions_new (id, ingredient_id, from_qty, from_unit, to_qty, to_unit)
+				SELECT id, ingredient_id, 1, from_unit, factor, to_unit FROM ingredient_conversions;
+				DROP TABLE ingredient_conversions;
+				ALTER TABLE ingredient_conversions_new RENAME TO ingredient_conversions;
+			`); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx.
+type queryer interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx.
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+func tableExists(q queryer, table string) (bool, error) {
+	var n int
+	err := q.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, table).Scan(&n)
+	return n > 0, err
+}
+
+func tableColumns(q queryer, table string) (map[string]bool, error) {
+	rows, err := q.Query(`SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cols := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		cols[name] = true
+	}
+	return cols, rows.Err()
+}
+
+func addColumnIfMissing(tx *sql.Tx, table, column, decl string) error {
+	exists, err := tableExists(tx, table)
+	if err != nil || !exists {
+		return err
+	}
+	cols, err := tableColumns(tx, table)
+	if err != nil {
+		return err
+	}
+	if cols[column] {
+		return nil
+	}
+	_, err = tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, decl))
+	return err
+}
+
+// fixLegacySubrecipeUnits gives subrecipe lines saved with the parent's
+// yield unit the subrecipe's yield unit instead (see legacySubrecipeUnit),
+// so they cost as they did before units were checked.
+func fixLegacySubrecipeUnits(tx *sql.Tx) error {
+	recipes, err := loadYieldRecipes(tx)
+	if err != nil {
+		return err
+	}
+	lines, err := loadSubrecipeLines(tx)
+	if err != nil {
+		return err
+	}
+	for _, l := range lines {
+		parent, sub := recipes[l.recipeID], recipes[l.subID]
+		if parent == nil || sub == nil {
+			continue
+		}
+		if unit, ok := legacySubrecipeUnit(parent, sub, l); ok {
+			if _, err := tx.Exec(`UPDATE recipe_subrecipes SET unit = ? WHERE id = ?`, unit, l.id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package internal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// openLegacyCopy opens a copy of the shipped db/chefops.db, which predates
+// schema versioning and unit checks.
+func openLegacyCopy(t *testing.T) *Coster {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("..", "db", "chefops.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "chefops.db")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	db, err := OpenDBAt(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return NewCoster(db)
+}
+
+func TestLegacyDatabaseCostsDishes(t *testing.T) {
+	c := openLegacyCopy(t)
+
+	recipes, err := c.store.Recipes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recipes) == 0 {
+		t.Fatal("no recipes in db/chefops.db")
+	}
+	for _, r := range recipes {
+		if _, err := c.Cost(r.ID); err != nil {
+			t.Errorf("costing %s: %v", r.Name, err)
+		}
+	}
+
+	dish, err := c.store.RecipeByName("DISH Lobster Roll")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines, err := c.store.Lines(dish.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range lines {
+		if l.Type == LineSubrecipe && l.Unit != "kg" {
+			t.Errorf("%s line unit = %q, want the subrecipe's yield unit kg", l.Name, l.Unit)
+		}
+	}
+}
+
+func TestOpenDoesNotCreateDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "typo", "chefops.db")
+	if _, err := OpenDBAt(path); !errors.Is(err, ErrNoDatabase) {
+		t.Fatalf("OpenDBAt(%s) = %v, want ErrNoDatabase", path, err)
+	}
+	if _, err := os.Stat(filepath.Dir(path)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("OpenDBAt created %s", filepath.Dir(path))
+	}
+
+	db, err := CreateDBAt(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+	if db, err = OpenDBAt(path); err != nil {
+		t.Fatalf("OpenDBAt after CreateDBAt: %v", err)
+	}
+	db.Close()
+}
@@ -1,6 +1,4 @@
--- FILE START: db/schema.sql
-
-PRAGMA foreign_keys = ON;
+-- 0001: base schema
 
 -- --------------------------
 -- INGREDIENTS
@@ -23,18 +21,21 @@ CREATE TABLE IF NOT EXISTS recipes (
     yield_unit TEXT NOT NULL,
     secondary_yield_qty REAL,
     secondary_yield_unit TEXT,
-    notes TEXT
+    notes TEXT,
+    metadata TEXT
 );
 
 -- --------------------------
 -- RECIPE ITEMS (Ingredients inside Recipes)
--- NO UNIT COLUMN HERE
+-- unit is optional: NULL means the ingredient's own unit,
+-- anything else is resolved through ingredient_conversions
 -- --------------------------
 CREATE TABLE IF NOT EXISTS recipe_items (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
     recipe_id INTEGER NOT NULL,
     ingredient_id INTEGER NOT NULL,
     qty REAL NOT NULL,
+    unit TEXT,
     FOREIGN KEY(recipe_id) REFERENCES recipes(id) ON DELETE CASCADE,
     FOREIGN KEY(ingredient_id) REFERENCES ingredients(id)
 );
@@ -54,14 +55,15 @@ CREATE TABLE IF NOT EXISTS recipe_subrecipes (
 
 -- --------------------------
 -- OPTIONAL INGREDIENT CONVERSIONS
+-- from_qty from_unit = to_qty to_unit (e.g. 1 kg = 10 piece)
 -- --------------------------
 CREATE TABLE IF NOT EXISTS ingredient_conversions (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
     ingredient_id INTEGER NOT NULL,
+    from_qty REAL NOT NULL DEFAULT 1,
     from_unit TEXT NOT NULL,
+    to_qty REAL NOT NULL,
     to_unit TEXT NOT NULL,
-    factor REAL NOT NULL,
     FOREIGN KEY(ingredient_id) REFERENCES ingredients(id) ON DELETE CASCADE
 );
 
--- FILE END
@@ -1,5 +1,4 @@
--- FILE START: views.sql
-PRAGMA foreign_keys = ON;
+-- 0002: reporting views
 
 ------------------------------------------------------------
 -- VIEW 1: RECIPE RAW LINES (ingredients + subrecipes)
@@ -186,4 +185,3 @@ ORDER BY ing.name;
 
 /* market_list(ingredient_id,ingredient_name,unit,cost_per_unit,total_qty,total_cost) */
 
--- FILE END: views.sql
@@ -0,0 +1,23 @@
+-- 0003: ingredient price history
+--
+-- Every price an ingredient has had, with the date it took effect.
+-- ingredients.cost_per_unit keeps the price in effect today.
+
+CREATE TABLE IF NOT EXISTS ingredient_prices (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    ingredient_id INTEGER NOT NULL,
+    cost_per_unit REAL NOT NULL,
+    effective_date TEXT NOT NULL,      -- YYYY-MM-DD
+    supplier TEXT,
+    source TEXT,                       -- e.g. invoice number, "import", "ingredient add"
+    created_at TEXT NOT NULL DEFAULT (datetime('now')),
+    FOREIGN KEY(ingredient_id) REFERENCES ingredients(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_ingredient_prices_lookup
+    ON ingredient_prices (ingredient_id, effective_date);
+
+-- Seed the history with the prices known today.
+INSERT INTO ingredient_prices (ingredient_id, cost_per_unit, effective_date, source)
+SELECT id, cost_per_unit, date('now'), 'initial'
+FROM ingredients;
@@ -0,0 +1,27 @@
+-- 0004: suppliers and ingredient sourcing
+
+CREATE TABLE IF NOT EXISTS suppliers (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    contact TEXT,
+    lead_time_days INTEGER,
+    order_days TEXT,                   -- e.g. "mon,thu"
+    min_order REAL,                    -- minimum order value
+    notes TEXT
+);
+
+-- Which suppliers carry an ingredient, and in what pack.
+-- pack_qty/pack_unit is one purchasable pack, e.g. 25 kg.
+-- The preferred link decides the supplier section of the market list.
+CREATE TABLE IF NOT EXISTS ingredient_suppliers (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    ingredient_id INTEGER NOT NULL,
+    supplier_id INTEGER NOT NULL,
+    sku TEXT,
+    pack_qty REAL,
+    pack_unit TEXT,
+    preferred INTEGER NOT NULL DEFAULT 0,
+    UNIQUE(ingredient_id, supplier_id),
+    FOREIGN KEY(ingredient_id) REFERENCES ingredients(id) ON DELETE CASCADE,
+    FOREIGN KEY(supplier_id) REFERENCES suppliers(id) ON DELETE CASCADE
+);
@@ -0,0 +1,9 @@
+-- 0005: purchase units
+--
+-- The pack an ingredient is bought in (e.g. 5 liter, 100 g) and whether a
+-- partial pack can be ordered (loose produce by weight). A supplier's
+-- pack in ingredient_suppliers overrides it for that supplier.
+
+ALTER TABLE ingredients ADD COLUMN pack_qty REAL;
+ALTER TABLE ingredients ADD COLUMN pack_unit TEXT;
+ALTER TABLE ingredients ADD COLUMN partial_packs INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,38 @@
+-- 0006: inventory counts
+--
+-- Each row is a stock count of one ingredient at one location, in the
+-- ingredient's unit. The latest count per ingredient and location is what
+-- is on hand.
+
+CREATE TABLE IF NOT EXISTS stock_counts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    ingredient_id INTEGER NOT NULL,
+    location TEXT NOT NULL DEFAULT 'main',
+    qty REAL NOT NULL,
+    counted_at TEXT NOT NULL,          -- YYYY-MM-DD HH:MM:SS
+    note TEXT,
+    FOREIGN KEY(ingredient_id) REFERENCES ingredients(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_counts_latest
+    ON stock_counts (ingredient_id, location, counted_at);
+
+DROP VIEW IF EXISTS stock_on_hand;
+CREATE VIEW stock_on_hand AS
+SELECT
+    sc.ingredient_id,
+    i.name AS ingredient_name,
+    sc.location,
+    sc.qty,
+    i.unit,
+    sc.counted_at
+FROM stock_counts sc
+JOIN ingredients i ON i.id = sc.ingredient_id
+WHERE sc.id = (
+    SELECT s2.id
+    FROM stock_counts s2
+    WHERE s2.ingredient_id = sc.ingredient_id
+      AND s2.location = sc.location
+    ORDER BY s2.counted_at DESC, s2.id DESC
+    LIMIT 1
+);
@@ -0,0 +1,7 @@
+-- 0007: par levels
+--
+-- min_level: reorder when projected stock falls below this.
+-- par_level: order back up to this. Both in the ingredient's unit.
+
+ALTER TABLE ingredients ADD COLUMN min_level REAL;
+ALTER TABLE ingredients ADD COLUMN par_level REAL;
@@ -0,0 +1,32 @@
+-- 0008: events
+--
+-- An event runs over one or more days with expected covers per day and a
+-- menu of dishes. mix_pct is the share of covers expected to order a dish,
+-- so portions per day = covers * mix_pct / 100.
+
+CREATE TABLE IF NOT EXISTS events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    start_date TEXT NOT NULL,          -- YYYY-MM-DD
+    end_date TEXT NOT NULL,            -- YYYY-MM-DD
+    notes TEXT
+);
+
+CREATE TABLE IF NOT EXISTS event_days (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id INTEGER NOT NULL,
+    day TEXT NOT NULL,                 -- YYYY-MM-DD
+    covers REAL NOT NULL DEFAULT 0,
+    UNIQUE(event_id, day),
+    FOREIGN KEY(event_id) REFERENCES events(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS event_dishes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id INTEGER NOT NULL,
+    recipe_id INTEGER NOT NULL,
+    mix_pct REAL NOT NULL,
+    UNIQUE(event_id, recipe_id),
+    FOREIGN KEY(event_id) REFERENCES events(id) ON DELETE CASCADE,
+    FOREIGN KEY(recipe_id) REFERENCES recipes(id)
+);
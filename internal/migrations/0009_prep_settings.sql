@@ -0,0 +1,11 @@
+-- 0009: prep settings
+--
+-- prep_lead_days:  make this many days before it is used.
+-- batch_qty:       one batch, in the recipe's yield unit.
+-- shelf_life_days: days it keeps once made.
+-- station:         where it is made (e.g. Garde Manger, Hot Line).
+
+ALTER TABLE recipes ADD COLUMN prep_lead_days INTEGER;
+ALTER TABLE recipes ADD COLUMN batch_qty REAL;
+ALTER TABLE recipes ADD COLUMN shelf_life_days INTEGER;
+ALTER TABLE recipes ADD COLUMN station TEXT;
@@ -0,0 +1,24 @@
+-- 0010: recipe archive
+--
+-- archived_at: when the recipe was archived; NULL for active recipes.
+-- Archived recipes keep their lines for history but are left out of
+-- recipe lists and the market list.
+
+ALTER TABLE recipes ADD COLUMN archived_at TEXT;
+
+DROP VIEW IF EXISTS market_list;
+
+CREATE VIEW market_list AS
+SELECT
+    ing.id AS ingredient_id,
+    ing.name AS ingredient_name,
+    ing.unit AS unit,
+    ing.cost_per_unit,
+    SUM(exp.total_qty) AS total_qty,
+    SUM(exp.total_qty * ing.cost_per_unit) AS total_cost
+FROM recipe_items_expanded exp
+JOIN recipes r ON r.id = exp.recipe_id
+JOIN ingredients ing ON exp.ingredient_id = ing.id
+WHERE r.archived_at IS NULL
+GROUP BY ing.id, ing.name, ing.unit, ing.cost_per_unit
+ORDER BY ing.name;
@@ -0,0 +1,10 @@
+-- 0011: legacy subrecipe line units
+--
+-- Before units were checked, `recipe add-subrecipe` defaulted a line's unit
+-- to the parent recipe's yield unit, so a dish made by the portion stored
+-- "0.12 portion" of a sauce made by the kg. The quantity was always read in
+-- the subrecipe's yield unit. Lines like that now get the subrecipe's yield
+-- unit. Deciding which lines cannot convert needs the unit library, so the
+-- rewrite runs in Go (fixLegacySubrecipeUnits) after this file.
+
+SELECT 1;
@@ -0,0 +1,12 @@
+-- 0012: drop the SQL cost views
+--
+-- recipe_raw_lines, recipe_totals and recipe_items_expanded_detail_export
+-- priced a subrecipe from its direct ingredients only and ignored line
+-- units, so any recipe with nested subrecipes or converted units came out
+-- wrong. Costs come from the Go engine (internal/costing.go): `chefops
+-- recipe cost`, `chefops export recipe`, the HTTP API and the chefops
+-- package.
+
+DROP VIEW IF EXISTS recipe_totals;
+DROP VIEW IF EXISTS recipe_items_expanded_detail_export;
+DROP VIEW IF EXISTS recipe_raw_lines;
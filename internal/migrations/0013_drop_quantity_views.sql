@@ -0,0 +1,11 @@
+-- 0013: drop the SQL quantity views
+--
+-- recipe_items_expanded multiplied subrecipe lines straight into their
+-- parents without dividing by the subrecipe's yield or converting line
+-- units, and market_list summed those quantities. Both were wrong for any
+-- recipe with a subrecipe or a converted unit. Quantities come from the Go
+-- engine (internal/expand.go): `chefops marketlist`, `chefops forecast`
+-- and the HTTP API.
+
+DROP VIEW IF EXISTS market_list;
+DROP VIEW IF EXISTS recipe_items_expanded;
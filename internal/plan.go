@@ -0,0 +1,388 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// PlanEntry is one line of a forecast plan: portions of a dish, optionally
+// on a given day and at a given outlet.
+type PlanEntry struct {
+	Dish     string
+	RecipeID int // known for event plans; 0 means Dish is a name to resolve
+	Portions float64
+	Day      string
+	Outlet   string
+}
+
+// Plan is a forecast plan file: a list of dishes and portions, e.g. for a
+// whole race weekend.
+type Plan struct {
+	Name    string
+	Entries []PlanEntry
+}
+
+// HasDays reports whether any entry is assigned to a day.
+func (p *Plan) HasDays() bool {
+	for _, e := range p.Entries {
+		if e.Day != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// HasOutlets reports whether any entry is assigned to an outlet.
+func (p *Plan) HasOutlets() bool {
+	for _, e := range p.Entries {
+		if e.Outlet != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadPlan reads a plan file. Files ending in .csv are read as CSV,
+// .yaml/.yml as YAML.
+func LoadPlan(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var plan *Plan
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		plan, err = ParsePlanCSV(bytes.NewReader(data))
+	case ".yaml", ".yml":
+		plan, err = ParsePlanYAML(bytes.NewReader(data))
+	default:
+		return nil, fmt.Errorf("%s: plan must be .csv, .yaml or .yml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if plan.Name == "" {
+		plan.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if len(plan.Entries) == 0 {
+		return nil, fmt.Errorf("%s: plan has no dishes", path)
+	}
+	return plan, nil
+}
+
+// ParsePlanCSV reads a plan with a header row. Required columns: dish,
+// portions. Optional: day, outlet.
+//
+//	dish,portions,day,outlet
+//	DISH Lobster Roll,120,2026-12-05,Paddock Club
+func ParsePlanCSV(r io.Reader) (*Plan, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	cr.Comment = '#'
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	col := make(map[string]int)
+	for i, h := range header {
+		col[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	for _, required := range []string{"dish", "portions"} {
+		if _, ok := col[required]; !ok {
+			return nil, fmt.Errorf("missing %q column", required)
+		}
+	}
+	field := func(rec []string, name string) string {
+		i, ok := col[name]
+		if !ok || i >= len(rec) {
+			return ""
+		}
+		return strings.TrimSpace(rec[i])
+	}
+
+	plan := &Plan{}
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+
+		fields := map[string]string{
+			"dish":     field(rec, "dish"),
+			"portions": field(rec, "portions"),
+			"day":      field(rec, "day"),
+			"outlet":   field(rec, "outlet"),
+		}
+		if fields["dish"] == "" && fields["portions"] == "" {
+			continue
+		}
+		e, err := planEntryFromFields(fields)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		plan.Entries = append(plan.Entries, e)
+	}
+	return plan, nil
+}
+
+// ParsePlanYAML reads the YAML form of a plan. It is not a general YAML
+// parser; only the subset below is understood:
+//
+//   - top-level "name: value", a "defaults" block of "key: value" pairs
+//     applied to every dish (e.g. a single outlet), and a "dishes" list
+//     whose items are "key: value" pairs;
+//   - plain, 'single' or "double" quoted scalars on one line;
+//   - "#" comments, blank lines and a leading "---".
+//
+// Anything else is an error naming its line: tab indentation, flow style
+// ([...] or {...}), block scalars (| or >), anchors and aliases, and
+// nesting deeper than the keys of a list item.
+//
+//	name: F1 Weekend 2026
+//	defaults:
+//	  outlet: Paddock Club
+//	dishes:
+//	  - dish: DISH Lobster Roll
+//	    portions: 120
+//	    day: 2026-12-05
+func ParsePlanYAML(r io.Reader) (*Plan, error) {
+	plan := &Plan{}
+	defaults := map[string]string{}
+
+	var section string         // current top-level key holding a block
+	var blockIndent int        // indentation of its keys or list dashes
+	var item map[string]string // current "dishes" list item
+	var itemIndent int         // indentation of its keys
+	var itemLine int
+	var items []map[string]string
+	var lines []int
+
+	flush := func() {
+		if item != nil {
+			items = append(items, item)
+			lines = append(lines, itemLine)
+			item = nil
+		}
+	}
+
+	sc := bufio.NewScanner(r)
+	n := 0
+	for sc.Scan() {
+		n++
+		raw := stripYAMLComment(sc.Text())
+		if strings.TrimSpace(raw) == "" || strings.TrimSpace(raw) == "---" {
+			continue
+		}
+		indent := len(raw) - len(strings.TrimLeft(raw, " "))
+		text := strings.TrimSpace(raw)
+		if strings.HasPrefix(raw[indent:], "\t") {
+			return nil, fmt.Errorf("line %d: indent with spaces, not tabs", n)
+		}
+		if err := checkYAMLSubset(text); err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+
+		listItem := strings.HasPrefix(text, "- ") || text == "-"
+		if indent == 0 && !(section == "dishes" && listItem) {
+			flush()
+			key, value, err := splitYAMLPair(text)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+			section = ""
+			switch {
+			case value == "" && (key == "dishes" || key == "defaults"):
+				section = key
+				blockIndent = -1
+			case key == "name":
+				plan.Name = value
+			case key == "dishes" || key == "defaults":
+				return nil, fmt.Errorf("line %d: %q must be a block", n, key)
+			default:
+				return nil, fmt.Errorf("line %d: unknown key %q", n, key)
+			}
+			continue
+		}
+
+		switch section {
+		case "defaults":
+			if blockIndent < 0 {
+				blockIndent = indent
+			}
+			if indent != blockIndent {
+				return nil, fmt.Errorf("line %d: nested blocks are not supported; defaults are \"key: value\" lines", n)
+			}
+			key, value, err := splitYAMLValue(text)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+			defaults[key] = value
+
+		case "dishes":
+			if listItem {
+				if blockIndent < 0 {
+					blockIndent = indent
+				} else if indent != blockIndent {
+					return nil, fmt.Errorf("line %d: nested lists are not supported", n)
+				}
+				flush()
+				item = map[string]string{}
+				itemLine = n
+				rest := strings.TrimPrefix(text, "-")
+				text = strings.TrimSpace(rest)
+				itemIndent = -1
+				if text == "" {
+					continue
+				}
+				indent += 1 + len(rest) - len(strings.TrimLeft(rest, " "))
+			}
+			if item == nil {
+				return nil, fmt.Errorf("line %d: expected a list item (\"- dish: ...\")", n)
+			}
+			if itemIndent < 0 && indent > blockIndent {
+				itemIndent = indent
+			}
+			if indent != itemIndent {
+				return nil, fmt.Errorf("line %d: nested blocks are not supported; a dish is \"key: value\" lines indented like its first key", n)
+			}
+			key, value, err := splitYAMLValue(text)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+			item[key] = value
+
+		default:
+			return nil, fmt.Errorf("line %d: unexpected indentation", n)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+
+	for i, it := range items {
+		for k, v := range defaults {
+			if _, ok := it[k]; !ok {
+				it[k] = v
+			}
+		}
+		e, err := planEntryFromFields(it)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lines[i], err)
+		}
+		plan.Entries = append(plan.Entries, e)
+	}
+	return plan, nil
+}
+
+func planEntryFromFields(f map[string]string) (PlanEntry, error) {
+	for k := range f {
+		switch k {
+		case "dish", "portions", "day", "outlet":
+		default:
+			return PlanEntry{}, fmt.Errorf("unknown field %q", k)
+		}
+	}
+
+	e := PlanEntry{Dish: f["dish"], Outlet: f["outlet"]}
+	if e.Dish == "" {
+		return e, fmt.Errorf("missing dish")
+	}
+
+	portions, err := strconv.ParseFloat(f["portions"], 64)
+	if err != nil || portions <= 0 {
+		return e, fmt.Errorf("%s: portions must be a positive number, got %q", e.Dish, f["portions"])
+	}
+	e.Portions = portions
+
+	if f["day"] != "" {
+		day, err := ParseDate(f["day"])
+		if err != nil {
+			return e, fmt.Errorf("%s: %w", e.Dish, err)
+		}
+		e.Day = day
+	}
+	return e, nil
+}
+
+// splitYAMLPair splits "key: value" and unquotes the value.
+func splitYAMLPair(text string) (string, string, error) {
+	key, value, ok := strings.Cut(text, ":")
+	if !ok {
+		return "", "", fmt.Errorf("expected \"key: value\", got %q", text)
+	}
+	key = strings.TrimSpace(key)
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		if value[0] == '"' {
+			if u, err := strconv.Unquote(value); err == nil {
+				return key, u, nil
+			}
+		}
+		value = value[1 : len(value)-1]
+	}
+	return key, value, nil
+}
+
+// splitYAMLValue is splitYAMLPair for keys inside a block, which must have a
+// value on the same line.
+func splitYAMLValue(text string) (string, string, error) {
+	key, value, err := splitYAMLPair(text)
+	if err == nil && value == "" {
+		err = fmt.Errorf("%q has no value (nested blocks are not supported)", key)
+	}
+	return key, value, err
+}
+
+// checkYAMLSubset rejects YAML constructs ParsePlanYAML does not read,
+// rather than misreading them as plain text.
+func checkYAMLSubset(text string) error {
+	value := strings.TrimSpace(strings.TrimPrefix(text, "-"))
+	if _, v, ok := strings.Cut(value, ":"); ok && !strings.HasPrefix(value, "[") && !strings.HasPrefix(value, "{") {
+		value = strings.TrimSpace(v)
+	}
+	if value == "" {
+		return nil
+	}
+	switch value[0] {
+	case '[', '{':
+		return fmt.Errorf("flow style (%c...) is not supported; write one \"key: value\" per line", value[0])
+	case '|', '>':
+		return fmt.Errorf("block scalars (%c) are not supported; keep the value on one line", value[0])
+	case '&', '*':
+		return fmt.Errorf("anchors and aliases are not supported")
+	}
+	return nil
+}
+
+// stripYAMLComment removes a trailing "# comment" outside quotes.
+func stripYAMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
@@ -0,0 +1,77 @@
+package internal
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePlanYAML(t *testing.T) {
+	const src = `---
+# F1 weekend
+name: "F1 Weekend"
+defaults:
+  outlet: Paddock Club
+dishes:
+  - dish: DISH Lobster Roll   # mains
+    portions: 120
+    day: 2026-12-05
+  -
+    dish: 'DISH Lobster Roll'
+    portions: 130
+    day: 2026-12-06
+    outlet: Grandstand
+`
+	plan, err := ParsePlanYAML(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Plan{Name: "F1 Weekend", Entries: []PlanEntry{
+		{Dish: "DISH Lobster Roll", Portions: 120, Day: "2026-12-05", Outlet: "Paddock Club"},
+		{Dish: "DISH Lobster Roll", Portions: 130, Day: "2026-12-06", Outlet: "Grandstand"},
+	}}
+	if !reflect.DeepEqual(plan, want) {
+		t.Errorf("got %+v\nwant %+v", plan, want)
+	}
+
+	// Lists may also start at the key's own indentation.
+	plan, err = ParsePlanYAML(strings.NewReader("dishes:\n- dish: DISH Tart\n  portions: 4\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Entries) != 1 || plan.Entries[0].Portions != 4 {
+		t.Errorf("zero-indented list: got %+v", plan.Entries)
+	}
+}
+
+func TestParsePlanYAMLUnsupported(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		message string
+	}{
+		{"tab indentation", "dishes:\n\t- dish: DISH Tart\n\t  portions: 4\n", "line 2: indent with spaces, not tabs"},
+		{"flow list", "dishes: [DISH Tart]\n", "line 1: flow style ([...) is not supported"},
+		{"flow mapping item", "dishes:\n  - {dish: DISH Tart, portions: 4}\n", "line 2: flow style ({...) is not supported"},
+		{"flow value", "dishes:\n  - dish: DISH Tart\n    portions: [4, 5]\n", "line 3: flow style"},
+		{"block scalar", "name: |\n  F1\n", "line 1: block scalars (|) are not supported"},
+		{"anchor", "defaults: &d\n", "line 1: anchors and aliases are not supported"},
+		{"nested block in a dish", "dishes:\n  - dish: DISH Tart\n    portions:\n      fri: 4\n", "line 3: \"portions\" has no value"},
+		{"deeper key in a dish", "dishes:\n  - dish: DISH Tart\n      portions: 4\n", "line 3: nested blocks are not supported"},
+		{"nested list", "dishes:\n  - dish: DISH Tart\n    portions: 4\n      - day: 2026-12-05\n", "line 4: nested lists are not supported"},
+		{"nested defaults", "defaults:\n  outlet: Paddock\n    day: 2026-12-05\n", "line 3: nested blocks are not supported"},
+		{"unknown top-level key", "name: F1\nguests: 40\n", "line 2: unknown key \"guests\""},
+		{"unknown dish field", "dishes:\n  - dish: DISH Tart\n    portions: 4\n    price: 9\n", "line 2: unknown field \"price\""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParsePlanYAML(strings.NewReader(tt.src))
+			if err == nil {
+				t.Fatal("no error")
+			}
+			if !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("error %q does not contain %q", err, tt.message)
+			}
+		})
+	}
+}
@@ -0,0 +1,276 @@
+package internal
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// PrepSettings are how and where a recipe is produced ahead of service.
+type PrepSettings struct {
+	LeadDays      int     // made this many days before it is used
+	BatchQty      float64 // one batch in the recipe's yield unit; 0 means any quantity
+	ShelfLifeDays int     // 0 means unknown: every day is prepped separately
+	Station       string
+}
+
+// LoadPrepSettings returns the prep settings of every recipe, keyed by
+// recipe ID.
+func LoadPrepSettings(db *sql.DB) (map[int]PrepSettings, error) {
+	rows, err := db.Query(`
+		SELECT id, COALESCE(prep_lead_days, 0), COALESCE(batch_qty, 0),
+		       COALESCE(shelf_life_days, 0), COALESCE(station, '')
+		FROM recipes
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	settings := make(map[int]PrepSettings)
+	for rows.Next() {
+		var id int
+		var s PrepSettings
+		if err := rows.Scan(&id, &s.LeadDays, &s.BatchQty, &s.ShelfLifeDays, &s.Station); err != nil {
+			return nil, err
+		}
+		settings[id] = s
+	}
+	return settings, rows.Err()
+}
+
+// PrepDemand is a quantity of a dish, in its yield unit, served on Day.
+type PrepDemand struct {
+	RecipeID int
+	Qty      float64
+	Day      string
+}
+
+// PrepTask is one line of the prep schedule: make Batches batches of a
+// recipe on Day, first used on NeededBy.
+type PrepTask struct {
+	RecipeID      int
+	Name          string
+	Station       string
+	Day           string
+	NeededBy      string
+	LastNeeded    string  // last day this run covers
+	Qty           float64 // required, in Unit
+	MakeQty       float64 // Qty rounded up to whole batches
+	Unit          string
+	BatchQty      float64
+	Batches       float64
+	ShelfLifeDays int
+	UsedIn        []string
+	Level         int // 0 for recipes without subrecipes, parents are higher
+}
+
+// UseBy returns the last day the run may be used, or "" without a shelf
+// life.
+func (t *PrepTask) UseBy() string {
+	if t.ShelfLifeDays <= 0 {
+		return ""
+	}
+	return addDays(t.Day, t.ShelfLifeDays)
+}
+
+// ExceedsShelfLife reports whether the run is needed after its use-by day,
+// i.e. its lead time is longer than its shelf life.
+func (t *PrepTask) ExceedsShelfLife() bool {
+	return t.ShelfLifeDays > 0 && t.LastNeeded > t.UseBy()
+}
+
+// prepNeed is the quantity of a recipe needed on one day and the recipes
+// (or dishes) it goes into.
+type prepNeed struct {
+	qty     float64
+	parents map[string]bool
+}
+
+// PrepSchedule turns served dishes into the subrecipes to make, when and in
+// how many batches.
+//
+// Dishes are assembled on their service day, so their subrecipes are needed
+// that day. Each subrecipe is made its lead time before it is needed, and
+// needs on later days are folded into the same run while they fall within
+// its shelf life. Runs are rounded up to whole batches, and the full batches
+// are what the next level down has to supply. Tasks are ordered by day with
+// nested subrecipes before the recipes that use them.
+func (c *Coster) PrepSchedule(settings map[int]PrepSettings, demand []PrepDemand) ([]*PrepTask, error) {
+	needs := make(map[int]map[string]*prepNeed)
+	levels := make(map[int]int)
+
+	addSubrecipeNeeds := func(rc *RecipeCost, qty float64, day string) {
+		if rc.YieldQty <= 0 {
+			return
+		}
+		scale := qty / rc.YieldQty
+		for _, l := range rc.Lines {
+			if l.Type != "subrecipe" {
+				continue
+			}
+			byDay, ok := needs[l.SubrecipeID]
+			if !ok {
+				byDay = make(map[string]*prepNeed)
+				needs[l.SubrecipeID] = byDay
+			}
+			n, ok := byDay[day]
+			if !ok {
+				n = &prepNeed{parents: make(map[string]bool)}
+				byDay[day] = n
+			}
+			n.qty += l.BaseQty * scale
+			n.parents[rc.Name] = true
+		}
+	}
+
+	for _, d := range demand {
+		if _, err := time.Parse(DateLayout, d.Day); err != nil {
+			return nil, fmt.Errorf("service day %q: expected YYYY-MM-DD", d.Day)
+		}
+		rc, err := c.Cost(d.RecipeID)
+		if err != nil {
+			return nil, err
+		}
+		addSubrecipeNeeds(rc, d.Qty, d.Day)
+	}
+
+	// A subrecipe's level is always below its parents', so handling the
+	// highest pending level first means all of a recipe's needs are known
+	// before its run is planned.
+	var tasks []*PrepTask
+	done := make(map[int]bool)
+	for {
+		next, nextLevel := 0, -1
+		for id := range needs {
+			if done[id] {
+				continue
+			}
+			lvl, err := c.prepLevel(id, levels)
+			if err != nil {
+				return nil, err
+			}
+			if lvl > nextLevel || (lvl == nextLevel && id < next) {
+				next, nextLevel = id, lvl
+			}
+		}
+		if nextLevel < 0 {
+			break
+		}
+		done[next] = true
+
+		rc, err := c.Cost(next)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range planRuns(rc, settings[next], needs[next]) {
+			t.Level = nextLevel
+			addSubrecipeNeeds(rc, t.MakeQty, t.Day)
+			tasks = append(tasks, t)
+		}
+	}
+
+	sort.Slice(tasks, func(i, j int) bool {
+		a, b := tasks[i], tasks[j]
+		if a.Day != b.Day {
+			return a.Day < b.Day
+		}
+		if a.Level != b.Level {
+			return a.Level < b.Level
+		}
+		if a.Station != b.Station {
+			return a.Station < b.Station
+		}
+		return a.Name < b.Name
+	})
+	return tasks, nil
+}
+
+// planRuns groups the needs of one recipe into prep runs.
+func planRuns(rc *RecipeCost, s PrepSettings, byDay map[string]*prepNeed) []*PrepTask {
+	days := make([]string, 0, len(byDay))
+	for day := range byDay {
+		days = append(days, day)
+	}
+	sort.Strings(days)
+
+	var tasks []*PrepTask
+	for i := 0; i < len(days); {
+		t := &PrepTask{
+			RecipeID:      rc.RecipeID,
+			Name:          rc.Name,
+			Station:       s.Station,
+			Day:           addDays(days[i], -s.LeadDays),
+			NeededBy:      days[i],
+			Unit:          rc.YieldUnit,
+			BatchQty:      s.BatchQty,
+			ShelfLifeDays: s.ShelfLifeDays,
+		}
+		parents := make(map[string]bool)
+
+		j := i
+		for ; j < len(days); j++ {
+			if j > i && (s.ShelfLifeDays <= 0 || days[j] > t.UseBy()) {
+				break
+			}
+			n := byDay[days[j]]
+			t.Qty += n.qty
+			t.LastNeeded = days[j]
+			for p := range n.parents {
+				parents[p] = true
+			}
+		}
+		i = j
+
+		for p := range parents {
+			t.UsedIn = append(t.UsedIn, p)
+		}
+		sort.Strings(t.UsedIn)
+
+		t.MakeQty = t.Qty
+		if s.BatchQty > 0 {
+			t.Batches = math.Ceil(t.Qty/s.BatchQty - packEpsilon)
+			t.MakeQty = t.Batches * s.BatchQty
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks
+}
+
+// prepLevel returns 0 for a recipe without subrecipes and otherwise one
+// more than its deepest subrecipe.
+func (c *Coster) prepLevel(recipeID int, levels map[int]int) (int, error) {
+	if lvl, ok := levels[recipeID]; ok {
+		return lvl, nil
+	}
+	rc, err := c.Cost(recipeID)
+	if err != nil {
+		return 0, err
+	}
+	lvl := 0
+	for _, l := range rc.Lines {
+		if l.Type != "subrecipe" {
+			continue
+		}
+		sub, err := c.prepLevel(l.SubrecipeID, levels)
+		if err != nil {
+			return 0, err
+		}
+		if sub+1 > lvl {
+			lvl = sub + 1
+		}
+	}
+	levels[recipeID] = lvl
+	return lvl, nil
+}
+
+// addDays shifts a YYYY-MM-DD date by n days.
+func addDays(day string, n int) string {
+	t, err := time.Parse(DateLayout, day)
+	if err != nil {
+		return day
+	}
+	return t.AddDate(0, 0, n).Format(DateLayout)
+}
@@ -0,0 +1,154 @@
+package internal
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// DateLayout is the format of effective dates and --as-of arguments.
+const DateLayout = "2006-01-02"
+
+// PriceEntry is one row of ingredient_prices.
+type PriceEntry struct {
+	ID            int
+	IngredientID  int
+	CostPerUnit   float64
+	EffectiveDate string
+	Supplier      string
+	Source        string
+	CreatedAt     string
+}
+
+// ParseDate validates a YYYY-MM-DD date. An empty string means today.
+func ParseDate(s string) (string, error) {
+	if s == "" {
+		return Today(), nil
+	}
+	t, err := time.Parse(DateLayout, s)
+	if err != nil {
+		return "", fmt.Errorf("invalid date %q (expected YYYY-MM-DD)", s)
+	}
+	return t.Format(DateLayout), nil
+}
+
+// Today returns the current local date as YYYY-MM-DD.
+func Today() string {
+	return time.Now().Format(DateLayout)
+}
+
+// RecordPrice adds a price to an ingredient's history and refreshes
+// ingredients.cost_per_unit to the price in effect today.
+func RecordPrice(db *sql.DB, ingredientID int, cost float64, effectiveDate, supplier, source string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := recordPrice(tx, ingredientID, cost, effectiveDate, supplier, source); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// recordPrice is RecordPrice inside tx.
+func recordPrice(tx *sql.Tx, ingredientID int, cost float64, effectiveDate, supplier, source string) error {
+	if _, err := tx.Exec(`
+		INSERT INTO ingredient_prices (ingredient_id, cost_per_unit, effective_date, supplier, source)
+		VALUES (?, ?, ?, NULLIF(?, ''), NULLIF(?, ''))
+	`, ingredientID, cost, effectiveDate, supplier, source); err != nil {
+		return fmt.Errorf("recording price: %w", err)
+	}
+
+	if err := refreshCurrentPrices(tx, ingredientID); err != nil {
+		return fmt.Errorf("updating current price: %w", err)
+	}
+	return nil
+}
+
+// refreshCurrentPrices sets ingredients.cost_per_unit to the price in
+// effect today for one ingredient, or for all of them when ingredientID is
+// 0. Rows already current are left alone. Entries dated in the future are
+// picked up at costing time through PriceAsOf, not written here.
+func refreshCurrentPrices(e execer, ingredientID int) error {
+	_, err := e.Exec(`
+		UPDATE ingredients
+		SET cost_per_unit = (
+		    SELECT p.cost_per_unit
+		    FROM ingredient_prices p
+		    WHERE p.ingredient_id = ingredients.id AND p.effective_date <= ?1
+		    ORDER BY p.effective_date DESC, p.id DESC
+		    LIMIT 1
+		)
+		WHERE (?2 = 0 OR id = ?2)
+		  AND EXISTS (
+		    SELECT 1 FROM ingredient_prices p
+		    WHERE p.ingredient_id = ingredients.id AND p.effective_date <= ?1
+		  )
+		  AND cost_per_unit IS NOT (
+		    SELECT p.cost_per_unit
+		    FROM ingredient_prices p
+		    WHERE p.ingredient_id = ingredients.id AND p.effective_date <= ?1
+		    ORDER BY p.effective_date DESC, p.id DESC
+		    LIMIT 1
+		  )
+	`, Today(), ingredientID)
+	return err
+}
+
+// PriceHistory lists an ingredient's prices, newest effective date first.
+func PriceHistory(db *sql.DB, ingredientID int) ([]PriceEntry, error) {
+	rows, err := db.Query(`
+		SELECT id, ingredient_id, cost_per_unit, effective_date,
+		       COALESCE(supplier, ''), COALESCE(source, ''), created_at
+		FROM ingredient_prices
+		WHERE ingredient_id = ?
+		ORDER BY effective_date DESC, id DESC
+	`, ingredientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []PriceEntry
+	for rows.Next() {
+		var p PriceEntry
+		if err := rows.Scan(&p.ID, &p.IngredientID, &p.CostPerUnit, &p.EffectiveDate,
+			&p.Supplier, &p.Source, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, p)
+	}
+	return list, rows.Err()
+}
+
+// PriceAsOf returns the price of an ingredient in effect on date: the
+// latest entry effective on or before it. Dates before the first recorded
+// price use the earliest entry, and ingredients without history use
+// ingredients.cost_per_unit.
+func PriceAsOf(db *sql.DB, ingredientID int, date string) (float64, error) {
+	var cost float64
+	err := db.QueryRow(`
+		SELECT cost_per_unit
+		FROM ingredient_prices
+		WHERE ingredient_id = ?
+		ORDER BY (effective_date <= ?) DESC,
+		         CASE WHEN effective_date <= ? THEN effective_date END DESC,
+		         effective_date ASC,
+		         id DESC
+		LIMIT 1
+	`, ingredientID, date, date).Scan(&cost)
+	if err == nil {
+		return cost, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	err = db.QueryRow(`SELECT cost_per_unit FROM ingredients WHERE id = ?`, ingredientID).Scan(&cost)
+	if err != nil {
+		return 0, fmt.Errorf("ingredient with ID %d not found", ingredientID)
+	}
+	return cost, nil
+}
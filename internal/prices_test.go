@@ -0,0 +1,62 @@
+package internal
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFutureDatedPriceBecomesCurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chefops.db")
+	db, err := CreateDBAt(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ingID, err := SaveIngredient(db, "Flour", "kg", 2, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := db.Exec(`INSERT INTO recipes (name, yield_qty, yield_unit) VALUES ('SUB Dough', 1, 'kg')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipeID, _ := r.LastInsertId()
+	if _, err := AddIngredientLine(db, int(recipeID), ingID, 1, "kg"); err != nil {
+		t.Fatal(err)
+	}
+
+	tomorrow := time.Now().AddDate(0, 0, 1).Format(DateLayout)
+	if err := RecordPrice(db, ingID, 3, tomorrow, "", "test"); err != nil {
+		t.Fatal(err)
+	}
+	if rc, err := NewCoster(db).Cost(int(recipeID)); err != nil || rc.TotalCost != 2 {
+		t.Fatalf("before the effective date: cost = %v, %v; want 2", rc, err)
+	}
+
+	// Let the date arrive by moving the entry to today, then reopen.
+	if _, err := db.Exec(`UPDATE ingredient_prices SET effective_date = ? WHERE effective_date = ?`, Today(), tomorrow); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+	if db, err = OpenDBAt(path); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	rc, err := NewCoster(db).Cost(int(recipeID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rc.TotalCost != 3 {
+		t.Errorf("on the effective date: cost = %v, want 3", rc.TotalCost)
+	}
+
+	// Opening and costing are reads; neither writes the new price back.
+	var stored float64
+	if err := db.QueryRow(`SELECT cost_per_unit FROM ingredients WHERE id = ?`, ingID).Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored != 2 {
+		t.Errorf("cost_per_unit = %v after opening, want 2 left untouched", stored)
+	}
+}
@@ -0,0 +1,131 @@
+package internal
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+)
+
+// PackSize is the unit an ingredient is bought in.
+type PackSize struct {
+	Qty          float64
+	Unit         string
+	AllowPartial bool
+}
+
+// Purchase is what to order for one market list line. Quantities are in
+// the ingredient's unit; without a pack size the order quantity is the
+// theoretical quantity.
+type Purchase struct {
+	Pack     PackSize
+	HasPack  bool
+	PackBase float64 // one pack in the ingredient's unit
+	Packs    float64 // packs to order
+	OrderQty float64
+	OverQty  float64 // OrderQty minus the theoretical quantity
+	OverCost float64
+}
+
+// packEpsilon absorbs float noise, so 10.0000000001 kg in 5 kg packs stays
+// two packs.
+const packEpsilon = 1e-9
+
+// LoadPackSizes returns the pack each ingredient is bought in, keyed by
+// ingredient ID: the primary supplier's pack when it has one, otherwise the
+// ingredient's own. Whether partial packs are allowed is always taken from
+// the ingredient.
+func LoadPackSizes(db *sql.DB) (map[int]PackSize, error) {
+	rows, err := db.Query(`
+		SELECT id, COALESCE(pack_qty, 0), COALESCE(NULLIF(pack_unit, ''), unit), partial_packs
+		FROM ingredients
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	own := make(map[int]PackSize)
+	for rows.Next() {
+		var id int
+		var p PackSize
+		if err := rows.Scan(&id, &p.Qty, &p.Unit, &p.AllowPartial); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		own[id] = p
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	primary, err := PrimarySupplierLinks(db)
+	if err != nil {
+		return nil, err
+	}
+
+	packs := make(map[int]PackSize)
+	for id, p := range own {
+		if l, ok := primary[id]; ok && l.PackQty > 0 {
+			p.Qty, p.Unit = l.PackQty, l.PackUnit
+		}
+		if p.Qty > 0 {
+			packs[id] = p
+		}
+	}
+	return packs, nil
+}
+
+// PlanPurchase rounds a theoretical quantity up to whole packs, unless the
+// ingredient allows partial packs.
+func PlanPurchase(db *sql.DB, u *IngredientUsage, pack PackSize) (Purchase, error) {
+	p := Purchase{Pack: pack, HasPack: true, OrderQty: u.Qty}
+
+	base, err := ConvertIngredientQty(db, u.IngredientID, pack.Qty, pack.Unit, u.Unit)
+	if err != nil {
+		return p, fmt.Errorf("%s: pack size %g %s: %w", u.Name, pack.Qty, pack.Unit, err)
+	}
+	if base <= 0 {
+		return p, fmt.Errorf("%s: pack size must be positive", u.Name)
+	}
+	p.PackBase = base
+
+	p.Packs = u.Qty / base
+	if !pack.AllowPartial {
+		p.Packs = math.Ceil(p.Packs - packEpsilon)
+		p.OrderQty = p.Packs * base
+	}
+	p.OverQty = p.OrderQty - u.Qty
+	if math.Abs(p.OverQty) < packEpsilon {
+		p.OverQty = 0
+	}
+	p.OverCost = p.OverQty * u.CostPerUnit
+	return p, nil
+}
+
+// PlanPurchases computes a Purchase for every item, keyed by ingredient ID.
+func PlanPurchases(db *sql.DB, items []*IngredientUsage) (map[int]Purchase, error) {
+	packs, err := LoadPackSizes(db)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := make(map[int]Purchase, len(items))
+	for _, u := range items {
+		pack, ok := packs[u.IngredientID]
+		if !ok {
+			plan[u.IngredientID] = Purchase{OrderQty: u.Qty}
+			continue
+		}
+		p, err := PlanPurchase(db, u, pack)
+		if err != nil {
+			return nil, err
+		}
+		plan[u.IngredientID] = p
+	}
+	return plan, nil
+}
+
+// OrderCost returns the cost of the order quantity.
+func (p Purchase) OrderCost(u *IngredientUsage) float64 {
+	return p.OrderQty * u.CostPerUnit
+}
@@ -0,0 +1,220 @@
+package internal
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrRecipeExists is returned (wrapped) when a new recipe name is taken.
+var ErrRecipeExists = errors.New("recipe already exists")
+
+// recipeCopyColumns are the recipe columns a clone inherits: everything
+// except id, name and archived_at.
+const recipeCopyColumns = `yield_qty, yield_unit, secondary_yield_qty, secondary_yield_unit,
+	notes, metadata, prep_lead_days, batch_qty, shelf_life_days, station`
+
+// RenameRecipe changes a recipe's name. Lines, events and history refer to
+// the recipe by ID and follow automatically.
+func RenameRecipe(db *sql.DB, recipeID int, newName string) error {
+	newName = strings.TrimSpace(newName)
+	if newName == "" {
+		return fmt.Errorf("new name is empty")
+	}
+	if err := recipeNameFree(db, newName, recipeID); err != nil {
+		return err
+	}
+	_, err := db.Exec(`UPDATE recipes SET name = ? WHERE id = ?`, newName, recipeID)
+	return err
+}
+
+// CloneRecipe copies a recipe with its ingredient and subrecipe lines under
+// a new name and returns the new ID. With deep set, every subrecipe below
+// it is cloned too, named with suffix appended (e.g. "BULK Batter v2"),
+// and the copies use the cloned subrecipes; a subrecipe shared by several
+// branches is cloned once.
+func CloneRecipe(db *sql.DB, recipeID int, newName string, deep bool, suffix string) (int, error) {
+	newName = strings.TrimSpace(newName)
+	if newName == "" {
+		return 0, fmt.Errorf("new name is empty")
+	}
+	if deep && strings.TrimSpace(suffix) == "" {
+		return 0, fmt.Errorf("cloning subrecipes needs a name suffix")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	cloned := make(map[int]int) // original ID -> clone ID
+	var clone func(id int, name string) (int, error)
+	clone = func(id int, name string) (int, error) {
+		if newID, ok := cloned[id]; ok {
+			return newID, nil
+		}
+		if err := recipeNameFree(tx, name, 0); err != nil {
+			return 0, err
+		}
+
+		res, err := tx.Exec(`
+			INSERT INTO recipes (name, `+recipeCopyColumns+`)
+			SELECT ?, `+recipeCopyColumns+` FROM recipes WHERE id = ?
+		`, name, id)
+		if err != nil {
+			return 0, err
+		}
+		newID64, err := res.LastInsertId()
+		if err != nil {
+			return 0, err
+		}
+		newID := int(newID64)
+		cloned[id] = newID
+
+		if _, err := tx.Exec(`
+			INSERT INTO recipe_items (recipe_id, ingredient_id, qty, unit)
+			SELECT ?, ingredient_id, qty, unit FROM recipe_items WHERE recipe_id = ? ORDER BY id
+		`, newID, id); err != nil {
+			return 0, err
+		}
+
+		type subLine struct {
+			subID int
+			name  string
+			qty   float64
+			unit  string
+		}
+		rows, err := tx.Query(`
+			SELECT rs.subrecipe_id, sub.name, rs.qty, rs.unit
+			FROM recipe_subrecipes rs
+			JOIN recipes sub ON sub.id = rs.subrecipe_id
+			WHERE rs.recipe_id = ?
+			ORDER BY rs.id
+		`, id)
+		if err != nil {
+			return 0, err
+		}
+		var subs []subLine
+		for rows.Next() {
+			var l subLine
+			if err := rows.Scan(&l.subID, &l.name, &l.qty, &l.unit); err != nil {
+				rows.Close()
+				return 0, err
+			}
+			subs = append(subs, l)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return 0, err
+		}
+
+		for _, l := range subs {
+			subID := l.subID
+			if deep {
+				if subID, err = clone(l.subID, l.name+suffix); err != nil {
+					return 0, err
+				}
+			}
+			if _, err := tx.Exec(`
+				INSERT INTO recipe_subrecipes (recipe_id, subrecipe_id, qty, unit)
+				VALUES (?, ?, ?, ?)
+			`, newID, subID, l.qty, l.unit); err != nil {
+				return 0, err
+			}
+		}
+		return newID, nil
+	}
+
+	newID, err := clone(recipeID, newName)
+	if err != nil {
+		return 0, err
+	}
+	return newID, tx.Commit()
+}
+
+// RecipeReferences lists what stops a recipe from being deleted: recipes
+// that use it as a subrecipe and events that have it on the menu.
+func RecipeReferences(db *sql.DB, recipeID int) ([]string, error) {
+	rows, err := db.Query(`
+		SELECT 'recipe ' || r.name
+		FROM recipe_subrecipes rs
+		JOIN recipes r ON r.id = rs.recipe_id
+		WHERE rs.subrecipe_id = ? AND rs.recipe_id <> rs.subrecipe_id
+		UNION
+		SELECT 'event ' || e.name
+		FROM event_dishes ed
+		JOIN events e ON e.id = ed.event_id
+		WHERE ed.recipe_id = ?
+		ORDER BY 1
+	`, recipeID, recipeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var refs []string
+	for rows.Next() {
+		var ref string
+		if err := rows.Scan(&ref); err != nil {
+			return nil, err
+		}
+		refs = append(refs, ref)
+	}
+	return refs, rows.Err()
+}
+
+// DeleteRecipe removes a recipe and its own lines. It refuses while other
+// recipes or events refer to it; archive it instead to keep the history.
+func DeleteRecipe(db *sql.DB, recipeID int) error {
+	refs, err := RecipeReferences(db, recipeID)
+	if err != nil {
+		return err
+	}
+	if len(refs) > 0 {
+		return fmt.Errorf("still used by %s", strings.Join(refs, ", "))
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, q := range []string{
+		`DELETE FROM recipe_items WHERE recipe_id = ?`,
+		`DELETE FROM recipe_subrecipes WHERE recipe_id = ?`,
+		`DELETE FROM recipes WHERE id = ?`,
+	} {
+		if _, err := tx.Exec(q, recipeID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ArchiveRecipe hides a recipe from lists and the market list, or brings
+// it back when archived is false.
+func ArchiveRecipe(db *sql.DB, recipeID int, archived bool) error {
+	var at any // NULL: active
+	if archived {
+		at = time.Now().Format(TimestampLayout)
+	}
+	_, err := db.Exec(`UPDATE recipes SET archived_at = ? WHERE id = ?`, at, recipeID)
+	return err
+}
+
+// recipeNameFree returns ErrRecipeExists when another recipe (not exceptID)
+// already has name, compared case-insensitively.
+func recipeNameFree(q queryer, name string, exceptID int) error {
+	var n int
+	if err := q.QueryRow(`SELECT COUNT(*) FROM recipes WHERE LOWER(name) = LOWER(?) AND id <> ?`, name, exceptID).Scan(&n); err != nil {
+		return err
+	}
+	if n > 0 {
+		return fmt.Errorf("%w: %s", ErrRecipeExists, name)
+	}
+	return nil
+}
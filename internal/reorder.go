@@ -0,0 +1,120 @@
+package internal
+
+import (
+	"database/sql"
+	"sort"
+)
+
+// ReorderLine is the reorder suggestion for one ingredient. Quantities are
+// in the ingredient's unit.
+type ReorderLine struct {
+	IngredientID int
+	Name         string
+	Unit         string
+	CostPerUnit  float64
+	OnHand       float64
+	Forecast     float64 // usage from the forecast, if any
+	MinLevel     float64
+	ParLevel     float64
+	Projected    float64 // OnHand - Forecast
+	SuggestQty   float64 // to bring Projected back up to par
+	Purchase     Purchase
+}
+
+// Reorder reports whether anything should be ordered.
+func (l *ReorderLine) Reorder() bool {
+	return l.SuggestQty > packEpsilon
+}
+
+// OrderCost returns the cost of the rounded order quantity.
+func (l *ReorderLine) OrderCost() float64 {
+	return l.Purchase.OrderQty * l.CostPerUnit
+}
+
+// ReorderSuggestions compares stock on hand against par levels plus the
+// forecasted usage (which may be empty) for every ingredient that has a
+// par level or is used by the forecast.
+//
+// An ingredient is reordered when its projected stock drops below its
+// minimum level (the par level if no minimum is set, zero without either);
+// the suggestion brings it back up to par and is rounded to purchase packs.
+func ReorderSuggestions(db *sql.DB, forecast map[int]*IngredientUsage) ([]*ReorderLine, error) {
+	onHand, err := OnHand(db)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`
+		SELECT id, name, unit, cost_per_unit, COALESCE(min_level, -1), COALESCE(par_level, -1)
+		FROM ingredients
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []*ReorderLine
+	for rows.Next() {
+		l := &ReorderLine{}
+		if err := rows.Scan(&l.IngredientID, &l.Name, &l.Unit, &l.CostPerUnit, &l.MinLevel, &l.ParLevel); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		used, inForecast := forecast[l.IngredientID]
+		if l.ParLevel < 0 && !inForecast {
+			continue
+		}
+		if inForecast {
+			l.Forecast = used.Qty
+		}
+		lines = append(lines, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	packs, err := LoadPackSizes(db)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, l := range lines {
+		if l.ParLevel < 0 {
+			l.ParLevel = 0
+		}
+		if l.MinLevel < 0 {
+			l.MinLevel = l.ParLevel
+		}
+		l.OnHand = onHand[l.IngredientID]
+		l.Projected = l.OnHand - l.Forecast
+
+		if l.Projected < l.MinLevel || l.Projected < 0 {
+			l.SuggestQty = l.ParLevel - l.Projected
+		}
+		// A minimum above par can make the shortfall negative, and float
+		// noise can leave it a hair below zero; neither is an order.
+		if l.SuggestQty <= packEpsilon {
+			l.SuggestQty = 0
+		}
+
+		u := &IngredientUsage{
+			IngredientID: l.IngredientID,
+			Name:         l.Name,
+			Unit:         l.Unit,
+			CostPerUnit:  l.CostPerUnit,
+			Qty:          l.SuggestQty,
+		}
+		l.Purchase = Purchase{OrderQty: l.SuggestQty}
+		if pack, ok := packs[l.IngredientID]; ok && l.Reorder() {
+			if l.Purchase, err = PlanPurchase(db, u, pack); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	sort.SliceStable(lines, func(i, j int) bool {
+		return lines[i].Reorder() && !lines[j].Reorder()
+	})
+	return lines, nil
+}
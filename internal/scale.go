@@ -0,0 +1,49 @@
+package internal
+
+import "fmt"
+
+// ScaledRecipe is a recipe's costing scaled to a target yield.
+type ScaledRecipe struct {
+	Recipe     *RecipeCost
+	TargetQty  float64
+	TargetUnit string
+	Factor     float64    // target yield ÷ recipe yield
+	Lines      []CostLine // Recipe.Lines with quantities and costs scaled
+	TotalCost  float64
+}
+
+// Scale costs recipeID and scales it to qty of unit, which may be its yield
+// unit, its secondary yield unit or a standard unit of either.
+func (c *Coster) Scale(recipeID int, qty float64, unit string) (*ScaledRecipe, error) {
+	if qty <= 0 {
+		return nil, fmt.Errorf("target quantity must be positive")
+	}
+	rc, err := c.Cost(recipeID)
+	if err != nil {
+		return nil, err
+	}
+	if rc.YieldQty <= 0 {
+		return nil, fmt.Errorf("recipe %s has no positive yield", rc.Name)
+	}
+
+	// Target yield expressed in the recipe's own yield unit
+	targetQty, err := ConvertYieldQty(rc, qty, unit)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &ScaledRecipe{
+		Recipe:     rc,
+		TargetQty:  qty,
+		TargetUnit: unit,
+		Factor:     targetQty / rc.YieldQty,
+	}
+	for _, l := range rc.Lines {
+		l.Qty *= s.Factor
+		l.BaseQty *= s.Factor
+		l.LineCost *= s.Factor
+		s.Lines = append(s.Lines, l)
+	}
+	s.TotalCost = rc.TotalCost * s.Factor
+	return s, nil
+}
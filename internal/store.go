@@ -0,0 +1,504 @@
+package internal
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNotFound is returned (wrapped) when a Store has no recipe or
+// ingredient with the requested ID or name.
+var ErrNotFound = errors.New("not found")
+
+// Ingredient is a purchasable ingredient, priced per Unit.
+type Ingredient struct {
+	ID          int
+	Name        string
+	Unit        string
+	CostPerUnit float64
+}
+
+// Recipe is a recipe or subrecipe with its yield. SecondaryYieldQty and
+// SecondaryYieldUnit, when set, say how much the same batch is in a second
+// unit (e.g. 2 kg = 40 piece).
+type Recipe struct {
+	ID                 int
+	Name               string
+	YieldQty           float64
+	YieldUnit          string
+	SecondaryYieldQty  float64
+	SecondaryYieldUnit string
+	Notes              string
+	Archived           bool
+}
+
+// Line is one line of a recipe: an ingredient or a subrecipe, with the
+// quantity as entered. Ingredient lines without a unit carry the
+// ingredient's own unit.
+type Line struct {
+	ID           int    // row ID, unique per Type; 0 if the Store has none
+	Type         string // "ingredient" or "subrecipe"
+	IngredientID int
+	SubrecipeID  int
+	Name         string
+	Qty          float64
+	Unit         string
+}
+
+// Conversion says FromQty FromUnit of an ingredient is ToQty ToUnit
+// (e.g. 1 piece = 60 g). It holds in both directions.
+type Conversion struct {
+	IngredientID int
+	FromQty      float64
+	FromUnit     string
+	ToQty        float64
+	ToUnit       string
+}
+
+// Store is what the costing, scaling, forecasting and market list code
+// reads. SQLStore implements it over the chefops SQLite database.
+type Store interface {
+	// Recipes returns the recipes that are not archived, by name.
+	Recipes() ([]Recipe, error)
+	Recipe(id int) (Recipe, error)
+	// RecipeByName matches name case-insensitively.
+	RecipeByName(name string) (Recipe, error)
+	// Lines returns a recipe's ingredient lines by ingredient name, then
+	// its subrecipe lines by subrecipe name.
+	Lines(recipeID int) ([]Line, error)
+
+	Ingredients() ([]Ingredient, error)
+	Ingredient(id int) (Ingredient, error)
+	Conversions(ingredientID int) ([]Conversion, error)
+	// PriceAsOf returns the ingredient's cost per unit on date
+	// (YYYY-MM-DD), falling back to its current cost.
+	PriceAsOf(ingredientID int, date string) (float64, error)
+}
+
+// SQLStore is the Store over a chefops SQLite database.
+type SQLStore struct {
+	db *sql.DB
+}
+
+// NewSQLStore returns a Store reading from db.
+func NewSQLStore(db *sql.DB) *SQLStore {
+	return &SQLStore{db: db}
+}
+
+// DB returns the underlying database handle.
+func (s *SQLStore) DB() *sql.DB {
+	return s.db
+}
+
+// Close closes the underlying database.
+func (s *SQLStore) Close() error {
+	return s.db.Close()
+}
+
+const recipeColumns = `id, name, yield_qty, yield_unit, secondary_yield_qty, secondary_yield_unit, notes, archived_at IS NOT NULL`
+
+func (s *SQLStore) Recipes() ([]Recipe, error) {
+	return s.queryRecipes(`SELECT ` + recipeColumns + ` FROM recipes WHERE archived_at IS NULL ORDER BY name`)
+}
+
+func (s *SQLStore) Recipe(id int) (Recipe, error) {
+	r, err := scanRecipe(s.db.QueryRow(`SELECT `+recipeColumns+` FROM recipes WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return r, fmt.Errorf("recipe with ID %d %w", id, ErrNotFound)
+	}
+	return r, err
+}
+
+func (s *SQLStore) RecipeByName(name string) (Recipe, error) {
+	r, err := scanRecipe(s.db.QueryRow(`
+		SELECT `+recipeColumns+` FROM recipes
+		WHERE LOWER(name) = LOWER(?)
+		ORDER BY name = ? DESC, id
+		LIMIT 1
+	`, name, name))
+	if err == sql.ErrNoRows {
+		return r, fmt.Errorf("recipe %s %w", name, ErrNotFound)
+	}
+	return r, err
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanRecipe(row rowScanner) (Recipe, error) {
+	var r Recipe
+	var secQty sql.NullFloat64
+	var secUnit, notes sql.NullString
+	err := row.Scan(&r.ID, &r.Name, &r.YieldQty, &r.YieldUnit, &secQty, &secUnit, &notes, &r.Archived)
+	r.SecondaryYieldQty = secQty.Float64
+	r.SecondaryYieldUnit = secUnit.String
+	r.Notes = notes.String
+	return r, err
+}
+
+func (s *SQLStore) Lines(recipeID int) ([]Line, error) {
+	rows, err := s.db.Query(`
+		SELECT ri.id, 'ingredient', ing.id, 0, ing.name, ri.qty, COALESCE(NULLIF(ri.unit, ''), ing.unit)
+		FROM recipe_items ri
+		JOIN ingredients ing ON ing.id = ri.ingredient_id
+		WHERE ri.recipe_id = ?
+		UNION ALL
+		SELECT rs.id, 'subrecipe', 0, sub.id, sub.name, rs.qty, rs.unit
+		FROM recipe_subrecipes rs
+		JOIN recipes sub ON sub.id = rs.subrecipe_id
+		WHERE rs.recipe_id = ?
+		ORDER BY 2, 5, 1
+	`, recipeID, recipeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []Line
+	for rows.Next() {
+		var l Line
+		if err := rows.Scan(&l.ID, &l.Type, &l.IngredientID, &l.SubrecipeID, &l.Name, &l.Qty, &l.Unit); err != nil {
+			return nil, err
+		}
+		lines = append(lines, l)
+	}
+	return lines, rows.Err()
+}
+
+func (s *SQLStore) Ingredients() ([]Ingredient, error) {
+	return s.queryIngredients(`SELECT id, name, unit, cost_per_unit FROM ingredients ORDER BY name`)
+}
+
+func (s *SQLStore) Ingredient(id int) (Ingredient, error) {
+	ing := Ingredient{ID: id}
+	err := s.db.QueryRow(`SELECT name, unit, cost_per_unit FROM ingredients WHERE id = ?`, id).
+		Scan(&ing.Name, &ing.Unit, &ing.CostPerUnit)
+	if err == sql.ErrNoRows {
+		return ing, fmt.Errorf("ingredient with ID %d %w", id, ErrNotFound)
+	}
+	return ing, err
+}
+
+func (s *SQLStore) Conversions(ingredientID int) ([]Conversion, error) {
+	return loadConversions(s.db, ingredientID)
+}
+
+// loadConversions reads an ingredient's conversions through q, which may be
+// a transaction.
+func loadConversions(q queryer, ingredientID int) ([]Conversion, error) {
+	rows, err := q.Query(`
+		SELECT from_qty, from_unit, to_qty, to_unit
+		FROM ingredient_conversions
+		WHERE ingredient_id = ?
+		ORDER BY id
+	`, ingredientID)
+	if err != nil {
+		return nil, fmt.Errorf("loading conversions: %w", err)
+	}
+	defer rows.Close()
+
+	var list []Conversion
+	for rows.Next() {
+		c := Conversion{IngredientID: ingredientID}
+		if err := rows.Scan(&c.FromQty, &c.FromUnit, &c.ToQty, &c.ToUnit); err != nil {
+			return nil, err
+		}
+		list = append(list, c)
+	}
+	return list, rows.Err()
+}
+
+func (s *SQLStore) PriceAsOf(ingredientID int, date string) (float64, error) {
+	return PriceAsOf(s.db, ingredientID, date)
+}
+
+// The SQLStore methods below look recipes and ingredients up by name and
+// change the database. They are not part of Store, which is all the costing
+// code needs; the chefops CLI and TUI use them so that they do not run SQL
+// of their own.
+
+// AllRecipes returns every recipe, archived or not, by name.
+func (s *SQLStore) AllRecipes() ([]Recipe, error) {
+	return s.queryRecipes(`SELECT ` + recipeColumns + ` FROM recipes ORDER BY name`)
+}
+
+// RecipesNamed returns the recipes whose name equals name, ignoring case.
+func (s *SQLStore) RecipesNamed(name string) ([]Recipe, error) {
+	return s.queryRecipes(`SELECT `+recipeColumns+` FROM recipes WHERE LOWER(name) = LOWER(?) ORDER BY name`, name)
+}
+
+// SearchRecipes returns up to limit recipes whose name contains text,
+// ignoring case. A limit of 0 means no limit.
+func (s *SQLStore) SearchRecipes(text string, limit int) ([]Recipe, error) {
+	return s.queryRecipes(`
+		SELECT `+recipeColumns+` FROM recipes
+		WHERE LOWER(name) LIKE '%' || LOWER(?) || '%'
+		ORDER BY name
+		LIMIT ?
+	`, text, sqlLimit(limit))
+}
+
+func (s *SQLStore) queryRecipes(query string, args ...any) ([]Recipe, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Recipe
+	for rows.Next() {
+		r, err := scanRecipe(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, r)
+	}
+	return list, rows.Err()
+}
+
+// SaveRecipe adds a recipe or, when the name exists, updates its yields.
+// It returns the recipe's ID.
+func (s *SQLStore) SaveRecipe(r Recipe) (int, error) {
+	_, err := s.db.Exec(`
+		INSERT INTO recipes (name, yield_qty, yield_unit, secondary_yield_qty, secondary_yield_unit)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET
+		    yield_qty = excluded.yield_qty,
+		    yield_unit = excluded.yield_unit,
+		    secondary_yield_qty = excluded.secondary_yield_qty,
+		    secondary_yield_unit = excluded.secondary_yield_unit
+	`, r.Name, r.YieldQty, r.YieldUnit, r.SecondaryYieldQty, r.SecondaryYieldUnit)
+	if err != nil {
+		return 0, fmt.Errorf("saving recipe: %w", err)
+	}
+	var id int
+	err = s.db.QueryRow(`SELECT id FROM recipes WHERE name = ?`, r.Name).Scan(&id)
+	return id, err
+}
+
+// SetRecipeNotes replaces a recipe's notes.
+func (s *SQLStore) SetRecipeNotes(recipeID int, notes string) error {
+	if _, err := s.Recipe(recipeID); err != nil {
+		return err
+	}
+	return UpdateRecipeNotes(s.db, recipeID, notes)
+}
+
+// RecipeMetadata returns a recipe's metadata; recipes without any get
+// empty metadata.
+func (s *SQLStore) RecipeMetadata(recipeID int) (*RecipeMetadata, error) {
+	var data sql.NullString
+	err := s.db.QueryRow(`SELECT metadata FROM recipes WHERE id = ?`, recipeID).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("recipe with ID %d %w", recipeID, ErrNotFound)
+	} else if err != nil {
+		return nil, err
+	}
+	return LoadMetadata(data.String)
+}
+
+// SetRecipeMetadata replaces a recipe's metadata.
+func (s *SQLStore) SetRecipeMetadata(recipeID int, meta *RecipeMetadata) error {
+	data, err := SaveMetadataToJSON(meta)
+	if err != nil {
+		return err
+	}
+	n, err := s.db.Exec(`UPDATE recipes SET metadata = ? WHERE id = ?`, data, recipeID)
+	if err != nil {
+		return err
+	}
+	if rows, _ := n.RowsAffected(); rows == 0 {
+		return fmt.Errorf("recipe with ID %d %w", recipeID, ErrNotFound)
+	}
+	return nil
+}
+
+// IngredientsNamed returns the ingredients whose name equals name, ignoring
+// case.
+func (s *SQLStore) IngredientsNamed(name string) ([]Ingredient, error) {
+	return s.queryIngredients(`
+		SELECT id, name, unit, cost_per_unit FROM ingredients
+		WHERE LOWER(name) = LOWER(?)
+		ORDER BY name
+	`, name)
+}
+
+// SearchIngredients returns up to limit ingredients whose name contains
+// text, ignoring case. A limit of 0 means no limit.
+func (s *SQLStore) SearchIngredients(text string, limit int) ([]Ingredient, error) {
+	return s.queryIngredients(`
+		SELECT id, name, unit, cost_per_unit FROM ingredients
+		WHERE LOWER(name) LIKE '%' || LOWER(?) || '%'
+		ORDER BY name
+		LIMIT ?
+	`, text, sqlLimit(limit))
+}
+
+// SuggestIngredients returns up to ten ingredients whose names look like
+// name: those containing it, else those sharing its first two letters,
+// else those starting with its first word.
+func (s *SQLStore) SuggestIngredients(name string) ([]Ingredient, error) {
+	list, err := s.SearchIngredients(name, 10)
+	if err != nil || len(list) > 0 {
+		return list, err
+	}
+
+	if len(name) >= 2 {
+		list, err = s.queryIngredients(`
+			SELECT id, name, unit, cost_per_unit FROM ingredients
+			WHERE SUBSTR(LOWER(name), 1, 2) = SUBSTR(LOWER(?), 1, 2)
+			ORDER BY name
+			LIMIT 10
+		`, name)
+		if err != nil || len(list) > 0 {
+			return list, err
+		}
+	}
+
+	first := strings.Split(strings.ToLower(name), " ")[0]
+	return s.queryIngredients(`
+		SELECT id, name, unit, cost_per_unit FROM ingredients
+		WHERE LOWER(name) LIKE ?
+		ORDER BY name
+		LIMIT 10
+	`, first+"%")
+}
+
+func (s *SQLStore) queryIngredients(query string, args ...any) ([]Ingredient, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Ingredient
+	for rows.Next() {
+		var ing Ingredient
+		if err := rows.Scan(&ing.ID, &ing.Name, &ing.Unit, &ing.CostPerUnit); err != nil {
+			return nil, err
+		}
+		list = append(list, ing)
+	}
+	return list, rows.Err()
+}
+
+// SaveIngredient adds an ingredient or updates the unit and cost of the one
+// with that name, recording the cost in its price history. It returns the
+// ingredient's ID.
+func (s *SQLStore) SaveIngredient(name, unit string, cost float64, source string) (int, error) {
+	return SaveIngredient(s.db, name, unit, cost, source)
+}
+
+// PriceHistory lists an ingredient's prices, newest effective date first.
+func (s *SQLStore) PriceHistory(ingredientID int) ([]PriceEntry, error) {
+	return PriceHistory(s.db, ingredientID)
+}
+
+// RecordPrice adds a price to an ingredient's history; see the function of
+// the same name.
+func (s *SQLStore) RecordPrice(ingredientID int, cost float64, effectiveDate, supplier, source string) error {
+	return RecordPrice(s.db, ingredientID, cost, effectiveDate, supplier, source)
+}
+
+// AddConversion records that c.FromQty c.FromUnit of an ingredient is
+// c.ToQty c.ToUnit.
+func (s *SQLStore) AddConversion(c Conversion) error {
+	if c.FromQty <= 0 || c.ToQty <= 0 {
+		return fmt.Errorf("conversion quantities must be positive")
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO ingredient_conversions (ingredient_id, from_unit, from_qty, to_unit, to_qty)
+		VALUES (?, ?, ?, ?, ?)
+	`, c.IngredientID, c.FromUnit, c.FromQty, c.ToUnit, c.ToQty)
+	return err
+}
+
+// SetPackSize sets the pack an ingredient is bought in; nil clears it.
+func (s *SQLStore) SetPackSize(ingredientID int, p *PackSize) error {
+	var err error
+	if p == nil {
+		_, err = s.db.Exec(`UPDATE ingredients SET pack_qty = NULL, pack_unit = NULL, partial_packs = 0 WHERE id = ?`, ingredientID)
+	} else {
+		_, err = s.db.Exec(`UPDATE ingredients SET pack_qty = ?, pack_unit = ?, partial_packs = ? WHERE id = ?`,
+			p.Qty, p.Unit, p.AllowPartial, ingredientID)
+	}
+	return err
+}
+
+// IngredientLine returns a recipe's line for an ingredient.
+func (s *SQLStore) IngredientLine(recipeID, ingredientID int) (Line, error) {
+	lines, err := s.Lines(recipeID)
+	if err != nil {
+		return Line{}, err
+	}
+	for _, l := range lines {
+		if l.Type == LineIngredient && l.IngredientID == ingredientID {
+			return l, nil
+		}
+	}
+	return Line{}, fmt.Errorf("ingredient line for ingredient %d in recipe %d %w", ingredientID, recipeID, ErrNotFound)
+}
+
+// AddIngredientLine adds qty of an ingredient to a recipe; see the function
+// of the same name.
+func (s *SQLStore) AddIngredientLine(recipeID, ingredientID int, qty float64, unit string) (int, error) {
+	return AddIngredientLine(s.db, recipeID, ingredientID, qty, unit)
+}
+
+// AddSubrecipeLine adds qty of a subrecipe to a recipe, refusing cycles;
+// see the function of the same name.
+func (s *SQLStore) AddSubrecipeLine(recipeID, subID int, qty float64, unit string) (int, error) {
+	return AddSubrecipeLine(s.db, recipeID, subID, qty, unit)
+}
+
+// UpdateLine sets the quantity and unit of a recipe line.
+func (s *SQLStore) UpdateLine(lineType string, lineID int, qty float64, unit string) error {
+	return UpdateLine(s.db, lineType, lineID, qty, unit)
+}
+
+// DeleteLine removes a recipe line.
+func (s *SQLStore) DeleteLine(lineType string, lineID int) error {
+	return DeleteLine(s.db, lineType, lineID)
+}
+
+// PrepSettings returns a recipe's prep settings.
+func (s *SQLStore) PrepSettings(recipeID int) (PrepSettings, error) {
+	var p PrepSettings
+	err := s.db.QueryRow(`
+		SELECT COALESCE(prep_lead_days, 0), COALESCE(batch_qty, 0),
+		       COALESCE(shelf_life_days, 0), COALESCE(station, '')
+		FROM recipes WHERE id = ?
+	`, recipeID).Scan(&p.LeadDays, &p.BatchQty, &p.ShelfLifeDays, &p.Station)
+	if err == sql.ErrNoRows {
+		return p, fmt.Errorf("recipe with ID %d %w", recipeID, ErrNotFound)
+	}
+	return p, err
+}
+
+// SetPrepSettings replaces a recipe's prep settings. Zero values are
+// stored as unset.
+func (s *SQLStore) SetPrepSettings(recipeID int, p PrepSettings) error {
+	n, err := s.db.Exec(`
+		UPDATE recipes
+		SET prep_lead_days = NULLIF(?, 0), batch_qty = NULLIF(?, 0),
+		    shelf_life_days = NULLIF(?, 0), station = NULLIF(?, '')
+		WHERE id = ?
+	`, p.LeadDays, p.BatchQty, p.ShelfLifeDays, p.Station, recipeID)
+	if err != nil {
+		return err
+	}
+	if rows, _ := n.RowsAffected(); rows == 0 {
+		return fmt.Errorf("recipe with ID %d %w", recipeID, ErrNotFound)
+	}
+	return nil
+}
+
+// sqlLimit turns "0 means no limit" into SQLite's -1.
+func sqlLimit(limit int) int {
+	if limit <= 0 {
+		return -1
+	}
+	return limit
+}
@@ -0,0 +1,209 @@
+package internal
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// UnassignedSupplier is the market list section for ingredients without a
+// supplier link.
+const UnassignedSupplier = "(no supplier)"
+
+// Supplier is one row of suppliers.
+type Supplier struct {
+	ID           int
+	Name         string
+	Contact      string
+	LeadTimeDays int
+	OrderDays    string
+	MinOrder     float64
+	Notes        string
+}
+
+// SupplierLink is one row of ingredient_suppliers with both names resolved.
+type SupplierLink struct {
+	IngredientID   int
+	IngredientName string
+	SupplierID     int
+	SupplierName   string
+	SKU            string
+	PackQty        float64
+	PackUnit       string
+	Preferred      bool
+}
+
+// SupplierGroup is one supplier's section of a market list.
+type SupplierGroup struct {
+	Supplier Supplier // zero ID for UnassignedSupplier
+	Items    []*IngredientUsage
+	Links    map[int]SupplierLink // keyed by ingredient ID
+}
+
+// Subtotal returns the estimated cost of the group.
+func (g *SupplierGroup) Subtotal() float64 {
+	var total float64
+	for _, it := range g.Items {
+		total += it.Cost()
+	}
+	return total
+}
+
+// BelowMinimum reports whether an order of the given value is under the
+// supplier's minimum order.
+func (s Supplier) BelowMinimum(orderValue float64) bool {
+	return s.MinOrder > 0 && orderValue < s.MinOrder
+}
+
+const supplierColumns = `id, name, COALESCE(contact, ''), COALESCE(lead_time_days, 0),
+	COALESCE(order_days, ''), COALESCE(min_order, 0), COALESCE(notes, '')`
+
+func scanSupplier(row interface{ Scan(...any) error }) (Supplier, error) {
+	var s Supplier
+	err := row.Scan(&s.ID, &s.Name, &s.Contact, &s.LeadTimeDays, &s.OrderDays, &s.MinOrder, &s.Notes)
+	return s, err
+}
+
+// ListSuppliers returns all suppliers ordered by name.
+func ListSuppliers(db *sql.DB) ([]Supplier, error) {
+	rows, err := db.Query(`SELECT ` + supplierColumns + ` FROM suppliers ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Supplier
+	for rows.Next() {
+		s, err := scanSupplier(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, s)
+	}
+	return list, rows.Err()
+}
+
+// GetSupplierByName looks a supplier up by exact name.
+func GetSupplierByName(db *sql.DB, name string) (Supplier, error) {
+	s, err := scanSupplier(db.QueryRow(`SELECT `+supplierColumns+` FROM suppliers WHERE name = ?`, name))
+	if err == sql.ErrNoRows {
+		return s, fmt.Errorf("supplier not found: %s", name)
+	}
+	return s, err
+}
+
+// SupplierLinks returns ingredient_suppliers rows, optionally filtered by
+// supplier (supplierID > 0), ordered by ingredient name.
+func SupplierLinks(db *sql.DB, supplierID int) ([]SupplierLink, error) {
+	rows, err := db.Query(`
+		SELECT l.ingredient_id, i.name, l.supplier_id, s.name,
+		       COALESCE(l.sku, ''), COALESCE(l.pack_qty, 0), COALESCE(l.pack_unit, ''), l.preferred
+		FROM ingredient_suppliers l
+		JOIN ingredients i ON i.id = l.ingredient_id
+		JOIN suppliers s ON s.id = l.supplier_id
+		WHERE ? = 0 OR l.supplier_id = ?
+		ORDER BY i.name, l.preferred DESC, l.id
+	`, supplierID, supplierID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []SupplierLink
+	for rows.Next() {
+		var l SupplierLink
+		if err := rows.Scan(&l.IngredientID, &l.IngredientName, &l.SupplierID, &l.SupplierName,
+			&l.SKU, &l.PackQty, &l.PackUnit, &l.Preferred); err != nil {
+			return nil, err
+		}
+		list = append(list, l)
+	}
+	return list, rows.Err()
+}
+
+// PrimarySupplierLinks returns, per ingredient ID, the link used for
+// ordering: the preferred one, otherwise the first one added.
+func PrimarySupplierLinks(db *sql.DB) (map[int]SupplierLink, error) {
+	links, err := SupplierLinks(db, 0)
+	if err != nil {
+		return nil, err
+	}
+	primary := make(map[int]SupplierLink)
+	for _, l := range links {
+		if _, ok := primary[l.IngredientID]; !ok {
+			primary[l.IngredientID] = l
+		}
+	}
+	return primary, nil
+}
+
+// GroupBySupplier splits a market list into one group per primary supplier,
+// ordered by supplier name with unassigned ingredients last.
+func GroupBySupplier(db *sql.DB, items []*IngredientUsage) ([]*SupplierGroup, error) {
+	primary, err := PrimarySupplierLinks(db)
+	if err != nil {
+		return nil, err
+	}
+	suppliers, err := ListSuppliers(db)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int]Supplier, len(suppliers))
+	for _, s := range suppliers {
+		byID[s.ID] = s
+	}
+
+	groups := make(map[int]*SupplierGroup)
+	for _, it := range items {
+		link, ok := primary[it.IngredientID]
+		id := 0
+		if ok {
+			id = link.SupplierID
+		}
+		g, exists := groups[id]
+		if !exists {
+			sup := Supplier{Name: UnassignedSupplier}
+			if id != 0 {
+				sup = byID[id]
+			}
+			g = &SupplierGroup{Supplier: sup, Links: make(map[int]SupplierLink)}
+			groups[id] = g
+		}
+		g.Items = append(g.Items, it)
+		if ok {
+			g.Links[it.IngredientID] = link
+		}
+	}
+
+	list := make([]*SupplierGroup, 0, len(groups))
+	for _, g := range groups {
+		list = append(list, g)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i].Supplier, list[j].Supplier
+		if (a.ID == 0) != (b.ID == 0) {
+			return b.ID == 0
+		}
+		return a.Name < b.Name
+	})
+	return list, nil
+}
+
+// ParseQtyUnit splits a quantity written as "25kg", "10 piece" or "0.5" into
+// number and unit. Standard units are canonicalised; a missing unit is "".
+func ParseQtyUnit(s string) (float64, string, error) {
+	s = strings.TrimSpace(s)
+	i := 0
+	for ; i < len(s); i++ {
+		if (s[i] < '0' || s[i] > '9') && s[i] != '.' {
+			break
+		}
+	}
+	qty, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid quantity %q (expected e.g. 25kg)", s)
+	}
+	return qty, CanonicalUnit(s[i:]), nil
+}
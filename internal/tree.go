@@ -0,0 +1,201 @@
+package internal
+
+import (
+	"database/sql"
+	"sort"
+)
+
+// TreeNode is one line of a recipe's dependency tree. Quantities and costs
+// are scaled to the amount of the root recipe the tree was built for.
+type TreeNode struct {
+	Type     string // "recipe", "ingredient" or "subrecipe"
+	ID       int    // recipe or ingredient ID
+	Name     string
+	Qty      float64 // in Unit
+	Unit     string
+	Cost     float64
+	Children []*TreeNode
+}
+
+// Tree returns the full ingredient/subrecipe hierarchy of qty yield units
+// of recipeID, every level scaled by its parent's yield.
+func (c *Coster) Tree(recipeID int, qty float64) (*TreeNode, error) {
+	rc, err := c.Cost(recipeID)
+	if err != nil {
+		return nil, err
+	}
+	root := &TreeNode{
+		Type: "recipe",
+		ID:   recipeID,
+		Name: rc.Name,
+		Qty:  qty,
+		Unit: rc.YieldUnit,
+		Cost: rc.CostPerYieldUnit() * qty,
+	}
+	if err := c.addTreeChildren(root, rc, qty); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+func (c *Coster) addTreeChildren(node *TreeNode, rc *RecipeCost, qty float64) error {
+	if rc.YieldQty <= 0 {
+		return nil
+	}
+	scale := qty / rc.YieldQty
+
+	for _, l := range rc.Lines {
+		child := &TreeNode{
+			Type: l.Type,
+			Name: l.Name,
+			Qty:  l.BaseQty * scale,
+			Unit: l.BaseUnit,
+			Cost: l.LineCost * scale,
+		}
+		if l.Type == "ingredient" {
+			child.ID = l.IngredientID
+		} else {
+			child.ID = l.SubrecipeID
+			sub, err := c.Cost(l.SubrecipeID)
+			if err != nil {
+				return err
+			}
+			if err := c.addTreeChildren(child, sub, child.Qty); err != nil {
+				return err
+			}
+		}
+		node.Children = append(node.Children, child)
+	}
+	return nil
+}
+
+// WhereUsed is a recipe that depends on an ingredient or recipe, directly
+// or through subrecipes.
+type WhereUsed struct {
+	RecipeID int
+	Name     string
+	Depth    int      // 1 when it uses the item itself
+	Via      []string // subrecipes between the item and the recipe, innermost first
+	Qty      float64  // direct uses only, as entered on the line
+	Unit     string
+}
+
+// IngredientWhereUsed lists every recipe that uses an ingredient, directly
+// or through any number of subrecipe levels.
+func IngredientWhereUsed(db *sql.DB, ingredientID int) ([]*WhereUsed, error) {
+	rows, err := db.Query(`
+		SELECT r.id, r.name, ri.qty, COALESCE(NULLIF(ri.unit, ''), ing.unit)
+		FROM recipe_items ri
+		JOIN recipes r ON r.id = ri.recipe_id
+		JOIN ingredients ing ON ing.id = ri.ingredient_id
+		WHERE ri.ingredient_id = ?
+	`, ingredientID)
+	if err != nil {
+		return nil, err
+	}
+	direct, err := scanWhereUsed(rows)
+	if err != nil {
+		return nil, err
+	}
+	return expandWhereUsed(db, direct, 0)
+}
+
+// RecipeWhereUsed lists every recipe that uses recipeID as a subrecipe,
+// directly or through other subrecipes.
+func RecipeWhereUsed(db *sql.DB, recipeID int) ([]*WhereUsed, error) {
+	rows, err := db.Query(`
+		SELECT r.id, r.name, rs.qty, COALESCE(rs.unit, '')
+		FROM recipe_subrecipes rs
+		JOIN recipes r ON r.id = rs.recipe_id
+		WHERE rs.subrecipe_id = ?
+	`, recipeID)
+	if err != nil {
+		return nil, err
+	}
+	direct, err := scanWhereUsed(rows)
+	if err != nil {
+		return nil, err
+	}
+	return expandWhereUsed(db, direct, recipeID)
+}
+
+func scanWhereUsed(rows *sql.Rows) ([]*WhereUsed, error) {
+	defer rows.Close()
+
+	var list []*WhereUsed
+	for rows.Next() {
+		w := &WhereUsed{Depth: 1}
+		if err := rows.Scan(&w.RecipeID, &w.Name, &w.Qty, &w.Unit); err != nil {
+			return nil, err
+		}
+		list = append(list, w)
+	}
+	return list, rows.Err()
+}
+
+// expandWhereUsed walks recipe_subrecipes upwards from the direct users,
+// breadth-first so each recipe is reported at its shortest distance.
+// skipID (the recipe being looked up, if any) is never reported, so a
+// cycle back to it cannot loop.
+func expandWhereUsed(db *sql.DB, direct []*WhereUsed, skipID int) ([]*WhereUsed, error) {
+	rows, err := db.Query(`
+		SELECT rs.subrecipe_id, r.id, r.name
+		FROM recipe_subrecipes rs
+		JOIN recipes r ON r.id = rs.recipe_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	type parent struct {
+		id   int
+		name string
+	}
+	parents := make(map[int][]parent)
+	for rows.Next() {
+		var sub int
+		var p parent
+		if err := rows.Scan(&sub, &p.id, &p.name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		parents[sub] = append(parents[sub], p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	seen := map[int]bool{skipID: true}
+	var result, queue []*WhereUsed
+	for _, w := range direct {
+		if seen[w.RecipeID] {
+			continue
+		}
+		seen[w.RecipeID] = true
+		result = append(result, w)
+		queue = append(queue, w)
+	}
+
+	for len(queue) > 0 {
+		w := queue[0]
+		queue = queue[1:]
+		for _, p := range parents[w.RecipeID] {
+			if seen[p.id] {
+				continue
+			}
+			seen[p.id] = true
+			via := append(append([]string{}, w.Via...), w.Name)
+			up := &WhereUsed{RecipeID: p.id, Name: p.name, Depth: w.Depth + 1, Via: via}
+			result = append(result, up)
+			queue = append(queue, up)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Depth != result[j].Depth {
+			return result[i].Depth < result[j].Depth
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
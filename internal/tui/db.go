@@ -1,14 +1,17 @@
 package tui
 
 import (
-	"database/sql"
+	"errors"
 	"fmt"
+
+	"github.com/ChefChristoph/chefops"
 )
 
 type RecipeSummary struct {
 	ID        int
 	Name      string
 	TotalCost float64
+	CostErr   error // why TotalCost could not be worked out, if it could not
 }
 
 type RecipeLine struct {
@@ -29,71 +32,62 @@ type RecipeDetail struct {
 	YieldUnit string
 }
 
-func LoadRecipes(db *sql.DB) ([]RecipeSummary, error) {
-	rows, err := db.Query(`SELECT recipe_id, recipe_name, total_cost FROM recipe_totals ORDER BY recipe_name`)
+func LoadRecipes(store chefops.Store) ([]RecipeSummary, error) {
+	recipes, err := store.Recipes()
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	var list []RecipeSummary
-	for rows.Next() {
-		var r RecipeSummary
-		rows.Scan(&r.ID, &r.Name, &r.TotalCost)
-		list = append(list, r)
+	coster := chefops.New(store).Coster()
+	list := make([]RecipeSummary, len(recipes))
+	for i, r := range recipes {
+		list[i] = RecipeSummary{ID: r.ID, Name: r.Name}
+		// A costing error (e.g. an unconvertible unit) is kept with the
+		// recipe rather than failing the list or showing a cost of 0.
+		rc, err := coster.Cost(r.ID)
+		if err != nil {
+			list[i].CostErr = err
+			continue
+		}
+		list[i].TotalCost = rc.TotalCost
 	}
 	return list, nil
 }
 
-func LoadRecipeDetail(db *sql.DB, recipeID int) (*RecipeDetail, error) {
-	var name, unit string
-	var yield float64
-
-	err := db.QueryRow(`
-		SELECT name, yield_qty, yield_unit
-		FROM recipes
-		WHERE id = ?`, recipeID).Scan(&name, &yield, &unit)
-	if err != nil {
-		return nil, err
-	}
-
-	rows, err := db.Query(`
-		SELECT type, name, qty, unit, line_cost
-		FROM recipe_raw_lines
-		WHERE recipe_id = ?
-		ORDER BY type, name
-	`, recipeID)
+func LoadRecipeDetail(store chefops.Store, recipeID int) (*RecipeDetail, error) {
+	rc, err := chefops.New(store).Cost(recipeID)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
 	detail := &RecipeDetail{
 		ID:        recipeID,
-		Name:      name,
-		YieldQty:  yield,
-		YieldUnit: unit,
+		Name:      rc.Name,
+		TotalCost: rc.TotalCost,
+		YieldQty:  rc.YieldQty,
+		YieldUnit: rc.YieldUnit,
 	}
 
-	for rows.Next() {
-		var l RecipeLine
-		rows.Scan(&l.Type, &l.Name, &l.Qty, &l.Unit, &l.Cost)
-		detail.Lines = append(detail.Lines, l)
-		detail.TotalCost += l.Cost
+	for _, l := range rc.Lines {
+		detail.Lines = append(detail.Lines, RecipeLine{
+			Type: l.Type,
+			Name: l.Name,
+			Qty:  l.Qty,
+			Unit: l.Unit,
+			Cost: l.LineCost,
+		})
 	}
 
 	return detail, nil
 }
 
-func LoadRecipeNotes(db *sql.DB, recipeID int) (string, error) {
-	var notes string
-	query := "SELECT notes FROM recipes WHERE id = ?"
-	err := db.QueryRow(query, recipeID).Scan(&notes)
+func LoadRecipeNotes(store chefops.Store, recipeID int) (string, error) {
+	r, err := store.Recipe(recipeID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return "", fmt.Errorf("recipe with ID %d not found", recipeID)
+		if errors.Is(err, chefops.ErrNotFound) {
+			return "", err
 		}
 		return "", fmt.Errorf("failed to load recipe notes: %w", err)
 	}
-	return notes, nil
+	return r.Notes, nil
 }
@@ -0,0 +1,126 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Dimension groups units that convert into each other by a fixed factor.
+type Dimension string
+
+const (
+	DimensionMass    Dimension = "mass"
+	DimensionVolume  Dimension = "volume"
+	DimensionCount   Dimension = "count"
+	DimensionPortion Dimension = "portion"
+)
+
+// Unit is one entry of the standard unit library. Factor is the size of one
+// unit expressed in the dimension's base unit (g, ml, piece, portion).
+type Unit struct {
+	Name      string
+	Dimension Dimension
+	Factor    float64
+	Aliases   []string
+}
+
+// Kitchen measures are metric: 1 tsp = 5 ml, 1 tbsp = 15 ml, 1 cup = 250 ml.
+var standardUnits = []Unit{
+	{Name: "mg", Dimension: DimensionMass, Factor: 0.001, Aliases: []string{"milligram", "milligrams"}},
+	{Name: "g", Dimension: DimensionMass, Factor: 1, Aliases: []string{"gr", "gram", "grams", "gramme", "grammes"}},
+	{Name: "kg", Dimension: DimensionMass, Factor: 1000, Aliases: []string{"kgs", "kilo", "kilos", "kilogram", "kilograms"}},
+	{Name: "oz", Dimension: DimensionMass, Factor: 28.349523125, Aliases: []string{"ounce", "ounces"}},
+	{Name: "lb", Dimension: DimensionMass, Factor: 453.59237, Aliases: []string{"lbs", "pound", "pounds"}},
+
+	{Name: "ml", Dimension: DimensionVolume, Factor: 1, Aliases: []string{"milliliter", "milliliters", "millilitre", "millilitres"}},
+	{Name: "cl", Dimension: DimensionVolume, Factor: 10, Aliases: []string{"centiliter", "centiliters", "centilitre", "centilitres"}},
+	{Name: "dl", Dimension: DimensionVolume, Factor: 100, Aliases: []string{"deciliter", "deciliters", "decilitre", "decilitres"}},
+	{Name: "liter", Dimension: DimensionVolume, Factor: 1000, Aliases: []string{"l", "lt", "ltr", "liters", "litre", "litres"}},
+	{Name: "tsp", Dimension: DimensionVolume, Factor: 5, Aliases: []string{"teaspoon", "teaspoons"}},
+	{Name: "tbsp", Dimension: DimensionVolume, Factor: 15, Aliases: []string{"tablespoon", "tablespoons"}},
+	{Name: "cup", Dimension: DimensionVolume, Factor: 250, Aliases: []string{"cups"}},
+
+	{Name: "piece", Dimension: DimensionCount, Factor: 1, Aliases: []string{"pc", "pcs", "pieces", "each", "ea"}},
+	{Name: "dozen", Dimension: DimensionCount, Factor: 12, Aliases: []string{"dz", "doz"}},
+
+	{Name: "portion", Dimension: DimensionPortion, Factor: 1, Aliases: []string{"portions", "serving", "servings", "pax"}},
+}
+
+var unitIndex = buildUnitIndex()
+
+func buildUnitIndex() map[string]Unit {
+	idx := make(map[string]Unit)
+	for _, u := range standardUnits {
+		idx[u.Name] = u
+		for _, a := range u.Aliases {
+			idx[a] = u
+		}
+	}
+	return idx
+}
+
+// LookupUnit finds a unit by canonical name or alias, case-insensitively.
+func LookupUnit(name string) (Unit, bool) {
+	u, ok := unitIndex[strings.ToLower(strings.TrimSpace(name))]
+	return u, ok
+}
+
+// NormalizeUnit returns the canonical name of a standard unit, or an error
+// listing the known units.
+func NormalizeUnit(name string) (string, error) {
+	u, ok := LookupUnit(name)
+	if !ok {
+		return "", fmt.Errorf("unknown unit %q (known units: %s)", name, strings.Join(StandardUnitNames(), ", "))
+	}
+	return u.Name, nil
+}
+
+// CanonicalUnit returns the canonical name for a standard unit and leaves
+// anything else untouched, so custom units keep working in conversions.
+func CanonicalUnit(name string) string {
+	if u, ok := LookupUnit(name); ok {
+		return u.Name
+	}
+	return strings.TrimSpace(name)
+}
+
+// SameUnit reports whether a and b name the same unit.
+func SameUnit(a, b string) bool {
+	return CanonicalUnit(a) == CanonicalUnit(b)
+}
+
+// ConvertStandard converts qty between two standard units of the same
+// dimension. It reports false when either unit is unknown or the dimensions
+// differ; those conversions need a per-ingredient row.
+func ConvertStandard(qty float64, from, to string) (float64, bool) {
+	if SameUnit(from, to) {
+		return qty, true
+	}
+
+	f, ok := LookupUnit(from)
+	if !ok {
+		return 0, false
+	}
+	t, ok := LookupUnit(to)
+	if !ok || f.Dimension != t.Dimension {
+		return 0, false
+	}
+
+	return qty * f.Factor / t.Factor, true
+}
+
+// StandardUnitNames lists the canonical unit names, sorted.
+func StandardUnitNames() []string {
+	names := make([]string, 0, len(standardUnits))
+	for _, u := range standardUnits {
+		names = append(names, u.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// StandardUnits returns the unit library in declaration order.
+func StandardUnits() []Unit {
+	return append([]Unit(nil), standardUnits...)
+}
@@ -0,0 +1,280 @@
+// ChefOps dashboard: a small hash-routed page over the read-only API.
+"use strict";
+
+const view = document.getElementById("view");
+
+// ---------------------------------------------------------------
+// Helpers
+// ---------------------------------------------------------------
+
+async function api(path, body) {
+  const opts = body === undefined ? {} : {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify(body),
+  };
+  const res = await fetch("/api" + path, opts);
+  const data = await res.json();
+  if (!res.ok) {
+    throw new Error(data.error || res.statusText);
+  }
+  return data;
+}
+
+// el builds an element; children may be strings, nodes or arrays of them.
+function el(tag, attrs, ...children) {
+  const node = document.createElement(tag);
+  for (const [k, v] of Object.entries(attrs || {})) {
+    if (k.startsWith("on")) {
+      node.addEventListener(k.slice(2), v);
+    } else {
+      node.setAttribute(k, v);
+    }
+  }
+  for (const c of children.flat()) {
+    if (c !== null && c !== undefined) {
+      node.append(c instanceof Node ? c : String(c));
+    }
+  }
+  return node;
+}
+
+const qty = (n) => Number(n).toFixed(3);
+const money = (n) => Number(n).toFixed(2);
+
+// table renders rows under columns of [header, cell(row), numeric?].
+function table(columns, rows, opts = {}) {
+  const head = el("tr", {}, columns.map(([h, , num]) => el("th", num ? { class: "num" } : {}, h)));
+  const body = rows.map((row) => {
+    const tr = el("tr", opts.onclick ? { class: "link", onclick: () => opts.onclick(row) } : {},
+      columns.map(([, cell, num]) => el("td", num ? { class: "num" } : {}, cell(row))));
+    return tr;
+  });
+  if (opts.total) {
+    body.push(el("tr", { class: "total" }, opts.total.map((c, i) =>
+      el("td", columns[i] && columns[i][2] ? { class: "num" } : {}, c))));
+  }
+  return el("table", {}, el("thead", {}, head), el("tbody", {}, body));
+}
+
+function showError(err) {
+  view.replaceChildren(el("p", { class: "error" }, err.message));
+}
+
+function summary(items) {
+  return el("div", { class: "summary" },
+    items.map(([label, value]) => el("div", {}, el("span", {}, label), el("strong", {}, value))));
+}
+
+const costColumns = [
+  ["Type", (l) => l.type],
+  ["Name", (l) => l.name],
+  ["Qty", (l) => qty(l.qty), true],
+  ["Unit", (l) => l.unit],
+  ["Cost/unit", (l) => money(l.cost_per_unit) + " / " + l.base_unit, true],
+  ["Line cost", (l) => money(l.line_cost), true],
+];
+
+// ---------------------------------------------------------------
+// Recipes
+// ---------------------------------------------------------------
+
+async function recipesView() {
+  const recipes = await api("/recipes");
+  const search = el("input", { type: "search", placeholder: "Search recipes…" });
+  const list = el("div");
+
+  const render = () => {
+    const q = search.value.toLowerCase();
+    const rows = recipes.filter((r) => r.name.toLowerCase().includes(q));
+    list.replaceChildren(table([
+      ["Recipe", (r) => r.name],
+      ["Yield", (r) => qty(r.yield_qty), true],
+      ["Unit", (r) => r.yield_unit],
+    ], rows, { onclick: (r) => { location.hash = "#/recipe/" + r.id; } }));
+  };
+  search.addEventListener("input", render);
+  render();
+
+  view.replaceChildren(el("h1", {}, "Recipes"), el("div", { class: "toolbar" }, search), list);
+  search.focus();
+}
+
+async function recipeView(id) {
+  const asOf = el("input", { type: "date" });
+  const costArea = el("div");
+  const scaleArea = el("div");
+
+  const loadCost = async () => {
+    try {
+      const rc = await api(`/recipes/${id}/cost` + (asOf.value ? "?as_of=" + asOf.value : ""));
+      const facts = [
+        ["Yield", `${qty(rc.yield_qty)} ${rc.yield_unit}`],
+        ["Total cost", money(rc.total_cost)],
+        [`Cost per ${rc.yield_unit}`, money(rc.cost_per_yield_unit)],
+      ];
+      if (rc.secondary_yield_unit) {
+        facts.push(["Secondary yield", `${qty(rc.secondary_yield_qty)} ${rc.secondary_yield_unit}`]);
+        facts.push([`Cost per ${rc.secondary_yield_unit}`, money(rc.total_cost / rc.secondary_yield_qty)]);
+      }
+      facts.push(["Prices", rc.as_of ? "as of " + rc.as_of : "current"]);
+      costArea.replaceChildren(summary(facts),
+        table(costColumns, rc.lines, { total: ["", "Total", "", "", "", money(rc.total_cost)] }));
+    } catch (err) {
+      costArea.replaceChildren(el("p", { class: "error" }, err.message));
+    }
+  };
+
+  const recipe = await api(`/recipes/${id}`);
+  const targetQty = el("input", { type: "number", min: "0", step: "any", value: recipe.yield_qty });
+  const units = [recipe.yield_unit];
+  if (recipe.secondary_yield_unit) {
+    units.push(recipe.secondary_yield_unit);
+  }
+  const targetUnit = el("input", { list: "yield-units", value: recipe.yield_unit, size: "8" });
+
+  const scale = async () => {
+    try {
+      const sr = await api(`/recipes/${id}/scale?qty=${encodeURIComponent(targetQty.value)}&unit=${encodeURIComponent(targetUnit.value)}`);
+      scaleArea.replaceChildren(
+        summary([
+          ["Target", `${qty(sr.target_qty)} ${sr.target_unit}`],
+          ["Factor", qty(sr.factor)],
+          ["Total cost", money(sr.total_cost)],
+        ]),
+        table(costColumns, sr.lines, { total: ["", "Total", "", "", "", money(sr.total_cost)] }));
+    } catch (err) {
+      scaleArea.replaceChildren(el("p", { class: "error" }, err.message));
+    }
+  };
+
+  asOf.addEventListener("change", loadCost);
+  view.replaceChildren(
+    el("p", { class: "no-print" }, el("a", { href: "#/recipes" }, "← Recipes")),
+    el("h1", {}, recipe.name),
+    recipe.notes ? el("p", { class: "muted" }, recipe.notes) : null,
+    el("div", { class: "toolbar" }, el("label", {}, "Prices as of ", asOf),
+      el("button", { class: "secondary", onclick: () => { asOf.value = ""; loadCost(); } }, "Current")),
+    costArea,
+    el("h2", {}, "Scale"),
+    el("div", { class: "toolbar" },
+      targetQty, targetUnit,
+      el("datalist", { id: "yield-units" }, units.map((u) => el("option", { value: u }))),
+      el("button", { onclick: scale }, "Scale")),
+    scaleArea);
+  await loadCost();
+}
+
+// ---------------------------------------------------------------
+// Forecast
+// ---------------------------------------------------------------
+
+async function forecastView() {
+  const recipes = await api("/recipes");
+  const rowsArea = el("div");
+  const result = el("div");
+  const dishes = [];
+
+  const addRow = () => {
+    const recipe = el("input", { list: "recipe-names", placeholder: "Dish", size: "32" });
+    const portions = el("input", { type: "number", min: "0", step: "any", placeholder: "Portions" });
+    const row = { recipe, portions };
+    dishes.push(row);
+    const node = el("div", { class: "toolbar" }, recipe, portions,
+      el("button", {
+        class: "secondary",
+        onclick: () => { dishes.splice(dishes.indexOf(row), 1); node.remove(); },
+      }, "Remove"));
+    rowsArea.append(node);
+    recipe.focus();
+  };
+
+  const run = async () => {
+    const body = {
+      dishes: dishes
+        .filter((d) => d.recipe.value.trim() !== "")
+        .map((d) => ({ recipe: d.recipe.value.trim(), portions: Number(d.portions.value) })),
+    };
+    try {
+      const f = await api("/forecast", body);
+      result.replaceChildren(
+        summary([["Dishes", f.dishes.length], ["Ingredient cost", money(f.total_cost)]]),
+        el("h2", {}, "Ingredients"),
+        table([
+          ["Ingredient", (u) => u.name],
+          ["Qty", (u) => qty(u.qty), true],
+          ["Unit", (u) => u.unit],
+          ["Cost", (u) => money(u.cost), true],
+        ], f.ingredients, { total: ["Total", "", "", money(f.total_cost)] }),
+        el("h2", {}, "Subrecipes (bulk prep, deepest first)"),
+        table([
+          ["Subrecipe", (s) => s.name],
+          ["Qty", (s) => qty(s.qty), true],
+          ["Unit", (s) => s.unit],
+          ["Used in", (s) => s.used_in.join(", ")],
+        ], f.subrecipes),
+        el("p", { class: "no-print" }, el("button", { onclick: () => window.print() }, "Print")));
+    } catch (err) {
+      result.replaceChildren(el("p", { class: "error" }, err.message));
+    }
+  };
+
+  view.replaceChildren(
+    el("h1", {}, "Forecast"),
+    el("datalist", { id: "recipe-names" }, recipes.map((r) => el("option", { value: r.name }))),
+    rowsArea,
+    el("div", { class: "toolbar" },
+      el("button", { class: "secondary", onclick: addRow }, "Add dish"),
+      el("button", { onclick: run }, "Calculate")),
+    result);
+  addRow();
+}
+
+// ---------------------------------------------------------------
+// Market list
+// ---------------------------------------------------------------
+
+async function marketListView() {
+  const items = await api("/marketlist");
+  const total = items.reduce((sum, u) => sum + u.cost, 0);
+  view.replaceChildren(
+    el("h1", {}, "Market list"),
+    el("p", { class: "muted" }, "One yield batch of every active recipe. " + new Date().toLocaleDateString()),
+    el("div", { class: "toolbar" }, el("button", { onclick: () => window.print() }, "Print")),
+    table([
+      ["Ingredient", (u) => u.name],
+      ["Qty", (u) => qty(u.qty), true],
+      ["Unit", (u) => u.unit],
+      ["Cost/unit", (u) => money(u.cost_per_unit), true],
+      ["Cost", (u) => money(u.cost), true],
+    ], items, { total: ["Total", "", "", "", money(total)] }));
+}
+
+// ---------------------------------------------------------------
+// Routing
+// ---------------------------------------------------------------
+
+async function route() {
+  const hash = location.hash || "#/recipes";
+  for (const a of document.querySelectorAll("nav a")) {
+    a.classList.toggle("active", hash.startsWith(a.getAttribute("href").replace(/s$/, "")));
+  }
+
+  const recipe = hash.match(/^#\/recipe\/(\d+)$/);
+  try {
+    if (recipe) {
+      await recipeView(recipe[1]);
+    } else if (hash === "#/forecast") {
+      await forecastView();
+    } else if (hash === "#/marketlist") {
+      await marketListView();
+    } else {
+      await recipesView();
+    }
+  } catch (err) {
+    showError(err);
+  }
+}
+
+window.addEventListener("hashchange", route);
+route();
@@ -0,0 +1,28 @@
+// Package web is the tablet dashboard served by `chefops web`: a recipe
+// browser with cost breakdowns, a scaling calculator, a forecast builder
+// and a printable market list. The pages are static files embedded in the
+// binary; they read everything from the read-only HTTP API.
+package web
+
+import (
+	"database/sql"
+	"embed"
+	"io/fs"
+	"net/http"
+
+	"github.com/ChefChristoph/chefops/internal/api"
+)
+
+//go:embed static
+var static embed.FS
+
+// Handler serves the dashboard at / and the read-only API under /api/.
+func Handler(db *sql.DB) http.Handler {
+	// fs.Sub only fails for an invalid path, and "static" is fixed.
+	files, _ := fs.Sub(static, "static")
+
+	mux := http.NewServeMux()
+	mux.Handle("/api/", api.NewReadOnlyServer(db))
+	mux.Handle("/", http.FileServerFS(files))
+	return mux
+}
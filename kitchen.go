@@ -0,0 +1,69 @@
+package chefops
+
+import "github.com/ChefChristoph/chefops/internal"
+
+// Kitchen costs, scales and forecasts recipes and builds market lists from a
+// Store. Each call reads the Store afresh, so a Kitchen can be kept for the
+// life of a program.
+type Kitchen struct {
+	store Store
+	asOf  string
+}
+
+// New returns a Kitchen working from s at current prices.
+func New(s Store) *Kitchen {
+	return &Kitchen{store: s}
+}
+
+// AsOf returns a copy of k that prices ingredients as they were on date
+// (YYYY-MM-DD). An empty date means current prices.
+func (k *Kitchen) AsOf(date string) *Kitchen {
+	c := *k
+	c.asOf = date
+	return &c
+}
+
+// Store returns the Store k works from.
+func (k *Kitchen) Store() Store {
+	return k.store
+}
+
+// Coster is a costing engine that remembers every recipe it costs, so
+// subrecipes shared by many recipes are costed once. It does not see
+// changes made to the Store after it was created.
+type Coster interface {
+	Cost(recipeID int) (*RecipeCost, error)
+	Scale(recipeID int, qty float64, unit string) (*ScaledRecipe, error)
+	Forecast(dishes []Dish) (*Forecast, error)
+	MarketList() ([]*IngredientUsage, error)
+}
+
+// Coster returns a Coster over k's Store at k's prices. Use it to cost many
+// recipes that share subrecipes in one go.
+func (k *Kitchen) Coster() Coster {
+	return internal.NewStoreCoster(k.store).AsOf(k.asOf)
+}
+
+// Cost returns the full costing of a recipe: every line priced, subrecipes
+// costed against their own yield.
+func (k *Kitchen) Cost(recipeID int) (*RecipeCost, error) {
+	return k.Coster().Cost(recipeID)
+}
+
+// Scale costs a recipe scaled to qty of unit: its yield unit, its secondary
+// yield unit or a standard unit of either.
+func (k *Kitchen) Scale(recipeID int, qty float64, unit string) (*ScaledRecipe, error) {
+	return k.Coster().Scale(recipeID, qty, unit)
+}
+
+// Forecast returns the ingredients and subrecipes needed to produce dishes,
+// expanded through every subrecipe level.
+func (k *Kitchen) Forecast(dishes []Dish) (*Forecast, error) {
+	return k.Coster().Forecast(dishes)
+}
+
+// MarketList returns the ingredients for one yield batch of every recipe
+// that is not archived.
+func (k *Kitchen) MarketList() ([]*IngredientUsage, error) {
+	return k.Coster().MarketList()
+}
@@ -4,8 +4,7 @@ echo "🚨 Resetting ChefOps database..."
 rm -f db/chefops.db
 
 echo "🔧 Recreating schema..."
-sqlite3 db/chefops.db <schema.sql
-sqlite3 db/chefops.db <views.sql
+chefops db migrate
 
 echo "🍏 Importing ingredients..."
 chefops ingredient add --name "Lobster Meat" --unit kg --cost 0